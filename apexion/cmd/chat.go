@@ -40,6 +40,7 @@ func runChat() error {
 	})
 	policy := permission.NewDefaultPolicy(&cfg.Permissions)
 	executor := tools.NewExecutor(registry, policy)
+	executor.SetMaxParallelReads(cfg.MaxParallelReads)
 
 	// Load hooks from .apexion/hooks.yaml and ~/.config/apexion/hooks.yaml
 	cwd, _ := os.Getwd()
@@ -82,6 +83,16 @@ func runChat() error {
 		fmt.Fprintln(os.Stderr, "open memory store:", err)
 		os.Exit(1)
 	}
+	spendStore, err := session.NewSQLiteSpendStore(store.DB())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "open spend store:", err)
+		os.Exit(1)
+	}
+	planStore, err := session.NewSQLitePlanStore(store.DB())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "open plan store:", err)
+		os.Exit(1)
+	}
 
 	// Provider factory for /provider hot-swap.
 	factory := agent.ProviderFactory(func(c *config.Config) (provider.Provider, error) {
@@ -114,6 +125,8 @@ func runChat() error {
 			a := agent.NewWithSession(p, executor, cfg, ui, store, sess)
 			a.SetProviderFactory(factory)
 			a.SetMemoryStore(memStore)
+			a.SetSpendStore(spendStore)
+			a.SetPlanStore(planStore)
 			if mcpMgr != nil {
 				a.SetMCPManager(mcpMgr)
 			}
@@ -128,6 +141,8 @@ func runChat() error {
 	a := agent.New(p, executor, cfg, ui, store)
 	a.SetProviderFactory(factory)
 	a.SetMemoryStore(memStore)
+	a.SetSpendStore(spendStore)
+	a.SetPlanStore(planStore)
 	if mcpMgr != nil {
 		a.SetMCPManager(mcpMgr)
 	}
@@ -0,0 +1,340 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apexion-ai/apexion/internal/agent"
+	"github.com/apexion-ai/apexion/internal/config"
+	"github.com/apexion-ai/apexion/internal/mcp"
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/session"
+	"github.com/apexion-ai/apexion/internal/tokenizer"
+	"github.com/apexion-ai/apexion/internal/tools"
+	"github.com/spf13/cobra"
+)
+
+const (
+	doctorProbeTimeout = 60 * time.Second
+	doctorMCPTimeout   = 20 * time.Second
+)
+
+// doctorCheck is one line of the doctor report.
+type doctorCheck struct {
+	Name     string   `json:"name"`
+	Status   string   `json:"status"` // "pass" | "warn" | "fail" | "skip"
+	Detail   string   `json:"detail,omitempty"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// doctorReport collects check results in the order they ran.
+type doctorReport struct {
+	Checks []doctorCheck `json:"checks"`
+	OK     bool          `json:"ok"`
+}
+
+func (r *doctorReport) add(name, status, detail string, problems ...error) {
+	c := doctorCheck{Name: name, Status: status, Detail: detail}
+	for _, p := range problems {
+		c.Problems = append(c.Problems, p.Error())
+	}
+	r.Checks = append(r.Checks, c)
+}
+
+// addProblems records a check that passes when problems is empty.
+func (r *doctorReport) addProblems(name, detail string, problems []error) {
+	if len(problems) == 0 {
+		r.add(name, "pass", detail)
+		return
+	}
+	r.add(name, "fail", detail, problems...)
+}
+
+func newDoctorCmd() *cobra.Command {
+	var jsonOutput bool
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check configuration, provider, git, sessions, MCP and hooks",
+		Long: "Runs a chain of diagnostics and prints a pass/fail report. " +
+			"The provider check sends one small request with a tool attached.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor(cmd.Context(), jsonOutput)
+		},
+	}
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "print JSON output")
+	return cmd
+}
+
+func runDoctor(ctx context.Context, jsonOutput bool) error {
+	cwd, _ := os.Getwd()
+	var r doctorReport
+
+	cfg := doctorConfig(&r)
+	if cfg != nil {
+		if p := doctorProvider(&r, cfg); p != nil {
+			doctorProbe(ctx, &r, cfg, p)
+		} else {
+			r.add("probe", "skip", "no provider")
+		}
+	} else {
+		r.add("provider", "skip", "config did not load")
+		r.add("probe", "skip", "config did not load")
+	}
+	if cfg != nil {
+		doctorTokenizer(&r, cfg)
+	}
+	doctorGit(&r, cwd)
+	doctorSessionDB(&r)
+	doctorMCP(ctx, &r, cwd)
+
+	n, errs := tools.CheckHooks(cwd)
+	r.addProblems("hooks", fmt.Sprintf("%d hook(s)", n), errs)
+	n, errs = agent.CheckRules(cwd)
+	r.addProblems("rules", fmt.Sprintf("%d file(s)", n), errs)
+	n, errs = agent.CheckSkills(cwd)
+	r.addProblems("skills", fmt.Sprintf("%d file(s)", n), errs)
+	n, errs = agent.CheckAgents(cwd)
+	r.addProblems("agents", fmt.Sprintf("%d file(s)", n), errs)
+	if cfg != nil {
+		doctorCommands(&r, "lint", cfg.Lint.Enabled, cfg.Lint.Commands)
+		doctorCommands(&r, "test", cfg.Test.Enabled, cfg.Test.Commands)
+	}
+
+	failed := 0
+	for _, c := range r.Checks {
+		if c.Status == "fail" {
+			failed++
+		}
+	}
+	r.OK = failed == 0
+
+	if jsonOutput {
+		b, _ := json.MarshalIndent(r, "", "  ")
+		fmt.Println(string(b))
+	} else {
+		printDoctorReport(r)
+	}
+	if failed > 0 {
+		return fmt.Errorf("doctor: %d check(s) failed", failed)
+	}
+	return nil
+}
+
+// doctorConfig loads and validates the config. It returns nil only if the
+// config could not be loaded at all.
+func doctorConfig(r *doctorReport) *config.Config {
+	cfg, err := loadConfig()
+	if err != nil {
+		r.add("config", "fail", "could not load", err)
+		return nil
+	}
+	detail := "provider " + cfg.Provider
+	if cfg.Model != "" {
+		detail += ", model " + cfg.Model
+	}
+	if err := cfg.Validate(); err != nil {
+		r.add("config", "fail", detail, splitJoined(err)...)
+		return cfg
+	}
+	r.add("config", "pass", detail)
+	return cfg
+}
+
+func doctorProvider(r *doctorReport, cfg *config.Config) provider.Provider {
+	p, err := buildProvider(cfg)
+	if err != nil {
+		r.add("provider", "fail", cfg.Provider, err)
+		return nil
+	}
+	if cfg.Model == "" {
+		cfg.Model = p.DefaultModel()
+	}
+	r.add("provider", "pass", fmt.Sprintf("%s (%s)", p.Name(), cfg.Model))
+	return p
+}
+
+// doctorProbe sends one tiny request with a single tool and checks that
+// the response streams and comes back as a tool call.
+func doctorProbe(ctx context.Context, r *doctorReport, cfg *config.Config, p provider.Provider) {
+	ctx, cancel := context.WithTimeout(ctx, doctorProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	events, err := p.Chat(ctx, &provider.ChatRequest{
+		Model:        cfg.Model,
+		SystemPrompt: "You are a connectivity check. Always answer by calling the report_status tool.",
+		Messages: []provider.Message{{
+			Role:    provider.RoleUser,
+			Content: []provider.Content{{Type: provider.ContentTypeText, Text: `Call report_status with status "ok".`}},
+		}},
+		Tools: []provider.ToolSchema{{
+			Name:        "report_status",
+			Description: "Report the result of the connectivity check.",
+			Parameters:  map[string]any{"status": map[string]any{"type": "string"}},
+		}},
+		MaxTokens: 1024,
+	})
+	if err != nil {
+		r.add("probe", "fail", "request failed", err)
+		return
+	}
+
+	var streamed int
+	var called bool
+	var stop provider.StopReason
+	for ev := range events {
+		switch ev.Type {
+		case provider.EventTextDelta, provider.EventThinkingDelta:
+			streamed++
+		case provider.EventToolCallDone:
+			streamed++
+			called = called || ev.ToolCall.Name == "report_status"
+		case provider.EventDone:
+			stop = ev.StopReason
+		case provider.EventError:
+			r.add("probe", "fail", "stream failed", ev.Error)
+			return
+		}
+	}
+
+	elapsed := time.Since(start).Round(time.Millisecond)
+	switch {
+	case !called:
+		r.add("probe", "fail", fmt.Sprintf("model answered without calling the probe tool (stop reason %q, %s)", stop, elapsed))
+	case streamed == 0:
+		r.add("probe", "warn", fmt.Sprintf("tool call ok, but no streamed events arrived (%s)", elapsed))
+	default:
+		r.add("probe", "pass", fmt.Sprintf("streamed %d event(s), tool call ok (%s)", streamed, elapsed))
+	}
+}
+
+// doctorTokenizer checks that the BPE vocabulary of the configured model is
+// available, embedded or overridden; without it token counts are estimates.
+func doctorTokenizer(r *doctorReport, cfg *config.Config) {
+	enc := tokenizer.ForModel(cfg.Provider, cfg.Model).Encoding()
+	loaded, err := enc.Loaded()
+	switch {
+	case loaded:
+		r.add("tokenizer", "pass", fmt.Sprintf("%s (%s)", enc.Name(), enc.Source()))
+	case errors.Is(err, fs.ErrNotExist):
+		path := filepath.Join(tokenizer.VocabDir(), enc.Name()+".tiktoken")
+		r.add("tokenizer", "warn", fmt.Sprintf("%s is not embedded in this build; token counts are estimated. Run go generate ./internal/tokenizer, or download https://openaipublic.blob.core.windows.net/encodings/%s.tiktoken to %s",
+			enc.Name(), enc.Name(), path))
+	default:
+		r.add("tokenizer", "fail", enc.Name(), err)
+	}
+}
+
+func doctorGit(r *doctorReport, cwd string) {
+	git := agent.GitExecutable()
+	out, err := exec.Command(git, "--version").Output()
+	if err != nil {
+		r.add("git", "fail", git, err)
+		return
+	}
+	detail := fmt.Sprintf("%s (%s)", git, strings.TrimSpace(string(out)))
+	cmd := exec.Command(git, "rev-parse", "--show-toplevel")
+	cmd.Dir = cwd
+	if err := cmd.Run(); err != nil {
+		r.add("git", "warn", detail+"; working directory is not a git repository")
+		return
+	}
+	r.add("git", "pass", detail)
+}
+
+func doctorSessionDB(r *doctorReport) {
+	dbPath, err := session.DefaultDBPath()
+	if err != nil {
+		r.add("session db", "fail", "", err)
+		return
+	}
+	store, err := session.NewSQLiteStore(dbPath)
+	if err != nil {
+		r.add("session db", "fail", dbPath, err)
+		return
+	}
+	defer store.Close()
+
+	var mode string
+	if err := store.DB().QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		r.add("session db", "fail", dbPath, err)
+		return
+	}
+	if !strings.EqualFold(mode, "wal") {
+		r.add("session db", "warn", fmt.Sprintf("%s (journal_mode=%s, expected wal)", dbPath, mode))
+		return
+	}
+	r.add("session db", "pass", dbPath+" (wal)")
+}
+
+func doctorMCP(ctx context.Context, r *doctorReport, cwd string) {
+	mcpCfg, err := mcp.LoadMCPConfig(cwd)
+	if err != nil {
+		r.add("mcp", "fail", "could not load mcp.json", err)
+		return
+	}
+	if len(mcpCfg.MCPServers) == 0 {
+		r.add("mcp", "skip", "no servers configured")
+		return
+	}
+
+	mgr := mcp.NewManager(mcpCfg)
+	defer mgr.Close()
+	names := make([]string, 0, len(mcpCfg.MCPServers))
+	for name := range mcpCfg.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		probeCtx, cancel := context.WithTimeout(ctx, doctorMCPTimeout)
+		n, err := mgr.Probe(probeCtx, name)
+		cancel()
+		if err != nil {
+			r.add("mcp "+name, "fail", "connect failed", err)
+			continue
+		}
+		r.add("mcp "+name, "pass", fmt.Sprintf("%d tool(s)", n))
+	}
+}
+
+func doctorCommands(r *doctorReport, kind string, enabled bool, commands map[string]string) {
+	if !enabled || len(commands) == 0 {
+		r.add(kind, "skip", "disabled")
+		return
+	}
+	r.addProblems(kind, fmt.Sprintf("%d command(s)", len(commands)), tools.CheckCommandTemplates(commands))
+}
+
+// splitJoined unwraps an errors.Join result into its parts.
+func splitJoined(err error) []error {
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return j.Unwrap()
+	}
+	return []error{err}
+}
+
+func printDoctorReport(r doctorReport) {
+	width := 0
+	for _, c := range r.Checks {
+		width = max(width, len(c.Name))
+	}
+	for _, c := range r.Checks {
+		fmt.Printf("  %-4s  %-*s  %s\n", strings.ToUpper(c.Status), width, c.Name, c.Detail)
+		indent := strings.Repeat(" ", width+10)
+		for _, p := range c.Problems {
+			fmt.Printf("%s- %s\n", indent, strings.ReplaceAll(p, "\n", "\n"+indent+"  "))
+		}
+	}
+	if r.OK {
+		fmt.Println("\nAll checks passed.")
+	}
+}
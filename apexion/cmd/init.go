@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/apexion-ai/apexion/internal/config"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -30,14 +31,14 @@ func runInit() error {
 
 	// Provider selection
 	providers := []string{
-		"openai", "anthropic", "deepseek", "minimax",
-		"kimi", "qwen", "glm", "doubao", "groq",
+		"openai", "anthropic", "gemini", "deepseek", "minimax",
+		"kimi", "qwen", "glm", "doubao", "groq", "ollama",
 	}
 	fmt.Println("Available providers:")
 	for i, p := range providers {
 		fmt.Printf("  %d. %s\n", i+1, p)
 	}
-	fmt.Print("\nSelect provider (1-9) [1]: ")
+	fmt.Printf("\nSelect provider (1-%d) [1]: ", len(providers))
 	input, _ := reader.ReadString('\n')
 	input = strings.TrimSpace(input)
 
@@ -57,20 +58,22 @@ func runInit() error {
 	fmt.Printf("Selected: %s\n\n", providerName)
 
 	// API key
-	fmt.Printf("Enter API key for %s: ", providerName)
-	apiKey, _ := reader.ReadString('\n')
-	apiKey = strings.TrimSpace(apiKey)
-	if apiKey == "" {
-		return fmt.Errorf("API key cannot be empty")
+	providerCfg := map[string]any{}
+	if config.ProviderNeedsAPIKey(providerName) {
+		fmt.Printf("Enter API key for %s: ", providerName)
+		apiKey, _ := reader.ReadString('\n')
+		apiKey = strings.TrimSpace(apiKey)
+		if apiKey == "" {
+			return fmt.Errorf("API key cannot be empty")
+		}
+		providerCfg["api_key"] = apiKey
 	}
 
 	// Build config YAML
 	configData := map[string]any{
 		"provider": providerName,
 		"providers": map[string]any{
-			providerName: map[string]any{
-				"api_key": apiKey,
-			},
+			providerName: providerCfg,
 		},
 		"permissions": map[string]any{
 			"mode":               "interactive",
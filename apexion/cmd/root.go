@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/apexion-ai/apexion/internal/agent"
 	"github.com/apexion-ai/apexion/internal/config"
+	"github.com/apexion-ai/apexion/internal/httpclient"
 	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/tools"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
@@ -19,7 +27,11 @@ var (
 	useTUI       bool
 	pipeMode     bool
 	outputFormat string
+	inputFormat  string
 	printLast    bool
+	recordFile   string
+	replayFile   string
+	replayStrict bool
 
 	// Package-level version info, set by Execute().
 	appVersion string
@@ -64,13 +76,27 @@ func Execute(version, commit, date string) {
 	rootCmd.AddCommand(newVersionCmd(version, commit, date))
 	rootCmd.AddCommand(newInitCmd())
 	rootCmd.AddCommand(newEvalToolRoutingCmd())
+	rootCmd.AddCommand(newDoctorCmd())
 
 	if err := rootCmd.Execute(); err != nil {
+		var be *agent.BudgetError
+		if errors.As(err, &be) {
+			// One JSON line so scripts can tell a budget stop from a failure.
+			line, _ := json.Marshal(struct {
+				Error string `json:"error"`
+				*agent.BudgetError
+			}{"budget_exceeded", be})
+			fmt.Fprintln(os.Stderr, string(line))
+			os.Exit(exitBudgetExceeded)
+		}
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
+// exitBudgetExceeded is the exit status of a run stopped by a budget.
+const exitBudgetExceeded = 3
+
 // displayVersion returns a formatted version string for the TUI welcome page,
 // e.g. "v0.3.1 (abc1234)".
 func displayVersion() string {
@@ -83,11 +109,21 @@ func displayVersion() string {
 
 // initConfig loads configuration, applying CLI flag overrides.
 func initConfig() *config.Config {
-	cfg, err := config.Load(cfgFile)
+	cfg, err := loadConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
+	return cfg
+}
+
+// loadConfig is initConfig without the exit, for callers that report
+// errors themselves.
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return nil, err
+	}
 
 	// CLI flags override config values
 	if providerFlag != "" {
@@ -103,19 +139,52 @@ func initConfig() *config.Config {
 		cfg.MaxIterations = maxTurnsFlag
 	}
 
-	return cfg
+	return cfg, nil
 }
 
 // providerBaseURLs references the canonical map in the config package.
 var providerBaseURLs = config.KnownProviderBaseURLs
 
 // buildProvider creates a Provider instance based on configuration.
+// When the provider has a fallback chain configured, the result is a
+// FallbackProvider with the configured provider as primary. The web tools
+// are pointed at the provider's network settings as well.
 func buildProvider(cfg *config.Config) (provider.Provider, error) {
-	name := cfg.Provider
+	if err := setupWebTransport(cfg); err != nil {
+		return nil, err
+	}
+	p, err := buildNamedProvider(cfg, cfg.Provider, cfg.Model)
+	if err != nil {
+		return nil, err
+	}
+	fallback := cfg.GetProviderConfig(cfg.Provider).Fallback
+	if len(fallback) == 0 {
+		return p, nil
+	}
+
+	targets := []provider.FallbackTarget{{Provider: p}}
+	for _, spec := range fallback {
+		name, model, _ := strings.Cut(spec, "/")
+		fp, err := buildNamedProvider(cfg, name, model)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping fallback %q: %v\n", spec, err)
+			continue
+		}
+		targets = append(targets, provider.FallbackTarget{Provider: fp, Model: fp.DefaultModel()})
+	}
+	if len(targets) == 1 {
+		return p, nil
+	}
+	return provider.NewFallbackProvider(targets...), nil
+}
+
+// buildNamedProvider creates the provider called name. An empty model
+// falls back to the provider's configured or built-in default.
+func buildNamedProvider(cfg *config.Config, name, model string) (provider.Provider, error) {
 	pc := cfg.GetProviderConfig(name)
 
 	apiKey := pc.APIKey
-	if apiKey == "" {
+	if apiKey == "" && config.ProviderNeedsAPIKey(name) {
 		return nil, fmt.Errorf(
 			"API key not configured for provider %q.\n"+
 				"Set it via:\n"+
@@ -127,7 +196,6 @@ func buildProvider(cfg *config.Config) (provider.Provider, error) {
 	}
 
 	// Determine model: CLI flag > config file > provider defaults YAML
-	model := cfg.Model
 	if pc.Model != "" && model == "" {
 		model = pc.Model
 	}
@@ -137,9 +205,41 @@ func buildProvider(cfg *config.Config) (provider.Provider, error) {
 		}
 	}
 
+	var httpClient *http.Client
+	if opts := httpOptions(pc.HTTP); !opts.IsZero() {
+		c, err := httpclient.New(opts)
+		if err != nil {
+			return nil, fmt.Errorf("providers.%s.http: %w", name, err)
+		}
+		httpClient = c
+	}
+
 	switch name {
 	case "anthropic":
-		p := provider.NewAnthropicProvider(apiKey, model)
+		p := provider.NewAnthropicProvider(apiKey, model, httpClient)
+		return rateLimited(name, pc, p), nil
+	case "gemini":
+		// Native generateContent API; legacy OpenAI-compat base URLs are normalized.
+		baseURL := pc.BaseURL
+		if baseURL == "" {
+			baseURL = providerBaseURLs[name]
+		}
+		p := provider.NewGeminiProvider(apiKey, baseURL, model, httpClient)
+		return rateLimited(name, pc, p), nil
+	case "ollama":
+		// Native /api/chat; model list and context sizes come from the server.
+		baseURL := pc.BaseURL
+		if baseURL == "" {
+			baseURL = providerBaseURLs[name]
+		}
+		p := provider.NewOllamaProvider(baseURL, model, httpClient)
+		return rateLimited(name, pc, p), nil
+	case "scripted":
+		// Offline scenario player for tests; no API involved.
+		p, err := provider.NewScriptedProvider(pc.Script)
+		if err != nil {
+			return nil, err
+		}
 		return p, nil
 	default:
 		// All other providers use OpenAI-compatible API
@@ -151,7 +251,52 @@ func buildProvider(cfg *config.Config) (provider.Provider, error) {
 				return nil, fmt.Errorf("unknown provider %q; set providers.%s.base_url in config", name, name)
 			}
 		}
-		p := provider.NewOpenAIProvider(apiKey, baseURL, model)
-		return p, nil
+		switch pc.Transport {
+		case "", "chat":
+			p := provider.NewOpenAIProvider(apiKey, baseURL, model, httpClient)
+			return rateLimited(name, pc, p), nil
+		case "responses":
+			p := provider.NewOpenAIResponsesProvider(apiKey, baseURL, model, httpClient)
+			return rateLimited(name, pc, p), nil
+		default:
+			return nil, fmt.Errorf("unknown transport %q for provider %q; use \"chat\" or \"responses\"", pc.Transport, name)
+		}
+	}
+}
+
+// httpOptions converts a provider's http config section.
+func httpOptions(hc config.HTTPConfig) httpclient.Options {
+	return httpclient.Options{
+		Proxy:              hc.Proxy,
+		CACert:             hc.CACert,
+		InsecureSkipVerify: hc.InsecureSkipVerify,
+		Headers:            hc.Headers,
+		ConnectTimeout:     time.Duration(hc.ConnectTimeoutSec) * time.Second,
+		StreamIdleTimeout:  time.Duration(hc.StreamIdleTimeoutSec) * time.Second,
+	}
+}
+
+// setupWebTransport routes web_fetch, web_search and doc_context through
+// the active provider's proxy, CA bundle and timeouts. Headers are left
+// out: they authenticate against the LLM gateway and must not be sent to
+// arbitrary web sites.
+func setupWebTransport(cfg *config.Config) error {
+	opts := httpOptions(cfg.GetProviderConfig(cfg.Provider).HTTP)
+	opts.Headers = nil
+	rt, err := httpclient.NewTransport(opts)
+	if err != nil {
+		return fmt.Errorf("providers.%s.http: %w", cfg.Provider, err)
 	}
+	tools.SetWebTransport(rt)
+	return nil
+}
+
+// rateLimited puts p behind the shared governor for name, so every agent
+// calling this provider draws from the same request and token budgets.
+func rateLimited(name string, pc *config.ProviderConfig, p provider.Provider) provider.Provider {
+	gov := provider.GovernorFor(name, provider.RateLimit{
+		RequestsPerMinute: pc.RateLimit.RequestsPerMinute,
+		TokensPerMinute:   pc.RateLimit.TokensPerMinute,
+	})
+	return provider.NewRateLimitedProvider(p, gov)
 }
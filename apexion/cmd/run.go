@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -10,8 +12,10 @@ import (
 	"syscall"
 
 	"github.com/apexion-ai/apexion/internal/agent"
+	"github.com/apexion-ai/apexion/internal/config"
 	"github.com/apexion-ai/apexion/internal/mcp"
 	"github.com/apexion-ai/apexion/internal/permission"
+	"github.com/apexion-ai/apexion/internal/provider"
 	"github.com/apexion-ai/apexion/internal/session"
 	"github.com/apexion-ai/apexion/internal/tools"
 	"github.com/apexion-ai/apexion/internal/tui"
@@ -28,7 +32,10 @@ func newRunCmd() *cobra.Command {
 		Example: `  apexion run -P "read main.go and tell me what it does"
   apexion run --prompt "list all Go files"
   echo "explain main.go" | apexion run --pipe
-  apexion run -P "run tests and fix" --pipe --output-format jsonl`,
+  apexion run -P "run tests and fix" --pipe --output-format jsonl
+  my-driver | apexion run --pipe --input-format stream-json --output-format jsonl
+  apexion run -P "add a /lint command" --record testdata/lint.cassette
+  apexion run -P "add a /lint command" --replay testdata/lint.cassette --replay-strict`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Pipe mode: auto-detect from stdin or --pipe flag
 			if pipeMode || !term.IsTerminal(int(os.Stdin.Fd())) {
@@ -44,20 +51,73 @@ func newRunCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&prompt, "prompt", "P", "", "the prompt to execute")
 	cmd.Flags().BoolVar(&pipeMode, "pipe", false, "pipe mode: read stdin, write stdout, auto-approve all tools")
 	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "output format: text or jsonl (pipe mode)")
+	cmd.Flags().StringVar(&inputFormat, "input-format", "text", "input format: text or stream-json (pipe mode)")
 	cmd.Flags().BoolVar(&printLast, "print-last", false, "only output the final LLM response (pipe mode)")
+	cmd.Flags().StringVar(&recordFile, "record", "", "record provider requests and responses to a cassette file")
+	cmd.Flags().StringVar(&replayFile, "replay", "", "serve provider responses from a cassette file instead of the API")
+	cmd.Flags().BoolVar(&replayStrict, "replay-strict", false, "fail on requests that are not in the replay cassette")
+	cmd.MarkFlagsMutuallyExclusive("record", "replay")
 
 	return cmd
 }
 
+// buildRunProvider builds the provider for `apexion run`, honouring
+// --record and --replay. The returned func flushes the cassette.
+func buildRunProvider(cfg *config.Config) (provider.Provider, func(), error) {
+	if replayFile != "" {
+		rp, err := provider.NewReplayProvider(replayFile, replayStrict)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rp, func() {
+			if n := rp.Remaining(); n > 0 {
+				fmt.Fprintf(os.Stderr, "replay: %d recorded interaction(s) not used\n", n)
+			}
+		}, nil
+	}
+
+	p, err := buildProvider(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if recordFile == "" {
+		return p, func() {}, nil
+	}
+	rec, err := provider.NewRecordingProvider(p, recordFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rec, func() { _ = rec.Close() }, nil
+}
+
 // runPipe executes in pipe mode: reads from stdin if no prompt, writes to stdout, auto-approves.
+// With --input-format stream-json, stdin is read line by line: the first
+// message is the prompt (unless -P is given) and later ones steer the
+// running turn.
 func runPipe(prompt string) error {
-	// If no prompt flag, read from stdin.
-	if prompt == "" {
-		data, err := io.ReadAll(os.Stdin)
-		if err != nil {
-			return fmt.Errorf("failed to read stdin: %w", err)
+	var steering *bufio.Scanner
+	switch inputFormat {
+	case "", "text":
+		// If no prompt flag, read from stdin.
+		if prompt == "" {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("failed to read stdin: %w", err)
+			}
+			prompt = strings.TrimSpace(string(data))
+		}
+	case "stream-json":
+		steering = bufio.NewScanner(os.Stdin)
+		steering.Buffer(make([]byte, 0, 64*1024), 4<<20)
+		if prompt == "" {
+			var err error
+			prompt, err = nextStreamJSONInput(steering)
+			if err != nil && err != io.EOF {
+				return err
+			}
 		}
-		prompt = strings.TrimSpace(string(data))
+	default:
+		return fmt.Errorf("unknown --input-format %q (want text or stream-json)", inputFormat)
 	}
 	if prompt == "" {
 		return fmt.Errorf("no prompt provided (use -P or pipe via stdin)")
@@ -66,11 +126,12 @@ func runPipe(prompt string) error {
 	cfg := initConfig()
 	cfg.Permissions.Mode = "auto-approve" // auto-approve in pipe mode
 
-	p, err := buildProvider(cfg)
+	p, closeProvider, err := buildRunProvider(cfg)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	defer closeProvider()
 
 	if cfg.Model == "" {
 		cfg.Model = p.DefaultModel()
@@ -85,6 +146,7 @@ func runPipe(prompt string) error {
 	})
 	policy := permission.AllowAllPolicy{}
 	executor := tools.NewExecutor(registry, policy)
+	executor.SetMaxParallelReads(cfg.MaxParallelReads)
 
 	// Load hooks
 	cwd, _ := os.Getwd()
@@ -110,11 +172,22 @@ func runPipe(prompt string) error {
 
 	store := session.NullStore{}
 	ui := tui.NewPipeIO(outputFormat, true, printLast)
+	if steering != nil {
+		go readSteering(steering, ui)
+	}
 
 	a := agent.New(p, executor, cfg, ui, store)
 	if mcpMgr != nil {
 		a.SetMCPManager(mcpMgr)
 	}
+	// Pipe mode saves no sessions, but its spend still counts toward the
+	// daily budget of later runs.
+	if spendStore, closeSpend, err := openSpendStore(); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: spend not persisted:", err)
+	} else {
+		defer closeSpend()
+		a.SetSpendStore(spendStore)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -131,15 +204,69 @@ func runPipe(prompt string) error {
 	return err
 }
 
+// openSpendStore opens the spend table of the session DB on its own.
+func openSpendStore() (session.SpendStore, func(), error) {
+	dbPath, err := session.DefaultDBPath()
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := session.NewSQLiteStore(dbPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	ss, err := session.NewSQLiteSpendStore(store.DB())
+	if err != nil {
+		store.Close()
+		return nil, nil, err
+	}
+	return ss, func() { store.Close() }, nil
+}
+
+// nextStreamJSONInput returns the next message from stream-json input,
+// skipping blank lines. It returns io.EOF at the end of input.
+func nextStreamJSONInput(sc *bufio.Scanner) (string, error) {
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		return tui.ParseStreamJSONInput(line)
+	}
+	if err := sc.Err(); err != nil {
+		return "", fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return "", io.EOF
+}
+
+// readSteering queues each further stream-json message for the running
+// turn. Malformed lines are reported on stderr and skipped.
+func readSteering(sc *bufio.Scanner, ui *tui.PipeIO) {
+	for {
+		text, err := nextStreamJSONInput(sc)
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			if sc.Err() != nil {
+				return
+			}
+			continue
+		}
+		ui.Steer(text)
+	}
+}
+
 // runOnce executes a single prompt and exits.
 func runOnce(prompt string) error {
 	cfg := initConfig()
 
-	p, err := buildProvider(cfg)
+	p, closeProvider, err := buildRunProvider(cfg)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	defer closeProvider()
 
 	if cfg.Model == "" {
 		cfg.Model = p.DefaultModel()
@@ -154,6 +281,7 @@ func runOnce(prompt string) error {
 	})
 	policy := permission.NewDefaultPolicy(&cfg.Permissions)
 	executor := tools.NewExecutor(registry, policy)
+	executor.SetMaxParallelReads(cfg.MaxParallelReads)
 
 	// Load hooks from .apexion/hooks.yaml and ~/.config/apexion/hooks.yaml
 	cwd, _ := os.Getwd()
@@ -188,6 +316,11 @@ func runOnce(prompt string) error {
 		os.Exit(1)
 	}
 	defer store.Close()
+	spendStore, err := session.NewSQLiteSpendStore(store.DB())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "open spend store:", err)
+		os.Exit(1)
+	}
 
 	if useTUI {
 		tuiCfg := tui.TUIConfig{
@@ -203,6 +336,7 @@ func runOnce(prompt string) error {
 				executor.SetToolCanceller(tc)
 			}
 			a := agent.New(p, executor, cfg, ui, store)
+			a.SetSpendStore(spendStore)
 			if mcpMgr != nil {
 				a.SetMCPManager(mcpMgr)
 			}
@@ -215,6 +349,7 @@ func runOnce(prompt string) error {
 	executor.SetConfirmer(ui)
 
 	a := agent.New(p, executor, cfg, ui, store)
+	a.SetSpendStore(spendStore)
 	if mcpMgr != nil {
 		a.SetMCPManager(mcpMgr)
 	}
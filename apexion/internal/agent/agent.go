@@ -14,6 +14,7 @@ import (
 	"github.com/apexion-ai/apexion/internal/provider"
 	"github.com/apexion-ai/apexion/internal/repomap"
 	"github.com/apexion-ai/apexion/internal/session"
+	"github.com/apexion-ai/apexion/internal/tokenizer"
 	"github.com/apexion-ai/apexion/internal/tools"
 	"github.com/apexion-ai/apexion/internal/tui"
 )
@@ -39,10 +40,19 @@ type Agent struct {
 	planMode        bool
 	rules           []Rule
 	skills          []SkillInfo
+	agentDefs       []AgentDef // user-defined sub-agent types
+	worktrees       worktreeResults
 	hookManager     *tools.HookManager
 	eventLogger     *EventLogger
 	checkpointMgr   *CheckpointManager
 	costTracker     *CostTracker
+	budget          *Budget
+	verifier        *tools.Verifier // project checks before a turn with edits ends (nil = disabled)
+	planStore       session.PlanStore
+	plan            *session.Plan // cached plan of the current session
+	turnStop        string        // why the last agent loop stopped before the model finished
+	interactive     bool          // chat loop: a user is there to answer budget prompts
+	subAgent        bool          // ephemeral sub-agent sharing its parent's budget
 	repoMap         *repomap.RepoMap
 	bgManager       *BackgroundManager
 	promptVariant   string // "full" or "lite"
@@ -89,8 +99,10 @@ func NewWithSession(p provider.Provider, exec *tools.Executor, cfg *config.Confi
 		costOverrides = make(map[string]ModelPricing, len(cfg.CostPricing))
 		for model, entry := range cfg.CostPricing {
 			costOverrides[model] = ModelPricing{
-				InputPerMillion:  entry.InputPerMillion,
-				OutputPerMillion: entry.OutputPerMillion,
+				InputPerMillion:      entry.InputPerMillion,
+				OutputPerMillion:     entry.OutputPerMillion,
+				CacheWritePerMillion: entry.CacheWritePerMillion,
+				CacheReadPerMillion:  entry.CacheReadPerMillion,
 			}
 		}
 	}
@@ -108,11 +120,17 @@ func NewWithSession(p provider.Provider, exec *tools.Executor, cfg *config.Confi
 		customCommands:   loadCustomCommands(cwd),
 		rules:            loadRules(cwd),
 		skills:           loadSkills(cwd),
+		agentDefs:        loadAgentDefs(cwd),
 		costTracker:      NewCostTracker(costOverrides),
+		budget:           NewBudget(cfg.Budget, nil),
+		verifier:         tools.NewVerifier(cfg.Verify),
+		planStore:        session.NullPlanStore{},
 		imageBridgeCache: make(map[string]string),
 		toolHealth:       make(map[string]*toolHealthState),
 		firstStepAllowed: make(map[string]bool),
 	}
+	a.watchFailover(p)
+	a.watchThrottle(p)
 
 	// Initialize repo map (async build in background).
 	if !cfg.RepoMap.Disabled {
@@ -129,6 +147,55 @@ func NewWithSession(p provider.Provider, exec *tools.Executor, cfg *config.Confi
 	return a
 }
 
+// watchFailover reports failovers of a fallback chain as a system message
+// and an event-log entry. Other providers are left alone.
+func (a *Agent) watchFailover(p provider.Provider) {
+	fp, ok := p.(*provider.FallbackProvider)
+	if !ok {
+		return
+	}
+	fp.OnFailover = func(f provider.Failover) {
+		a.io.SystemMessage(fmt.Sprintf("Provider failover: %s → %s (%s)", f.From, f.To, f.Reason))
+		if a.eventLogger != nil {
+			a.eventLogger.Log(EventFailover, map[string]string{
+				"from":   f.From,
+				"to":     f.To,
+				"reason": f.Reason,
+			})
+		}
+	}
+}
+
+// watchThrottle shows rate-limiter waits in the status bar and logs them.
+// Every rate-limited provider in a fallback chain is watched.
+func (a *Agent) watchThrottle(p provider.Provider) {
+	targets := []provider.Provider{p}
+	if fp, ok := p.(*provider.FallbackProvider); ok {
+		targets = targets[:0]
+		for _, t := range fp.Targets() {
+			targets = append(targets, t.Provider)
+		}
+	}
+	for _, t := range targets {
+		rp, ok := t.(*provider.RateLimitedProvider)
+		if !ok {
+			continue
+		}
+		rp.OnThrottle = func(th provider.Throttle) {
+			if ts, ok := a.io.(tui.ThrottleStatus); ok {
+				ts.SetThrottled(th.Provider, th.Waiting, th.Wait)
+			}
+			if th.Wait > 0 && a.eventLogger != nil {
+				a.eventLogger.Log(EventThrottle, map[string]any{
+					"provider": th.Provider,
+					"lane":     th.Lane.String(),
+					"wait_ms":  th.Wait.Milliseconds(),
+				})
+			}
+		}
+	}
+}
+
 // SetProviderFactory sets the factory function for /provider hot-swap.
 func (a *Agent) SetProviderFactory(f ProviderFactory) {
 	a.providerFactory = f
@@ -141,6 +208,19 @@ func (a *Agent) SetMemoryStore(ms session.MemoryStore) {
 	a.rebuildSystemPrompt()
 }
 
+// SetSpendStore persists provider spend so session and daily budgets
+// survive restarts. Call it before the first turn.
+func (a *Agent) SetSpendStore(ss session.SpendStore) {
+	a.budget = NewBudget(a.config.Budget, ss)
+}
+
+// SetPlanStore persists the plans written in plan mode so they survive
+// restarts and /resume.
+func (a *Agent) SetPlanStore(ps session.PlanStore) {
+	a.planStore = ps
+	a.plan = nil
+}
+
 // SetMCPManager injects the MCP manager for /mcp command and status display.
 func (a *Agent) SetMCPManager(m *mcp.Manager) {
 	a.mcpManager = m
@@ -199,6 +279,42 @@ func (a *Agent) rebuildSystemPrompt() {
 	}
 }
 
+// contextWindow returns the context window used for budget math: the config
+// override if set, else the provider's window for the active model.
+func (a *Agent) contextWindow() int {
+	if a.config.ContextWindow > 0 {
+		return a.config.ContextWindow
+	}
+	if cw, ok := a.provider.(provider.ModelContextWindower); ok && a.config.Model != "" {
+		return cw.ContextWindowFor(a.config.Model)
+	}
+	return a.provider.ContextWindow()
+}
+
+// maxOutputTokens returns the output token budget for a model response and
+// the ceiling a truncated response may be retried with. The ceiling is the
+// model's known output limit, or the budget itself when the limit is
+// unknown, so unknown models are never sent a budget they might reject.
+func (a *Agent) maxOutputTokens() (budget, ceiling int) {
+	model := a.config.Model
+	if model == "" {
+		model = a.provider.DefaultModel()
+	}
+	budget = a.config.MaxTokensFor(model)
+	ceiling = max(provider.MaxOutputTokens(model), budget)
+	return budget, ceiling
+}
+
+// tokenCounter returns the tokenizer used for budget math with the current
+// provider/model.
+func (a *Agent) tokenCounter() session.TokenCounter {
+	model := a.config.Model
+	if model == "" {
+		model = a.provider.DefaultModel()
+	}
+	return tokenizer.ForModel(a.config.Provider, model)
+}
+
 // imageInputSupport returns whether the current provider/model should accept
 // image attachments, plus a short reason used in user-facing diagnostics.
 func (a *Agent) imageInputSupport() (supported bool, reason string, model string) {
@@ -223,6 +339,7 @@ func (a *Agent) imageInputSupport() (supported bool, reason string, model string
 
 // Run starts the interactive REPL loop.
 func (a *Agent) Run(ctx context.Context) error {
+	a.interactive = true
 	// Initialize event logger.
 	if el, err := NewEventLogger(a.session.ID); err == nil {
 		a.eventLogger = el
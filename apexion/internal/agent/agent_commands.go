@@ -2,12 +2,15 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/apexion-ai/apexion/internal/config"
 	"github.com/apexion-ai/apexion/internal/permission"
@@ -46,6 +49,9 @@ func (a *Agent) handleSlashCommand(ctx context.Context, input string) (bool, boo
 		} else {
 			a.io.SystemMessage(fmt.Sprintf("Tokens used: %d", a.session.TokensUsed))
 		}
+		if a.budget != nil {
+			a.io.SystemMessage(a.budget.Summary())
+		}
 		return true, false
 	case "/test":
 		return a.handleTest(arg), false
@@ -83,14 +89,7 @@ func (a *Agent) handleSlashCommand(ctx context.Context, input string) (bool, boo
 	case "/mcp":
 		return a.handleMCP(ctx, arg), false
 	case "/plan":
-		a.planMode = !a.planMode
-		if a.planMode {
-			a.io.SystemMessage("Plan mode ON — agent will analyze and propose, not execute.")
-		} else {
-			a.io.SystemMessage("Plan mode OFF — agent will execute normally.")
-		}
-		a.io.SetPlanMode(a.planMode)
-		return true, false
+		return a.handlePlan(ctx, arg), false
 	case "/rules":
 		return a.handleRules(), false
 	case "/skills":
@@ -130,7 +129,7 @@ func (a *Agent) handleCompact(ctx context.Context) bool {
 		a.io.SystemMessage("Summarizer not configured.")
 		return true
 	}
-	before := a.session.EstimateTokens()
+	before := a.session.EstimateTokens(a.tokenCounter())
 	summary, err := a.summarizer.Summarize(ctx, a.session.Summary, a.session.Messages)
 	if err != nil {
 		a.io.Error("Compact failed: " + err.Error())
@@ -140,7 +139,7 @@ func (a *Agent) handleCompact(ctx context.Context) bool {
 	a.session.Messages = session.TruncateSession(a.session.Messages, 10)
 	a.session.GentleCompactDone = false
 	a.session.GentleCompactPhase = 0
-	after := a.session.EstimateTokens()
+	after := a.session.EstimateTokens(a.tokenCounter())
 	a.io.SystemMessage(fmt.Sprintf("Compacted: %dk → %dk tokens. %d messages retained.\nSummary:\n%s",
 		before/1000, after/1000, len(a.session.Messages), truncate(summary, 300)))
 	return true
@@ -181,10 +180,14 @@ func (a *Agent) handleTrust(arg string) bool {
 func (a *Agent) handleHelp() bool {
 	help := `Available commands:
   /help              Show this help message
+  /model             Show current model (and installed models, if listable)
   /model <name>      Switch model (e.g. /model claude-haiku-4-5-20251001)
   /provider <name>   Switch provider (e.g. /provider deepseek)
   /config            Show current configuration
   /plan              Toggle plan mode (read-only analysis)
+  /plan show         Show the saved plan and step statuses
+  /plan edit         Edit the saved plan in $EDITOR
+  /plan execute      Run the plan step by step, resuming after failures
   /compact           Manually trigger context compaction
   /changes           Show files modified in this session
   /trust             Show session-level tool approvals
@@ -212,6 +215,8 @@ func (a *Agent) handleHelp() bool {
   /bg collect [id]   Collect completed agent output
   /bg cancel <id>    Cancel a running background agent
   /bg wait           Wait for all background agents
+  /bg merge <id>     Merge an isolated agent's worktree branch
+  /bg discard <id>   Delete an isolated agent's worktree branch
   /events [n]        Show recent event log entries
   /audit             Show bash command audit log
   /save              Save current session to disk
@@ -388,8 +393,30 @@ func (a *Agent) handleCustomCommand(ctx context.Context, cmd *CustomCommand, raw
 }
 
 func (a *Agent) handleModel(name string) bool {
+	available := a.listModels()
 	if name == "" {
-		a.io.SystemMessage(fmt.Sprintf("Current model: %s\nUsage: /model <name>", a.config.Model))
+		current := a.config.Model
+		if current == "" {
+			current = a.provider.DefaultModel()
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Current model: %s\nUsage: /model <name>", current)
+		if len(available) > 0 {
+			sb.WriteString("\n\nAvailable models:")
+			for _, m := range available {
+				marker := "  "
+				if m == current {
+					marker = "* "
+				}
+				fmt.Fprintf(&sb, "\n  %s%s", marker, m)
+			}
+		}
+		a.io.SystemMessage(sb.String())
+		return true
+	}
+	if len(available) > 0 && !slices.Contains(available, name) {
+		a.io.Error(fmt.Sprintf("Model %q is not available on %s. Available: %s",
+			name, a.provider.Name(), strings.Join(available, ", ")))
 		return true
 	}
 	old := a.config.Model
@@ -402,6 +429,26 @@ func (a *Agent) handleModel(name string) bool {
 	return true
 }
 
+// listModels returns the models the provider reports as installed, or nil
+// when the provider cannot enumerate them at runtime.
+func (a *Agent) listModels() []string {
+	lister, ok := a.provider.(provider.ModelLister)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	models, err := lister.ListModels(ctx)
+	if errors.Is(err, provider.ErrModelListUnsupported) {
+		return nil
+	}
+	if err != nil {
+		a.io.SystemMessage(fmt.Sprintf("Could not list models: %v", err))
+		return nil
+	}
+	return models
+}
+
 func (a *Agent) handleProvider(name string) bool {
 	if name == "" {
 		a.io.SystemMessage(fmt.Sprintf("Current provider: %s\nUsage: /provider <name>", a.config.Provider))
@@ -416,7 +463,7 @@ func (a *Agent) handleProvider(name string) bool {
 	pc := a.config.GetProviderConfig(name)
 	needSave := false
 
-	if pc.APIKey == "" {
+	if pc.APIKey == "" && config.ProviderNeedsAPIKey(name) {
 		a.io.SystemMessage(fmt.Sprintf("No API key configured for %q.", name))
 		a.io.SystemMessage("Enter API key:")
 		key, err := a.io.ReadInput()
@@ -470,6 +517,8 @@ func (a *Agent) handleProvider(name string) bool {
 	}
 	a.provider = p
 	a.summarizer = &session.LLMSummarizer{Provider: p}
+	a.watchFailover(p)
+	a.watchThrottle(p)
 	a.rebuildSystemPrompt()
 	a.io.SystemMessage(fmt.Sprintf("Provider switched: %s → %s (model: %s)",
 		oldName, name, p.DefaultModel()))
@@ -507,7 +556,7 @@ func (a *Agent) handleConfig() bool {
   Tokens used:    %d`,
 		a.config.Provider,
 		model,
-		a.provider.ContextWindow(),
+		a.contextWindow(),
 		maxIterDisplay,
 		a.config.Permissions.Mode,
 		a.session.ID,
@@ -619,6 +668,10 @@ func formatHistory(messages []provider.Message) string {
 			switch c.Type {
 			case provider.ContentTypeText:
 				fmt.Fprintf(&sb, "    text: %s\n", truncate(c.Text, 100))
+			case provider.ContentTypeThinking:
+				fmt.Fprintf(&sb, "    thinking: %s\n", truncate(c.Text, 100))
+			case provider.ContentTypeRedactedThinking:
+				sb.WriteString("    thinking: [redacted]\n")
 			case provider.ContentTypeToolUse:
 				fmt.Fprintf(&sb, "    tool_use: %s(%s)\n", c.ToolName, truncate(string(c.ToolInput), 60))
 			case provider.ContentTypeToolResult:
@@ -929,11 +982,6 @@ func (a *Agent) handleArchitect(arg string) bool {
 }
 
 func (a *Agent) handleBG(arg string) bool {
-	if a.bgManager == nil {
-		a.io.SystemMessage("Background agent manager not available.")
-		return true
-	}
-
 	parts := strings.SplitN(strings.TrimSpace(arg), " ", 2)
 	subcmd := ""
 	subarg := ""
@@ -944,6 +992,33 @@ func (a *Agent) handleBG(arg string) bool {
 		subarg = strings.TrimSpace(parts[1])
 	}
 
+	// Worktree results also come from foreground task sub-agents, so they
+	// are handled without the background manager.
+	switch subcmd {
+	case "merge", "discard":
+		if subarg == "" {
+			a.io.Error(fmt.Sprintf("Usage: /bg %s <id>", subcmd))
+			return true
+		}
+		if subcmd == "merge" {
+			if err := a.mergeWorktree(context.Background(), subarg); err != nil {
+				a.io.Error(err.Error())
+			} else {
+				a.io.SystemMessage(fmt.Sprintf("Merged the changes of %s.", subarg))
+			}
+		} else if err := a.discardWorktree(context.Background(), subarg); err != nil {
+			a.io.Error(err.Error())
+		} else {
+			a.io.SystemMessage(fmt.Sprintf("Discarded the changes of %s.", subarg))
+		}
+		return true
+	}
+
+	if a.bgManager == nil {
+		a.io.SystemMessage("Background agent manager not available.")
+		return true
+	}
+
 	switch subcmd {
 	case "collect":
 		if subarg == "" {
@@ -1001,7 +1076,11 @@ func (a *Agent) handleBG(arg string) bool {
 		a.io.SystemMessage("All background agents completed.")
 
 	default:
-		a.io.SystemMessage(a.bgManager.Summary())
+		summary := a.bgManager.Summary()
+		if wt := a.worktrees.summary(); wt != "" {
+			summary += "\n\n" + wt
+		}
+		a.io.SystemMessage(summary)
 	}
 
 	return true
@@ -2,6 +2,8 @@ package agent
 
 import (
 	"context"
+	"fmt"
+	"os"
 
 	"github.com/apexion-ai/apexion/internal/permission"
 	"github.com/apexion-ai/apexion/internal/session"
@@ -46,6 +48,14 @@ Rules:
 - When finished, provide a clear summary of all changes you made.
 - Do NOT create unnecessary files or add features beyond what was asked.`
 
+// worktreePromptNote is appended to the system prompt of a sub-agent that
+// runs in an isolated worktree.
+const worktreePromptNote = `
+
+You are working in an isolated git worktree at %s, on the scratch branch %s.
+File paths and bash commands resolve there, not in the user's working tree.
+Your changes are committed to the branch when you finish. Do not push, and do not switch branches.`
+
 // wireTaskTool finds the TaskTool in the registry and injects the sub-agent runner
 // and confirmer (for code mode).
 func (a *Agent) wireTaskTool() {
@@ -58,6 +68,8 @@ func (a *Agent) wireTaskTool() {
 		return
 	}
 	tt.SetRunner(a.runSubAgent)
+	tt.SetTypes(a.subAgentTypes())
+	tt.SetIsolated(a.config.SubAgentIsolation == "worktree")
 	// Wire confirmer for code mode confirmation (if available).
 	if c, ok := a.io.(tools.Confirmer); ok {
 		tt.SetConfirmer(c)
@@ -86,7 +98,7 @@ type subAgentReporter interface {
 }
 
 // runSubAgent creates and runs an ephemeral sub-agent.
-// mode is "explore" (default), "plan", or "code".
+// mode is "explore" (default), "plan", "code", or the name of an agent definition.
 func (a *Agent) runSubAgent(ctx context.Context, prompt string, mode string) (string, error) {
 	// If the main IO supports progress reporting, wire it up.
 	var buf *tui.BufferIO
@@ -101,14 +113,19 @@ func (a *Agent) runSubAgent(ctx context.Context, prompt string, mode string) (st
 	var executor *tools.Executor
 	var sysPrompt string
 
-	switch mode {
-	case "code":
+	def := a.agentDef(mode)
+	switch {
+	case def != nil:
+		// Tools beyond the read-only set were confirmed at task call time.
+		executor = tools.NewExecutor(a.agentDefRegistry(ctx, def), permission.AllowAllPolicy{})
+		sysPrompt = def.Prompt
+	case mode == "code":
 		// Code sub-agent gets write permissions with AllowAll policy
 		// (user already confirmed via the confirmer at task call time).
 		codeRegistry := tools.CodeRegistry()
 		executor = tools.NewExecutor(codeRegistry, permission.AllowAllPolicy{})
 		sysPrompt = codeSubAgentSystemPrompt
-	case "plan":
+	case mode == "plan":
 		roRegistry := tools.ReadOnlyRegistry()
 		executor = tools.NewExecutor(roRegistry, permission.AllowAllPolicy{})
 		sysPrompt = planSubAgentSystemPrompt
@@ -118,6 +135,20 @@ func (a *Agent) runSubAgent(ctx context.Context, prompt string, mode string) (st
 		sysPrompt = subAgentSystemPrompt
 	}
 
+	// With worktree isolation, sub-agents that can modify files get a
+	// worktree of their own so that parallel ones cannot clobber each other.
+	var wt *worktree
+	if a.isolateSubAgent(ctx, mode, def) {
+		var err error
+		if wt, err = a.newWorktree(ctx); err != nil {
+			return "", fmt.Errorf("cannot create worktree: %w", err)
+		}
+		cwd, _ := os.Getwd()
+		root := wt.root(cwd)
+		executor = tools.NewExecutor(tools.RootedRegistry(executor.Registry(), root, cwd), permission.AllowAllPolicy{})
+		sysPrompt += fmt.Sprintf(worktreePromptNote, root, wt.branch)
+	}
+
 	subCfg := *a.config
 	subCfg.MaxIterations = 0
 	subCfg.SystemPrompt = sysPrompt
@@ -125,6 +156,12 @@ func (a *Agent) runSubAgent(ctx context.Context, prompt string, mode string) (st
 	if a.config.SubAgentModel != "" {
 		subCfg.Model = a.config.SubAgentModel
 	}
+	if def != nil {
+		if def.Model != "" {
+			subCfg.Model = def.Model
+		}
+		subCfg.MaxIterations = def.MaxIterations
+	}
 
 	sub := &Agent{
 		provider:   a.provider,
@@ -134,9 +171,20 @@ func (a *Agent) runSubAgent(ctx context.Context, prompt string, mode string) (st
 		store:      session.NullStore{},
 		basePrompt: sysPrompt,
 		io:         buf,
+		// Spend is priced and budgeted with the parent's.
+		costTracker:      a.costTracker,
+		budget:           a.budget,
+		subAgent:         true,
+		imageBridgeCache: make(map[string]string),
+		toolHealth:       make(map[string]*toolHealthState),
+		firstStepAllowed: make(map[string]bool),
 	}
 	sub.rebuildSystemPrompt()
 
 	err := sub.RunOnce(ctx, prompt)
-	return buf.Output(), err
+	output := buf.Output()
+	if wt != nil {
+		output += a.finishWorktree(wt, prompt)
+	}
+	return output, err
 }
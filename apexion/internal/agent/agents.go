@@ -0,0 +1,312 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/apexion-ai/apexion/internal/mcp"
+	"github.com/apexion-ai/apexion/internal/tools"
+	"gopkg.in/yaml.v3"
+)
+
+// AgentDef is a user-defined sub-agent type loaded from a markdown file.
+// The task tool delegates to it by name like to the built-in modes.
+type AgentDef struct {
+	Name          string        // frontmatter name, or the file name without extension
+	Description   string        // shown to the LLM in the task tool description
+	Tools         []string      // tool names or path.Match patterns; empty = read-only tools
+	Model         string        // model override; empty = sub_agent_model or the main model
+	Timeout       time.Duration // 0 = the task tool default
+	MaxIterations int           // 0 = unlimited
+	Prompt        string        // system prompt (the file body)
+	Path          string
+}
+
+// agentFrontmatter is the YAML frontmatter of an agent definition file.
+type agentFrontmatter struct {
+	Name          string   `yaml:"name"`
+	Description   string   `yaml:"description"`
+	Tools         []string `yaml:"tools"`
+	Model         string   `yaml:"model"`
+	TimeoutSec    int      `yaml:"timeout_sec"`
+	MaxIterations int      `yaml:"max_iterations"`
+}
+
+var agentNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// builtinSubAgentModes are the task tool modes agent definitions cannot replace.
+var builtinSubAgentModes = map[string]bool{"explore": true, "plan": true, "code": true}
+
+// noSubAgentTools are never given to a sub-agent: sub-agents do not nest
+// and cannot ask the user questions.
+var noSubAgentTools = map[string]bool{"task": true, "question": true}
+
+// loadAgentDefs scans for .apexion/agents/*.md in:
+//  1. ~/.config/apexion/agents/    (global)
+//  2. {gitRoot}/.apexion/agents/   (project)
+//  3. {cwd}/.apexion/agents/       (local)
+//
+// Like skills, the first definition of a name wins. Invalid files are skipped;
+// `apexion doctor` reports them.
+func loadAgentDefs(cwd string) []AgentDef {
+	return loadAgentDefsFromDirs(agentDirs(cwd, findGitRoot(cwd)))
+}
+
+// loadAgentDefsFromDirs parses the *.md agent definitions in the given directories.
+func loadAgentDefsFromDirs(dirs []string) []AgentDef {
+	seen := make(map[string]bool)
+	var defs []AgentDef
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+				continue
+			}
+			file := filepath.Join(dir, e.Name())
+			def, err := parseAgentDef(file)
+			if err != nil || seen[def.Name] {
+				continue
+			}
+			seen[def.Name] = true
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
+// agentDirs returns directories to scan for agent definitions.
+func agentDirs(cwd, gitRoot string) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+
+	add := func(dir string) {
+		abs, err := filepath.Abs(dir)
+		if err != nil || seen[abs] {
+			return
+		}
+		if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+			return
+		}
+		seen[abs] = true
+		dirs = append(dirs, abs)
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		add(filepath.Join(home, ".config", "apexion", "agents"))
+	}
+	if gitRoot != "" && gitRoot != cwd {
+		add(filepath.Join(gitRoot, ".apexion", "agents"))
+	}
+	add(filepath.Join(cwd, ".apexion", "agents"))
+
+	return dirs
+}
+
+// parseAgentDef reads an agent definition: YAML frontmatter followed by
+// the system prompt.
+func parseAgentDef(file string) (AgentDef, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return AgentDef{}, err
+	}
+	content := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(content, "---") {
+		return AgentDef{}, errors.New("missing frontmatter")
+	}
+	end := strings.Index(content[3:], "---")
+	if end < 0 {
+		return AgentDef{}, errors.New("frontmatter is not closed with ---")
+	}
+	fm, err := decodeAgentFrontmatter(content[3 : 3+end])
+	if err != nil {
+		return AgentDef{}, err
+	}
+	def := AgentDef{
+		Name:          fm.Name,
+		Description:   strings.TrimSpace(fm.Description),
+		Tools:         fm.Tools,
+		Model:         fm.Model,
+		Timeout:       time.Duration(fm.TimeoutSec) * time.Second,
+		MaxIterations: fm.MaxIterations,
+		Prompt:        strings.TrimSpace(content[3+end+3:]),
+		Path:          file,
+	}
+	if def.Name == "" {
+		def.Name = strings.TrimSuffix(filepath.Base(file), ".md")
+	}
+	switch {
+	case !agentNameRe.MatchString(def.Name):
+		return AgentDef{}, fmt.Errorf("name %q must be lowercase letters, digits, '-' or '_'", def.Name)
+	case builtinSubAgentModes[def.Name]:
+		return AgentDef{}, fmt.Errorf("name %q is a built-in task mode", def.Name)
+	case def.Description == "":
+		return AgentDef{}, errors.New("description is required")
+	case def.Prompt == "":
+		return AgentDef{}, errors.New("system prompt (the body after the frontmatter) is empty")
+	case fm.TimeoutSec < 0 || fm.MaxIterations < 0:
+		return AgentDef{}, errors.New("timeout_sec and max_iterations must be non-negative")
+	}
+	for _, pattern := range def.Tools {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return AgentDef{}, fmt.Errorf("tools: bad pattern %q", pattern)
+		}
+	}
+	return def, nil
+}
+
+// decodeAgentFrontmatter decodes fm strictly so that misspelled keys are
+// reported rather than silently ignored.
+func decodeAgentFrontmatter(fm string) (agentFrontmatter, error) {
+	var meta agentFrontmatter
+	dec := yaml.NewDecoder(bytes.NewReader([]byte(fm)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&meta); err != nil && !errors.Is(err, io.EOF) {
+		return agentFrontmatter{}, err
+	}
+	return meta, nil
+}
+
+// CheckAgents validates every agent definition loadAgentDefs would read
+// and returns the number of files checked.
+func CheckAgents(cwd string) (int, []error) {
+	var n int
+	var errs []error
+	for _, dir := range agentDirs(cwd, findGitRoot(cwd)) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+				continue
+			}
+			n++
+			file := filepath.Join(dir, e.Name())
+			if _, err := parseAgentDef(file); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", file, err))
+			}
+		}
+	}
+	return n, errs
+}
+
+// agentDef returns the user-defined sub-agent type named mode, or nil.
+func (a *Agent) agentDef(mode string) *AgentDef {
+	for i := range a.agentDefs {
+		if a.agentDefs[i].Name == mode {
+			return &a.agentDefs[i]
+		}
+	}
+	return nil
+}
+
+// allows reports whether the definition grants the named tool.
+func (d *AgentDef) allows(name string) bool {
+	if noSubAgentTools[name] {
+		return false
+	}
+	for _, pattern := range d.Tools {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mcpServers returns the MCP servers named by mcp__server__* tool entries.
+func (d *AgentDef) mcpServers() []string {
+	var servers []string
+	for _, pattern := range d.Tools {
+		rest, ok := strings.CutPrefix(pattern, "mcp__")
+		if !ok {
+			continue
+		}
+		server, _, _ := strings.Cut(rest, "__")
+		if server != "" && !strings.ContainsAny(server, "*?[") {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// subAgentTypes describes the agent definitions for the task tool.
+func (a *Agent) subAgentTypes() []tools.SubAgentType {
+	var types []tools.SubAgentType
+	for i := range a.agentDefs {
+		def := &a.agentDefs[i]
+		types = append(types, tools.SubAgentType{
+			Name:        def.Name,
+			Description: def.Description,
+			ReadOnly:    a.agentDefReadOnly(def),
+			Timeout:     def.Timeout,
+		})
+	}
+	return types
+}
+
+// agentDefReadOnly reports whether every tool the definition grants is a
+// read-only built-in. MCP tools may have side effects, so they count as
+// writes.
+func (a *Agent) agentDefReadOnly(def *AgentDef) bool {
+	if len(def.Tools) == 0 {
+		return true
+	}
+	if len(def.mcpServers()) > 0 {
+		return false
+	}
+	for _, t := range a.executor.Registry().All() {
+		if def.allows(t.Name()) && !t.IsReadOnly() {
+			return false
+		}
+	}
+	return true
+}
+
+// agentDefRegistry builds the tool registry of a sub-agent run from the
+// definition's tool list, connecting the MCP servers it names first.
+func (a *Agent) agentDefRegistry(ctx context.Context, def *AgentDef) *tools.Registry {
+	if len(def.Tools) == 0 {
+		return tools.ReadOnlyRegistry()
+	}
+	reg := tools.NewRegistry()
+	for _, t := range a.executor.Registry().All() {
+		if def.allows(t.Name()) {
+			reg.Register(t)
+		}
+	}
+
+	var servers []string
+	for _, s := range def.mcpServers() {
+		if a.mcpManager != nil && a.mcpManager.HasServer(s) {
+			servers = append(servers, s)
+		}
+	}
+	if len(servers) == 0 {
+		return reg
+	}
+	connectCtx, cancel := context.WithTimeout(ctx, mcpLazyConnectTimeout)
+	defer cancel()
+	a.mcpManager.EnsureConnected(connectCtx, servers)
+	// Register into a scratch registry: the main one may be in use by
+	// concurrent tool calls.
+	mcpReg := tools.NewRegistry()
+	mcp.RegisterToolsForServers(a.mcpManager, mcpReg, servers)
+	for _, t := range mcpReg.All() {
+		if def.allows(t.Name()) {
+			reg.Register(t)
+		}
+	}
+	return reg
+}
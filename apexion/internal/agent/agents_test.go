@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apexion-ai/apexion/internal/config"
+	"github.com/apexion-ai/apexion/internal/permission"
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/session"
+	"github.com/apexion-ai/apexion/internal/tools"
+)
+
+func writeAgentFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadAgentDefs(t *testing.T) {
+	global := filepath.Join(t.TempDir(), "agents")
+	local := filepath.Join(t.TempDir(), ".apexion", "agents")
+	writeAgentFiles(t, global, map[string]string{
+		"reviewer.md": "---\ndescription: Global reviewer\n---\nGlobal prompt.",
+	})
+	writeAgentFiles(t, local, map[string]string{
+		"reviewer.md": "---\ndescription: Local reviewer\n---\nLocal prompt.",
+		"migrations.md": `---
+name: migration-writer
+description: Writes database migrations
+tools: [read_file, write_file, "mcp__db__*"]
+model: big
+timeout_sec: 300
+max_iterations: 20
+---
+Write reversible migrations.`,
+		"no-desc.md":  "---\nname: no-desc\n---\nPrompt.",
+		"builtin.md":  "---\nname: code\ndescription: Shadows code mode\n---\nPrompt.",
+		"no-body.md":  "---\ndescription: Empty prompt\n---\n",
+		"typo.md":     "---\ndescription: Misspelled key\ntimeout: 10\n---\nPrompt.",
+		"no-front.md": "Just a prompt.",
+	})
+
+	defs := loadAgentDefsFromDirs([]string{global, local})
+	if len(defs) != 2 {
+		t.Fatalf("expected 2 definitions, got %+v", defs)
+	}
+	byName := map[string]AgentDef{}
+	for _, d := range defs {
+		byName[d.Name] = d
+	}
+	if d := byName["reviewer"]; d.Description != "Global reviewer" || d.Prompt != "Global prompt." {
+		t.Errorf("first definition should win: %+v", d)
+	}
+	m := byName["migration-writer"]
+	if m.Model != "big" || m.Timeout != 300*time.Second || m.MaxIterations != 20 ||
+		m.Prompt != "Write reversible migrations." || len(m.Tools) != 3 {
+		t.Errorf("unexpected definition: %+v", m)
+	}
+	if got := m.mcpServers(); len(got) != 1 || got[0] != "db" {
+		t.Errorf("mcpServers = %v, want [db]", got)
+	}
+	for name, want := range map[string]bool{"read_file": true, "mcp__db__query": true, "bash": false, "mcp__web__get": false} {
+		if m.allows(name) != want {
+			t.Errorf("allows(%q) = %v, want %v", name, !want, want)
+		}
+	}
+}
+
+func TestCheckAgents(t *testing.T) {
+	cwd := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	writeAgentFiles(t, filepath.Join(cwd, ".apexion", "agents"), map[string]string{
+		"ok.md":   "---\ndescription: Fine\n---\nPrompt.",
+		"typo.md": "---\ndescription: Misspelled key\ntimeout: 10\n---\nPrompt.",
+	})
+	n, errs := CheckAgents(cwd)
+	if n != 2 || len(errs) != 1 || !strings.Contains(errs[0].Error(), "typo.md") {
+		t.Errorf("CheckAgents = %d, %v", n, errs)
+	}
+}
+
+const agentDefScript = `
+turns:
+  - tool_calls:
+      - name: task
+        input: {prompt: "Review main.go", mode: reviewer}
+  - expect:
+      system_contains: "You review Go code."
+      user_contains: "Review main.go"
+      tools: [read_file, grep]
+    tool_calls:
+      - name: read_file
+        input: {file_path: main.go}
+  - text: "main.go looks fine."
+  - expect:
+      tool_results:
+        - tool: task
+          contains: "main.go looks fine."
+    text: "The reviewer found no issues."
+`
+
+func TestAgentDef_TaskDelegates(t *testing.T) {
+	p, err := provider.ParseScript([]byte(agentDefScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("HOME", t.TempDir())
+	writeAgentFiles(t, filepath.Join(dir, ".apexion", "agents"), map[string]string{
+		"reviewer.md": "---\ndescription: Reviews Go code\ntools: [read_file, grep]\n---\nYou review Go code.",
+	})
+	if err := os.WriteFile("main.go", []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Provider = "scripted"
+	cfg.RepoMap.Disabled = true
+	cfg.ToolRouting.Enabled = false // offer every tool on the first step
+	executor := tools.NewExecutor(tools.DefaultRegistry(nil, nil), permission.AllowAllPolicy{})
+	ui := &scenarioIO{}
+	a := New(p, executor, cfg, ui, session.NullStore{})
+
+	task, _ := executor.Registry().Get("task")
+	if !strings.Contains(task.Description(), "'reviewer': Reviews Go code") {
+		t.Errorf("task description does not list the reviewer:\n%s", task.Description())
+	}
+	if types := a.subAgentTypes(); len(types) != 1 || !types[0].ReadOnly {
+		t.Errorf("subAgentTypes = %+v, want one read-only type", types)
+	}
+	reg := a.agentDefRegistry(t.Context(), a.agentDef("reviewer"))
+	if _, ok := reg.Get("write_file"); ok || len(reg.All()) != 2 {
+		t.Errorf("reviewer registry has %d tools, want read_file and grep only", len(reg.All()))
+	}
+
+	if err := a.RunOnce(t.Context(), "Review main.go"); err != nil {
+		t.Fatal(err)
+	}
+	if n := p.Remaining(); n > 0 {
+		t.Errorf("%d scripted turn(s) were never requested; output:\n%s", n, strings.Join(ui.system, "\n"))
+	}
+}
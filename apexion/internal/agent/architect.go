@@ -2,9 +2,13 @@ package agent
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/apexion-ai/apexion/internal/provider"
 )
 
 // ArchitectStep describes a single step in an architect plan.
@@ -13,6 +17,7 @@ type ArchitectStep struct {
 	Files       []string `json:"files"`
 	Action      string   `json:"action"` // "create" | "modify" | "delete" | "run"
 	Details     string   `json:"details"`
+	DependsOn   []int    `json:"depends_on"` // 1-based numbers of earlier steps this one needs
 }
 
 // ArchitectPlan is the structured output from the architect model.
@@ -28,22 +33,11 @@ type ArchitectMode struct {
 	architectModel string // big model for planning
 	coderModel     string // small model for execution
 	autoExecute    bool   // skip per-step confirmation
+	maxParallel    int    // steps dispatched at once
+	continueOnFail bool   // keep starting steps after a failure
 }
 
-const architectSystemPrompt = `You are a senior software architect. Analyze the user's request and the codebase, then output a structured JSON plan.
-
-Your plan MUST be valid JSON with this exact format:
-{
-  "summary": "Brief description of what will be done",
-  "steps": [
-    {
-      "description": "What this step does",
-      "files": ["path/to/file1.go", "path/to/file2.go"],
-      "action": "modify",
-      "details": "Specific instructions for the coder: what to change and how"
-    }
-  ]
-}
+const architectSystemPrompt = `You are a senior software architect. Turn the user's request and the codebase findings into a structured implementation plan: a short summary plus ordered steps, each with a description, the files it touches, an action, and details for the coder.
 
 Actions can be: "create" (new file), "modify" (edit existing), "delete" (remove file), "run" (execute a command).
 
@@ -53,23 +47,58 @@ Rules:
 - For "modify" actions, describe exactly what to change (not just "update the file").
 - For "run" actions, put the command in the details field.
 - Order steps logically (create before use, modify before test).
-- Output ONLY the JSON plan, no other text.`
+- In depends_on, list the numbers (starting at 1) of the earlier steps a step needs. Steps that touch different files and don't depend on each other run in parallel, so only list real dependencies.`
+
+const architectExplorePrompt = `You are a senior software architect preparing an implementation plan. Explore the codebase to understand the current architecture, then report the relevant files and how they fit together, and describe the changes the request needs, file by file. Do not write the plan itself.`
+
+// architectPlanSchema is the response schema for ArchitectPlan.
+var architectPlanSchema = &provider.ResponseSchema{
+	Name:        "architect_plan",
+	Description: "an implementation plan for the coder",
+	Schema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"summary": map[string]any{"type": "string", "description": "Brief description of what will be done"},
+			"steps": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"description": map[string]any{"type": "string", "description": "What this step does"},
+						"files":       map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+						"action":      map[string]any{"type": "string", "enum": []string{"create", "modify", "delete", "run"}},
+						"details":     map[string]any{"type": "string", "description": "Specific instructions for the coder: what to change and how"},
+						"depends_on":  map[string]any{"type": "array", "items": map[string]any{"type": "integer"}, "description": "Numbers of earlier steps this step needs"},
+					},
+					"required": []string{"description", "files", "action", "details", "depends_on"},
+				},
+			},
+		},
+		"required": []string{"summary", "steps"},
+	},
+}
 
 // NewArchitectMode creates an ArchitectMode from configuration.
 func NewArchitectMode(a *Agent, architectModel, coderModel string, autoExecute bool) *ArchitectMode {
+	maxParallel := a.config.Architect.MaxParallel
+	if maxParallel <= 0 {
+		maxParallel = 3
+	}
 	return &ArchitectMode{
 		agent:          a,
 		architectModel: architectModel,
 		coderModel:     coderModel,
 		autoExecute:    autoExecute,
+		maxParallel:    maxParallel,
+		continueOnFail: a.config.Architect.ContinueOnFailure,
 	}
 }
 
 // Run executes the full architect workflow:
 // 1. Send request to big model -> get structured plan
 // 2. Display plan to user
-// 3. Execute each step with small model code sub-agent
-// 4. Verify after each step
+// 3. Execute the steps with small model code sub-agents, independent
+// steps in parallel
 func (am *ArchitectMode) Run(ctx context.Context, prompt string) error {
 	a := am.agent
 
@@ -102,71 +131,38 @@ func (am *ArchitectMode) Run(ctx context.Context, prompt string) error {
 		}
 	}
 
-	// Phase 3: Execute each step
-	for i, step := range plan.Steps {
-		a.io.SystemMessage(fmt.Sprintf("\n--- Step %d/%d: %s ---", i+1, len(plan.Steps), step.Description))
-
-		if step.Action == "delete" {
-			a.io.SystemMessage("Skipping delete step (requires manual confirmation).")
-			continue
-		}
-
-		stepPrompt := am.buildStepPrompt(step)
-
-		// Use code sub-agent with the coder model
-		oldModel := a.config.SubAgentModel
-		if am.coderModel != "" {
-			a.config.SubAgentModel = am.coderModel
-		}
-
-		output, err := a.runSubAgent(ctx, stepPrompt, "code")
-
-		// Restore model
-		a.config.SubAgentModel = oldModel
-
-		if err != nil {
-			a.io.Error(fmt.Sprintf("Step %d failed: %v", i+1, err))
-			if !am.autoExecute {
-				a.io.SystemMessage("Continue with remaining steps? (send message to continue, /quit to stop)")
-				input, err := a.io.ReadInput()
-				if err != nil || strings.HasPrefix(input, "/") {
-					a.io.SystemMessage("Remaining steps cancelled.")
-					return nil
-				}
-			}
-			continue
-		}
-
-		// Show step result (truncated)
-		if output != "" {
-			summary := output
-			if len(summary) > 500 {
-				summary = summary[:500] + "..."
-			}
-			a.io.SystemMessage("Step result: " + summary)
-		}
-
-		a.io.SystemMessage(fmt.Sprintf("Step %d/%d complete.", i+1, len(plan.Steps)))
+	// Phase 3: Execute the steps
+	oldModel := a.config.SubAgentModel
+	if am.coderModel != "" {
+		a.config.SubAgentModel = am.coderModel
 	}
-
-	a.io.SystemMessage("\nArchitect mode complete. All steps executed.")
+	defer func() { a.config.SubAgentModel = oldModel }()
+
+	// Steps share the working tree even with worktree isolation, so that
+	// each one sees what the steps it depends on changed.
+	ctx = context.WithValue(ctx, sharedTreeKey{}, true)
+	am.executeSteps(ctx, plan, func(ctx context.Context, step ArchitectStep) (string, error) {
+		return a.runSubAgent(ctx, am.buildStepPrompt(step), "code")
+	})
 	return nil
 }
 
-// getPlan sends the prompt to the architect model and parses the JSON plan.
+// getPlan explores the codebase with a read-only sub-agent, then asks the
+// architect model to turn the findings into a structured plan.
 func (am *ArchitectMode) getPlan(ctx context.Context, prompt string) (*ArchitectPlan, error) {
 	a := am.agent
 
-	// Temporarily switch to architect model if configured
-	oldModel := a.config.Model
+	model := a.config.Model
 	if am.architectModel != "" {
-		a.config.Model = am.architectModel
+		model = am.architectModel
 	}
 
-	// Use explore sub-agent to generate the plan (read-only, returns text)
-	planPrompt := fmt.Sprintf("%s\n\nUser request:\n%s\n\nExplore the codebase first to understand the current architecture, then output your JSON plan.", architectSystemPrompt, prompt)
+	// Temporarily switch to architect model if configured
+	oldModel := a.config.Model
+	a.config.Model = model
 
-	output, err := a.runSubAgent(ctx, planPrompt, "explore")
+	explorePrompt := fmt.Sprintf("%s\n\nUser request:\n%s", architectExplorePrompt, prompt)
+	findings, err := a.runSubAgent(ctx, explorePrompt, "explore")
 
 	// Restore model
 	a.config.Model = oldModel
@@ -175,17 +171,22 @@ func (am *ArchitectMode) getPlan(ctx context.Context, prompt string) (*Architect
 		return nil, err
 	}
 
-	// Parse JSON from the output (may be wrapped in markdown code blocks)
-	jsonStr := extractJSON(output)
-	if jsonStr == "" {
-		return nil, fmt.Errorf("architect did not produce valid JSON plan")
+	req := &provider.ChatRequest{
+		Model:        model,
+		SystemPrompt: architectSystemPrompt,
+		Messages: []provider.Message{{
+			Role: provider.RoleUser,
+			Content: []provider.Content{{
+				Type: provider.ContentTypeText,
+				Text: "User request:\n" + prompt + "\n\nCodebase findings:\n" + findings,
+			}},
+		}},
+		MaxTokens: a.config.MaxTokensFor(model),
 	}
-
 	var plan ArchitectPlan
-	if err := json.Unmarshal([]byte(jsonStr), &plan); err != nil {
-		return nil, fmt.Errorf("failed to parse architect plan: %w", err)
+	if err := provider.ChatJSON(ctx, a.provider, req, architectPlanSchema, &plan); err != nil {
+		return nil, fmt.Errorf("architect did not produce a valid plan: %w", err)
 	}
-
 	return &plan, nil
 }
 
@@ -200,6 +201,13 @@ func (am *ArchitectMode) formatPlan(plan *ArchitectPlan) string {
 		if len(step.Files) > 0 {
 			sb.WriteString(fmt.Sprintf("     Files: %s\n", strings.Join(step.Files, ", ")))
 		}
+		if len(step.DependsOn) > 0 {
+			after := make([]string, len(step.DependsOn))
+			for j, n := range step.DependsOn {
+				after[j] = strconv.Itoa(n)
+			}
+			sb.WriteString(fmt.Sprintf("     After: step %s\n", strings.Join(after, ", ")))
+		}
 		if step.Details != "" {
 			details := step.Details
 			if len(details) > 200 {
@@ -234,38 +242,166 @@ func (am *ArchitectMode) buildStepPrompt(step ArchitectStep) string {
 	return sb.String()
 }
 
-// extractJSON finds a JSON object in text, handling markdown code blocks.
-func extractJSON(text string) string {
-	// Try to find JSON in code blocks first
-	if idx := strings.Index(text, "```json"); idx >= 0 {
-		start := idx + 7
-		if end := strings.Index(text[start:], "```"); end >= 0 {
-			return strings.TrimSpace(text[start : start+end])
+// Step states while a plan executes.
+const (
+	stepPending = iota
+	stepRunning
+	stepDone
+	stepSkipped // delete step, left to the user
+	stepFailed
+	stepBlocked // a step it depends on failed
+)
+
+type stepResult struct {
+	index  int
+	output string
+	err    error
+}
+
+// executeSteps runs the plan's steps through run. A step starts once the
+// steps it depends on are done, with up to maxParallel steps at once. After
+// a failure no new step starts unless continueOnFail is set; steps that
+// depend on a failed step never run.
+func (am *ArchitectMode) executeSteps(ctx context.Context, plan *ArchitectPlan, run func(context.Context, ArchitectStep) (string, error)) {
+	a := am.agent
+	steps := plan.Steps
+	deps := stepDeps(steps)
+	state := make([]int, len(steps))
+	results := make(chan stepResult)
+	running, failed := 0, 0
+
+	progress := func() string {
+		var done, waiting int
+		for _, st := range state {
+			switch st {
+			case stepDone:
+				done++
+			case stepPending:
+				waiting++
+			}
+		}
+		return fmt.Sprintf("%d/%d done, %d running, %d waiting, %d failed", done, len(steps), running, waiting, failed)
+	}
+
+	for {
+		stopping := ctx.Err() != nil || (failed > 0 && !am.continueOnFail)
+		for i, step := range steps {
+			if stopping || running >= am.maxParallel {
+				break
+			}
+			if state[i] != stepPending {
+				continue
+			}
+			ready, blockedBy := true, -1
+			for _, d := range deps[i] {
+				switch state[d] {
+				case stepDone, stepSkipped:
+				case stepFailed, stepBlocked:
+					blockedBy = d
+				default:
+					ready = false
+				}
+			}
+			switch {
+			case blockedBy >= 0:
+				state[i] = stepBlocked
+				a.io.SystemMessage(fmt.Sprintf("Step %d/%d not run: it depends on step %d, which did not complete.", i+1, len(steps), blockedBy+1))
+			case !ready:
+			case step.Action == "delete":
+				state[i] = stepSkipped
+				a.io.SystemMessage(fmt.Sprintf("Step %d/%d: skipping delete step (requires manual confirmation).", i+1, len(steps)))
+			default:
+				state[i] = stepRunning
+				running++
+				a.io.SystemMessage(fmt.Sprintf("\n--- Step %d/%d: %s ---", i+1, len(steps), step.Description))
+				go func() {
+					output, err := run(ctx, step)
+					results <- stepResult{index: i, output: output, err: err}
+				}()
+			}
+		}
+		if running == 0 {
+			break
+		}
+
+		r := <-results
+		running--
+		if r.err != nil {
+			state[r.index] = stepFailed
+			failed++
+			a.io.Error(fmt.Sprintf("Step %d failed: %v", r.index+1, r.err))
+			continue
 		}
+		state[r.index] = stepDone
+		if r.output != "" {
+			summary := r.output
+			if len(summary) > 500 {
+				summary = summary[:500] + "..."
+			}
+			a.io.SystemMessage(fmt.Sprintf("Step %d result: %s", r.index+1, summary))
+		}
+		a.io.SystemMessage(fmt.Sprintf("Step %d/%d complete. Progress: %s", r.index+1, len(steps), progress()))
 	}
-	if idx := strings.Index(text, "```"); idx >= 0 {
-		start := idx + 3
-		// Skip language identifier on same line
-		if nl := strings.Index(text[start:], "\n"); nl >= 0 {
-			start += nl + 1
+
+	var notRun []string
+	for i, st := range state {
+		if st == stepPending || st == stepBlocked {
+			notRun = append(notRun, strconv.Itoa(i+1))
 		}
-		if end := strings.Index(text[start:], "```"); end >= 0 {
-			candidate := strings.TrimSpace(text[start : start+end])
-			if strings.HasPrefix(candidate, "{") {
-				return candidate
+	}
+	if failed == 0 && len(notRun) == 0 {
+		a.io.SystemMessage("\nArchitect mode complete. All steps executed.")
+		return
+	}
+	msg := "\nArchitect mode stopped: " + progress() + "."
+	if len(notRun) > 0 {
+		msg += " Not run: step " + strings.Join(notRun, ", ") + "."
+	}
+	a.io.SystemMessage(msg)
+}
+
+// stepDeps returns, for each step, the earlier steps it must wait for: the
+// ones it declares, plus every earlier step touching one of its files.
+// References to itself or to later steps are ignored, so the result has
+// no cycles.
+func stepDeps(steps []ArchitectStep) [][]int {
+	deps := make([][]int, len(steps))
+	for i, step := range steps {
+		seen := make(map[int]bool)
+		add := func(j int) {
+			if !seen[j] {
+				seen[j] = true
+				deps[i] = append(deps[i], j)
+			}
+		}
+		for _, n := range step.DependsOn {
+			if n >= 1 && n <= i {
+				add(n - 1)
+			}
+		}
+		for j := 0; j < i; j++ {
+			if stepsOverlap(steps[j], step) {
+				add(j)
 			}
 		}
+		sort.Ints(deps[i])
 	}
+	return deps
+}
 
-	// Try to find raw JSON object
-	start := strings.Index(text, "{")
-	if start < 0 {
-		return ""
+// stepsOverlap reports whether two steps may touch the same files. "run"
+// steps and steps without files may touch anything.
+func stepsOverlap(a, b ArchitectStep) bool {
+	anything := func(s ArchitectStep) bool { return s.Action == "run" || len(s.Files) == 0 }
+	if anything(a) || anything(b) {
+		return true
 	}
-	// Find matching closing brace (simple approach: find last })
-	end := strings.LastIndex(text, "}")
-	if end <= start {
-		return ""
+	for _, fa := range a.Files {
+		for _, fb := range b.Files {
+			if filepath.Clean(fa) == filepath.Clean(fb) {
+				return true
+			}
+		}
 	}
-	return text[start : end+1]
+	return false
 }
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apexion-ai/apexion/internal/config"
+)
+
+func TestStepDeps(t *testing.T) {
+	steps := []ArchitectStep{
+		{Action: "modify", Files: []string{"a.go"}},
+		{Action: "modify", Files: []string{"b.go"}},
+		{Action: "modify", Files: []string{"./a.go"}},
+		{Action: "create", Files: []string{"c.go"}, DependsOn: []int{2, 4, 9}},
+		{Action: "run", Details: "go test ./..."},
+		{Action: "modify", Files: []string{"d.go"}},
+	}
+	want := [][]int{
+		nil,
+		nil,
+		{0},          // same file as step 1
+		{1},          // declared; self and out-of-range references are dropped
+		{0, 1, 2, 3}, // run steps wait for everything before them
+		{4},          // and everything after waits for them
+	}
+	if got := stepDeps(steps); !reflect.DeepEqual(got, want) {
+		t.Errorf("stepDeps = %v, want %v", got, want)
+	}
+}
+
+func newTestArchitect(t *testing.T, arch config.ArchitectConfig) (*ArchitectMode, *scenarioIO) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Architect = arch
+	ui := &scenarioIO{}
+	return NewArchitectMode(&Agent{io: ui, config: cfg}, "", "", true), ui
+}
+
+func TestArchitectExecuteSteps_Parallel(t *testing.T) {
+	am, ui := newTestArchitect(t, config.ArchitectConfig{})
+	plan := &ArchitectPlan{Steps: []ArchitectStep{
+		{Description: "one", Action: "modify", Files: []string{"a.go"}},
+		{Description: "two", Action: "modify", Files: []string{"b.go"}},
+		{Description: "three", Action: "modify", Files: []string{"c.go"}, DependsOn: []int{1, 2}},
+	}}
+
+	var mu sync.Mutex
+	var events []string
+	var started sync.WaitGroup
+	started.Add(2)
+	am.executeSteps(t.Context(), plan, func(_ context.Context, step ArchitectStep) (string, error) {
+		if step.Description != "three" {
+			// Steps one and two only return once both have started.
+			started.Done()
+			waitGroup(t, &started)
+		}
+		mu.Lock()
+		events = append(events, step.Description)
+		mu.Unlock()
+		return "", nil
+	})
+
+	if len(events) != 3 || events[2] != "three" {
+		t.Errorf("dependent step did not run last: %v", events)
+	}
+	if all := strings.Join(ui.system, "\n"); !strings.Contains(all, "All steps executed") {
+		t.Errorf("missing completion message; got:\n%s", all)
+	}
+}
+
+func waitGroup(t *testing.T, wg *sync.WaitGroup) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("independent steps did not run in parallel")
+	}
+}
+
+func TestArchitectExecuteSteps_Failure(t *testing.T) {
+	plan := &ArchitectPlan{Steps: []ArchitectStep{
+		{Description: "one", Action: "modify", Files: []string{"a.go"}},
+		{Description: "two", Action: "modify", Files: []string{"b.go"}},
+		{Description: "three", Action: "modify", Files: []string{"c.go"}, DependsOn: []int{1}},
+	}}
+	run := func(ran *[]string) func(context.Context, ArchitectStep) (string, error) {
+		return func(_ context.Context, step ArchitectStep) (string, error) {
+			*ran = append(*ran, step.Description)
+			if step.Description == "one" {
+				return "", errors.New("compile error")
+			}
+			return "", nil
+		}
+	}
+
+	tests := []struct {
+		continueOnFail bool
+		wantRan        []string
+		wantMessage    string
+	}{
+		{false, []string{"one"}, "Not run: step 2, 3."},
+		{true, []string{"one", "two"}, "Step 3/3 not run: it depends on step 1"},
+	}
+	for _, tt := range tests {
+		// One step at a time keeps the order deterministic.
+		am, ui := newTestArchitect(t, config.ArchitectConfig{MaxParallel: 1, ContinueOnFailure: tt.continueOnFail})
+		var ran []string
+		am.executeSteps(t.Context(), plan, run(&ran))
+		if !reflect.DeepEqual(ran, tt.wantRan) {
+			t.Errorf("continue=%v: ran %v, want %v", tt.continueOnFail, ran, tt.wantRan)
+		}
+		all := strings.Join(ui.system, "\n")
+		if !strings.Contains(all, tt.wantMessage) || !strings.Contains(all, "Step 1 failed: compile error") {
+			t.Errorf("continue=%v: missing messages; got:\n%s", tt.continueOnFail, all)
+		}
+		if !strings.Contains(all, "1 failed.") {
+			t.Errorf("continue=%v: progress does not count the failure; got:\n%s", tt.continueOnFail, all)
+		}
+	}
+}
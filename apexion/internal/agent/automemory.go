@@ -2,7 +2,6 @@ package agent
 
 import (
 	"context"
-	"encoding/json"
 	"strings"
 
 	"github.com/apexion-ai/apexion/internal/provider"
@@ -15,9 +14,35 @@ Focus on:
 - Project patterns (architecture decisions, file conventions)
 - Important corrections the user made
 
-Output a JSON array: [{"content": "...", "tags": ["preference"|"project"|"correction"]}]
-Return empty array [] if nothing worth remembering.
-Only output the JSON array, nothing else.`
+Tag each fact as "preference", "project" or "correction".
+Return an empty list if nothing is worth remembering.`
+
+// autoMemorySchema is the response schema for extracted memories.
+var autoMemorySchema = &provider.ResponseSchema{
+	Name:        "memories",
+	Description: "facts worth remembering across sessions",
+	Schema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"memories": map[string]any{
+				"type":     "array",
+				"maxItems": 3,
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"content": map[string]any{"type": "string"},
+						"tags": map[string]any{
+							"type":  "array",
+							"items": map[string]any{"type": "string", "enum": []string{"preference", "project", "correction"}},
+						},
+					},
+					"required": []string{"content", "tags"},
+				},
+			},
+		},
+		"required": []string{"memories"},
+	},
+}
 
 // AutoMemoryExtractor analyzes conversation history and extracts
 // valuable information to persist as cross-session memories.
@@ -66,44 +91,24 @@ func (ame *AutoMemoryExtractor) Extract(ctx context.Context, messages []provider
 				}},
 			},
 		},
-		SystemPrompt: "You extract factual information from conversations. Output only valid JSON.",
+		SystemPrompt: "You extract factual information from conversations.",
 		MaxTokens:    1024,
 	}
 
-	events, err := ame.provider.Chat(ctx, req)
-	if err != nil {
-		return 0, err
-	}
-
-	var response strings.Builder
-	for evt := range events {
-		if evt.Type == provider.EventTextDelta {
-			response.WriteString(evt.TextDelta)
-		}
+	var result struct {
+		Memories []struct {
+			Content string   `json:"content"`
+			Tags    []string `json:"tags"`
+		} `json:"memories"`
 	}
-
-	// Parse the JSON response.
-	type memoryEntry struct {
-		Content string   `json:"content"`
-		Tags    []string `json:"tags"`
-	}
-
-	var entries []memoryEntry
-	raw := strings.TrimSpace(response.String())
-	// Try to extract JSON array even if surrounded by markdown code fences.
-	if idx := strings.Index(raw, "["); idx >= 0 {
-		if end := strings.LastIndex(raw, "]"); end > idx {
-			raw = raw[idx : end+1]
-		}
-	}
-	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
-		return 0, nil // parsing failed, skip silently
+	if err := provider.ChatJSON(ctx, ame.provider, req, autoMemorySchema, &result); err != nil {
+		return 0, err
 	}
 
 	// Store each extracted memory, deduplicating against existing memories.
 	existing, _ := ame.store.List(50)
 	added := 0
-	for _, entry := range entries {
+	for _, entry := range result.Memories {
 		if entry.Content == "" {
 			continue
 		}
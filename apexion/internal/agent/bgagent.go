@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/apexion-ai/apexion/internal/provider"
 	"github.com/apexion-ai/apexion/internal/tools"
 	"github.com/apexion-ai/apexion/internal/tui"
 )
@@ -75,7 +76,10 @@ func (bm *BackgroundManager) Launch(ctx context.Context, prompt, mode string, ru
 	bm.counter++
 	id := fmt.Sprintf("bg-%d", bm.counter)
 
-	bgCtx, cancel := context.WithCancel(ctx)
+	// Background agents queue for the provider separately from the
+	// foreground loop so neither can starve the other.
+	bgCtx, cancel := context.WithCancel(provider.WithLane(ctx, provider.LaneBackground))
+	bgCtx = context.WithValue(bgCtx, bgIDKey{}, id)
 	agent := &BackgroundAgent{
 		ID:        id,
 		Prompt:    prompt,
@@ -0,0 +1,241 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apexion-ai/apexion/internal/config"
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/session"
+	"github.com/apexion-ai/apexion/internal/tools"
+)
+
+// BudgetError reports that the next provider call would cross a budget.
+// apexion run exits with it; the chat loop asks the user first.
+type BudgetError struct {
+	Scope    string  `json:"scope"`  // "turn", "session" or "daily"
+	Metric   string  `json:"metric"` // "cost" or "tokens"
+	Limit    float64 `json:"limit"`
+	Spent    float64 `json:"spent"`
+	Estimate float64 `json:"next_call_estimate"`
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("%s budget reached: spent %s of %s, next call ~%s",
+		e.Scope, e.format(e.Spent), e.format(e.Limit), e.format(e.Estimate))
+}
+
+func (e *BudgetError) format(v float64) string {
+	if e.Metric == "cost" {
+		return fmt.Sprintf("$%.2f", v)
+	}
+	return fmt.Sprintf("%d tokens", int(v))
+}
+
+// Budget enforces config.BudgetConfig and records every provider call in a
+// SpendStore. An agent shares its Budget with the sub-agents and background
+// agents it starts, so their spend counts toward the same limits.
+type Budget struct {
+	cfg   config.BudgetConfig
+	store session.SpendStore
+
+	mu        sync.Mutex
+	sessionID string
+	turn      session.Spend
+	session   session.Spend
+	day       session.Spend
+	dayStart  time.Time
+	approved  map[string]bool // "scope/metric" the user let through this turn
+}
+
+// NewBudget creates a Budget. A nil store keeps totals in memory only.
+func NewBudget(cfg config.BudgetConfig, store session.SpendStore) *Budget {
+	if store == nil {
+		store = session.NullSpendStore{}
+	}
+	return &Budget{cfg: cfg, store: store, approved: make(map[string]bool)}
+}
+
+// StartTurn resets the turn total and any approvals. When sessionID changes
+// (first turn, /resume) the spend already recorded for it is loaded.
+func (b *Budget) StartTurn(sessionID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.turn = session.Spend{}
+	clear(b.approved)
+	if sessionID != b.sessionID {
+		b.sessionID = sessionID
+		// A store error leaves the total at zero rather than blocking work.
+		b.session, _ = b.store.Total(sessionID, time.Time{})
+	}
+	b.rollDay()
+}
+
+// rollDay reloads the daily total when the local calendar day changes.
+// Must be called with b.mu held.
+func (b *Budget) rollDay() {
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if start.Equal(b.dayStart) {
+		return
+	}
+	b.dayStart = start
+	b.day, _ = b.store.Total("", start)
+}
+
+// Add records the spend of one provider call.
+func (b *Budget) Add(model string, s session.Spend) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollDay()
+	b.turn = b.turn.Add(s)
+	b.session = b.session.Add(s)
+	b.day = b.day.Add(s)
+	_ = b.store.Record(b.sessionID, model, s)
+}
+
+// Check returns the first budget that is used up, or that next would cross,
+// skipping limits approved for this turn. Scopes are checked narrowest first.
+func (b *Budget) Check(next session.Spend) *BudgetError {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollDay()
+	for _, sc := range b.scopes() {
+		if e := b.check(sc.name, "cost", sc.limit.MaxCost, sc.spent.Cost, next.Cost); e != nil {
+			return e
+		}
+		if e := b.check(sc.name, "tokens", float64(sc.limit.MaxTokens), float64(sc.spent.Tokens), float64(next.Tokens)); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func (b *Budget) check(scope, metric string, limit, spent, next float64) *BudgetError {
+	if limit <= 0 || b.approved[scope+"/"+metric] {
+		return nil
+	}
+	if spent < limit && spent+next <= limit {
+		return nil
+	}
+	return &BudgetError{Scope: scope, Metric: metric, Limit: limit, Spent: spent, Estimate: next}
+}
+
+// Approve lets calls go over the given limit for the rest of the turn.
+func (b *Budget) Approve(scope, metric string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.approved[scope+"/"+metric] = true
+}
+
+type budgetScope struct {
+	name  string
+	limit config.BudgetLimit
+	spent session.Spend
+}
+
+// scopes must be called with b.mu held.
+func (b *Budget) scopes() []budgetScope {
+	return []budgetScope{
+		{"turn", b.cfg.Turn, b.turn},
+		{"session", b.cfg.Session, b.session},
+		{"daily", b.cfg.Daily, b.day},
+	}
+}
+
+// Summary describes spend against each limit, for /cost.
+func (b *Budget) Summary() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollDay()
+	var sb strings.Builder
+	sb.WriteString("Budget:")
+	for _, sc := range b.scopes() {
+		cost := fmt.Sprintf("$%.4f", sc.spent.Cost)
+		if sc.limit.MaxCost > 0 {
+			cost += fmt.Sprintf(" / $%.2f", sc.limit.MaxCost)
+		}
+		tokens := fmt.Sprintf("%d", sc.spent.Tokens)
+		if sc.limit.MaxTokens > 0 {
+			tokens += fmt.Sprintf(" / %d", sc.limit.MaxTokens)
+		}
+		sb.WriteString(fmt.Sprintf("\n  %-8s %s, %s tokens", sc.name, cost, tokens))
+	}
+	return sb.String()
+}
+
+// spendModel returns the model the next or last call is billed as.
+func (a *Agent) spendModel() string {
+	model := a.config.Model
+	if model == "" {
+		model = a.provider.DefaultModel()
+	}
+	if fp, ok := a.provider.(*provider.FallbackProvider); ok && fp.ActiveModel() != "" {
+		model = fp.ActiveModel()
+	}
+	return model
+}
+
+// recordSpend prices one call's usage and adds it to the session cost and
+// the budget.
+func (a *Agent) recordSpend(model string, usage provider.Usage) {
+	var cost float64
+	if a.costTracker != nil {
+		cost = a.costTracker.RecordTurn(model, usage)
+		a.io.SetCost(a.costTracker.SessionCost())
+	}
+	if a.budget != nil {
+		a.budget.Add(model, session.Spend{Cost: cost, Tokens: usage.PromptTokens() + usage.OutputTokens})
+	}
+}
+
+// enforceBudget runs before each provider call, with the previous call's
+// usage as the estimate for the next one. It returns nil to go ahead. In
+// the chat loop the user may allow going over; that holds for the rest of
+// the turn.
+func (a *Agent) enforceBudget(model string, last provider.Usage) *BudgetError {
+	if a.budget == nil {
+		return nil
+	}
+	next := session.Spend{Tokens: last.PromptTokens() + last.OutputTokens}
+	if a.costTracker != nil {
+		next.Cost = a.costTracker.EstimateCost(model, last)
+	}
+	for {
+		be := a.budget.Check(next)
+		if be == nil {
+			return nil
+		}
+		approved := a.interactive && a.askBudgetOverride(be)
+		if a.eventLogger != nil {
+			action := "stop"
+			if approved {
+				action = "continue"
+			}
+			a.eventLogger.Log(EventBudget, map[string]any{
+				"scope":              be.Scope,
+				"metric":             be.Metric,
+				"limit":              be.Limit,
+				"spent":              be.Spent,
+				"next_call_estimate": be.Estimate,
+				"action":             action,
+			})
+		}
+		if !approved {
+			return be
+		}
+		a.budget.Approve(be.Scope, be.Metric)
+	}
+}
+
+// askBudgetOverride asks whether to keep going past be for this turn.
+func (a *Agent) askBudgetOverride(be *BudgetError) bool {
+	if q, ok := a.io.(tools.Questioner); ok {
+		answer, err := q.AskQuestion("Budget: "+be.Error()+". Continue this turn?",
+			[]string{"Stop here", "Continue for the rest of this turn"})
+		return err == nil && strings.HasPrefix(answer, "Continue")
+	}
+	return a.io.Confirm("budget", be.Error()+". Continue for the rest of this turn?", tools.PermissionWrite)
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apexion-ai/apexion/internal/config"
+	"github.com/apexion-ai/apexion/internal/permission"
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/session"
+	"github.com/apexion-ai/apexion/internal/tools"
+)
+
+// fixedSpendStore reports preset totals and records nothing.
+type fixedSpendStore struct{ session, daily session.Spend }
+
+func (f fixedSpendStore) Record(string, string, session.Spend) error { return nil }
+func (f fixedSpendStore) Total(id string, _ time.Time) (session.Spend, error) {
+	if id == "" {
+		return f.daily, nil
+	}
+	return f.session, nil
+}
+
+func TestBudget_Check(t *testing.T) {
+	cfg := config.BudgetConfig{
+		Turn:    config.BudgetLimit{MaxTokens: 1000},
+		Session: config.BudgetLimit{MaxCost: 2},
+		Daily:   config.BudgetLimit{MaxCost: 5},
+	}
+	tests := []struct {
+		name       string
+		store      fixedSpendStore
+		turnTokens int
+		next       session.Spend
+		wantScope  string
+		wantMetric string
+	}{
+		{name: "under all limits", next: session.Spend{Cost: 0.1, Tokens: 100}},
+		{name: "next call crosses turn tokens", turnTokens: 800, next: session.Spend{Tokens: 300}, wantScope: "turn", wantMetric: "tokens"},
+		{name: "turn tokens used up", turnTokens: 1000, wantScope: "turn", wantMetric: "tokens"},
+		{name: "resumed session over cost", store: fixedSpendStore{session: session.Spend{Cost: 1.95}}, next: session.Spend{Cost: 0.1}, wantScope: "session", wantMetric: "cost"},
+		{name: "earlier sessions today", store: fixedSpendStore{daily: session.Spend{Cost: 5}}, wantScope: "daily", wantMetric: "cost"},
+	}
+	for _, tt := range tests {
+		b := NewBudget(cfg, tt.store)
+		b.StartTurn("sess-1")
+		b.Add("gpt-4o", session.Spend{Tokens: tt.turnTokens})
+		be := b.Check(tt.next)
+		switch {
+		case tt.wantScope == "" && be != nil:
+			t.Errorf("%s: unexpected %v", tt.name, be)
+		case tt.wantScope != "" && (be == nil || be.Scope != tt.wantScope || be.Metric != tt.wantMetric):
+			t.Errorf("%s: got %v, want %s/%s", tt.name, be, tt.wantScope, tt.wantMetric)
+		}
+	}
+}
+
+func TestBudget_ApprovalLastsOneTurn(t *testing.T) {
+	b := NewBudget(config.BudgetConfig{Turn: config.BudgetLimit{MaxTokens: 100}}, nil)
+	b.StartTurn("sess-1")
+	b.Add("m", session.Spend{Tokens: 150})
+	if b.Check(session.Spend{}) == nil {
+		t.Fatal("expected the turn budget to be reached")
+	}
+	b.Approve("turn", "tokens")
+	if be := b.Check(session.Spend{}); be != nil {
+		t.Errorf("approved limit still enforced: %v", be)
+	}
+	b.StartTurn("sess-1")
+	b.Add("m", session.Spend{Tokens: 150})
+	if b.Check(session.Spend{}) == nil {
+		t.Error("approval should not carry over to the next turn")
+	}
+}
+
+const budgetScript = `
+turns:
+  - tool_calls:
+      - name: glob
+        input: {pattern: "*.go"}
+    usage: {input: 900, output: 200}
+  - text: "never requested"
+`
+
+func TestRunOnce_StopsAtBudget(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	p, err := provider.ParseScript([]byte(budgetScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Provider = "scripted"
+	cfg.RepoMap.Disabled = true
+	cfg.Budget.Turn.MaxTokens = 2000
+	executor := tools.NewExecutor(tools.DefaultRegistry(nil, nil), permission.AllowAllPolicy{})
+	a := New(p, executor, cfg, &scenarioIO{}, session.NullStore{})
+
+	// 1100 tokens spent; the next call is estimated at another 1100.
+	err = a.RunOnce(t.Context(), "list the go files")
+	var be *BudgetError
+	if !errors.As(err, &be) || be.Scope != "turn" || be.Metric != "tokens" || be.Spent != 1100 {
+		t.Fatalf("expected a turn token budget error, got %v", err)
+	}
+	if p.Remaining() != 1 {
+		t.Errorf("the call over budget should not be sent; %d turn(s) left", p.Remaining())
+	}
+}
+
+func TestSubAgentSpendCountsTowardParent(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	p, err := provider.ParseScript([]byte(`
+turns:
+  - text: "nothing here"
+    usage: {input: 400, output: 100}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Provider = "scripted"
+	cfg.RepoMap.Disabled = true
+	executor := tools.NewExecutor(tools.DefaultRegistry(nil, nil), permission.AllowAllPolicy{})
+	a := New(p, executor, cfg, &scenarioIO{}, session.NullStore{})
+	a.budget.StartTurn(a.session.ID)
+
+	if _, err := a.runSubAgent(t.Context(), "look around", "explore"); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.budget.turn.Tokens; got != 500 {
+		t.Errorf("parent turn tokens = %d, want 500", got)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/apexion-ai/apexion/internal/config"
+	"github.com/apexion-ai/apexion/internal/provider"
 )
 
 func TestSplitFrontmatter(t *testing.T) {
@@ -348,3 +351,19 @@ func TestCommandDirs_DifferentRoots(t *testing.T) {
 		t.Errorf("expected at least 2 .apexion/commands dirs when cwd!=gitRoot, got %d: %v", found, dirs)
 	}
 }
+
+func TestHandleModel_WrappedProviderAcceptsUnlistedModel(t *testing.T) {
+	inner := provider.NewOpenAIProvider("key", "https://api.deepseek.com/v1", "deepseek-chat", nil)
+	wrapped := []provider.Provider{
+		provider.NewRateLimitedProvider(inner, provider.NewGovernor(provider.RateLimit{})),
+		provider.NewFallbackProvider(provider.FallbackTarget{Provider: inner}),
+	}
+	for _, p := range wrapped {
+		io := &scenarioIO{}
+		a := &Agent{provider: p, config: config.DefaultConfig(), io: io}
+		a.handleModel("deepseek-reasoner")
+		if a.config.Model != "deepseek-reasoner" {
+			t.Errorf("%T: model = %q, want deepseek-reasoner; messages: %v", p, a.config.Model, io.system)
+		}
+	}
+}
@@ -62,7 +62,7 @@ func loadProjectContext(cwd string) string {
 // findGitRoot runs `git rev-parse --show-toplevel` to find the repository root.
 // Returns empty string if not inside a git repository.
 func findGitRoot(cwd string) string {
-	cmd := exec.Command(gitExecutable(), "rev-parse", "--show-toplevel")
+	cmd := exec.Command(GitExecutable(), "rev-parse", "--show-toplevel")
 	cmd.Dir = cwd
 	out, err := cmd.Output()
 	if err != nil {
@@ -71,8 +71,8 @@ func findGitRoot(cwd string) string {
 	return strings.TrimSpace(string(out))
 }
 
-// gitExecutable returns the git binary path, checking common locations.
-func gitExecutable() string {
+// GitExecutable returns the git binary path, checking common locations.
+func GitExecutable() string {
 	if p, err := exec.LookPath("git"); err == nil {
 		return p
 	}
@@ -5,21 +5,28 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/apexion-ai/apexion/internal/provider"
 )
 
 // ModelPricing holds per-million-token pricing for a model.
+// Prompt-cache rates of zero fall back to InputPerMillion.
 type ModelPricing struct {
-	InputPerMillion  float64
-	OutputPerMillion float64
+	InputPerMillion      float64
+	OutputPerMillion     float64
+	CacheWritePerMillion float64
+	CacheReadPerMillion  float64
 }
 
 // TurnCost records cost data for a single LLM turn.
 type TurnCost struct {
-	InputTokens  int
-	OutputTokens int
-	Cost         float64
-	Model        string
-	Timestamp    time.Time
+	InputTokens         int
+	OutputTokens        int
+	CacheCreationTokens int
+	CacheReadTokens     int
+	Cost                float64
+	Model               string
+	Timestamp           time.Time
 }
 
 // CostTracker accumulates token usage and dollar cost across turns.
@@ -42,47 +49,56 @@ func NewCostTracker(overrides map[string]ModelPricing) *CostTracker {
 // DefaultPricing returns built-in pricing for well-known models.
 func DefaultPricing() map[string]ModelPricing {
 	return map[string]ModelPricing{
-		// Anthropic
-		"claude-sonnet-4-20250514":  {3.0, 15.0},
-		"claude-opus-4-20250514":    {15.0, 75.0},
-		"claude-haiku-4-5-20251001": {0.80, 4.0},
-		// OpenAI
-		"gpt-4o":        {2.50, 10.0},
-		"gpt-4o-mini":   {0.15, 0.60},
-		"gpt-4.1":       {2.0, 8.0},
-		"gpt-4.1-mini":  {0.40, 1.60},
-		"gpt-4.1-nano":  {0.10, 0.40},
-		"o3":            {2.0, 8.0},
-		"o3-mini":       {1.10, 4.40},
-		"o4-mini":       {1.10, 4.40},
+		// Anthropic: cache writes cost 1.25x input, reads 0.1x.
+		"claude-sonnet-4-20250514":  {3.0, 15.0, 3.75, 0.30},
+		"claude-opus-4-20250514":    {15.0, 75.0, 18.75, 1.50},
+		"claude-haiku-4-5-20251001": {0.80, 4.0, 1.0, 0.08},
+		// OpenAI: caching is automatic, writes are not surcharged.
+		"gpt-4o":       {2.50, 10.0, 0, 1.25},
+		"gpt-4o-mini":  {0.15, 0.60, 0, 0.075},
+		"gpt-4.1":      {2.0, 8.0, 0, 0.50},
+		"gpt-4.1-mini": {0.40, 1.60, 0, 0.10},
+		"gpt-4.1-nano": {0.10, 0.40, 0, 0.025},
+		"o3":           {2.0, 8.0, 0, 0.50},
+		"o3-mini":      {1.10, 4.40, 0, 0.55},
+		"o4-mini":      {1.10, 4.40, 0, 0.275},
 		// DeepSeek
-		"deepseek-chat":     {0.27, 1.10},
-		"deepseek-reasoner": {0.55, 2.19},
+		"deepseek-chat":     {0.27, 1.10, 0, 0.07},
+		"deepseek-reasoner": {0.55, 2.19, 0, 0.14},
 		// Google
-		"gemini-2.5-pro":   {1.25, 10.0},
-		"gemini-2.5-flash": {0.15, 0.60},
+		"gemini-2.5-pro":   {1.25, 10.0, 0, 0.31},
+		"gemini-2.5-flash": {0.15, 0.60, 0, 0.0375},
 		// Mistral
-		"codestral-latest": {0.30, 0.90},
+		"codestral-latest": {0.30, 0.90, 0, 0},
 	}
 }
 
 // RecordTurn records token usage for a single LLM turn and returns the turn cost.
-func (ct *CostTracker) RecordTurn(model string, inputTokens, outputTokens int) float64 {
+func (ct *CostTracker) RecordTurn(model string, usage provider.Usage) float64 {
 	ct.mu.Lock()
 	defer ct.mu.Unlock()
 
-	cost := ct.calculateCost(model, inputTokens, outputTokens)
+	cost := ct.calculateCost(model, usage)
 	ct.sessionCost += cost
 	ct.turns = append(ct.turns, TurnCost{
-		InputTokens:  inputTokens,
-		OutputTokens: outputTokens,
-		Cost:         cost,
-		Model:        model,
-		Timestamp:    time.Now(),
+		InputTokens:         usage.InputTokens,
+		OutputTokens:        usage.OutputTokens,
+		CacheCreationTokens: usage.CacheCreationTokens,
+		CacheReadTokens:     usage.CacheReadTokens,
+		Cost:                cost,
+		Model:               model,
+		Timestamp:           time.Now(),
 	})
 	return cost
 }
 
+// EstimateCost prices usage for model without recording it.
+func (ct *CostTracker) EstimateCost(model string, usage provider.Usage) float64 {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	return ct.calculateCost(model, usage)
+}
+
 // SessionCost returns the total session cost in dollars.
 func (ct *CostTracker) SessionCost() float64 {
 	ct.mu.Lock()
@@ -102,19 +118,51 @@ func (ct *CostTracker) Summary() string {
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("Session cost: $%.4f (%d turns)\n\n", ct.sessionCost, len(ct.turns)))
 
-	totalIn, totalOut := 0, 0
+	totalIn, totalOut, totalWrite, totalRead := 0, 0, 0, 0
 	for i, t := range ct.turns {
 		totalIn += t.InputTokens
 		totalOut += t.OutputTokens
-		sb.WriteString(fmt.Sprintf("  Turn %d: %s  in=%d out=%d  $%.4f\n",
-			i+1, t.Model, t.InputTokens, t.OutputTokens, t.Cost))
+		totalWrite += t.CacheCreationTokens
+		totalRead += t.CacheReadTokens
+		cache := ""
+		if t.CacheCreationTokens > 0 || t.CacheReadTokens > 0 {
+			cache = fmt.Sprintf(" cache_write=%d cache_read=%d", t.CacheCreationTokens, t.CacheReadTokens)
+		}
+		sb.WriteString(fmt.Sprintf("  Turn %d: %s  in=%d out=%d%s  $%.4f\n",
+			i+1, t.Model, t.InputTokens, t.OutputTokens, cache, t.Cost))
 	}
+	prompt := totalIn + totalWrite + totalRead
 	sb.WriteString(fmt.Sprintf("\nTotal tokens: %d input + %d output = %d",
-		totalIn, totalOut, totalIn+totalOut))
+		prompt, totalOut, prompt+totalOut))
+	if totalWrite > 0 || totalRead > 0 {
+		sb.WriteString(fmt.Sprintf("\nPrompt cache: %d written, %d read, hit ratio %.1f%%",
+			totalWrite, totalRead, ct.cacheHitRatio()*100))
+	}
 
 	return sb.String()
 }
 
+// CacheHitRatio returns the fraction of session prompt tokens served from
+// the prompt cache (0 when nothing has been recorded).
+func (ct *CostTracker) CacheHitRatio() float64 {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	return ct.cacheHitRatio()
+}
+
+// cacheHitRatio must be called with lock held.
+func (ct *CostTracker) cacheHitRatio() float64 {
+	prompt, read := 0, 0
+	for _, t := range ct.turns {
+		prompt += t.InputTokens + t.CacheCreationTokens + t.CacheReadTokens
+		read += t.CacheReadTokens
+	}
+	if prompt == 0 {
+		return 0
+	}
+	return float64(read) / float64(prompt)
+}
+
 // FormatCost returns a compact cost string like "$0.12" for status bar display.
 func (ct *CostTracker) FormatCost() string {
 	ct.mu.Lock()
@@ -126,7 +174,7 @@ func (ct *CostTracker) FormatCost() string {
 }
 
 // calculateCost computes the dollar cost for a turn. Must be called with lock held.
-func (ct *CostTracker) calculateCost(model string, inputTokens, outputTokens int) float64 {
+func (ct *CostTracker) calculateCost(model string, usage provider.Usage) float64 {
 	p, ok := ct.pricing[model]
 	if !ok {
 		// Try prefix matching for versioned model names (e.g. "gpt-4o-2024-08-06")
@@ -141,6 +189,15 @@ func (ct *CostTracker) calculateCost(model string, inputTokens, outputTokens int
 	if !ok {
 		return 0 // unknown model, no pricing
 	}
-	return (float64(inputTokens) * p.InputPerMillion / 1_000_000) +
-		(float64(outputTokens) * p.OutputPerMillion / 1_000_000)
+	writeRate, readRate := p.CacheWritePerMillion, p.CacheReadPerMillion
+	if writeRate == 0 {
+		writeRate = p.InputPerMillion
+	}
+	if readRate == 0 {
+		readRate = p.InputPerMillion
+	}
+	return (float64(usage.InputTokens) * p.InputPerMillion / 1_000_000) +
+		(float64(usage.OutputTokens) * p.OutputPerMillion / 1_000_000) +
+		(float64(usage.CacheCreationTokens) * writeRate / 1_000_000) +
+		(float64(usage.CacheReadTokens) * readRate / 1_000_000)
 }
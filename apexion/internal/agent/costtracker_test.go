@@ -3,6 +3,8 @@ package agent
 import (
 	"strings"
 	"testing"
+
+	"github.com/apexion-ai/apexion/internal/provider"
 )
 
 func TestDefaultPricing(t *testing.T) {
@@ -25,7 +27,7 @@ func TestDefaultPricing(t *testing.T) {
 
 func TestRecordTurn(t *testing.T) {
 	ct := NewCostTracker(nil)
-	cost := ct.RecordTurn("deepseek-chat", 1000, 500)
+	cost := ct.RecordTurn("deepseek-chat", provider.Usage{InputTokens: 1000, OutputTokens: 500})
 	if cost <= 0 {
 		t.Fatal("expected positive cost for known model")
 	}
@@ -41,7 +43,7 @@ func TestRecordTurn(t *testing.T) {
 
 func TestRecordTurnUnknownModel(t *testing.T) {
 	ct := NewCostTracker(nil)
-	cost := ct.RecordTurn("unknown-model-xyz", 1000, 500)
+	cost := ct.RecordTurn("unknown-model-xyz", provider.Usage{InputTokens: 1000, OutputTokens: 500})
 	if cost != 0 {
 		t.Fatalf("expected 0 cost for unknown model, got %f", cost)
 	}
@@ -55,7 +57,7 @@ func TestRecordTurnWithOverrides(t *testing.T) {
 		"my-custom-model": {InputPerMillion: 10.0, OutputPerMillion: 20.0},
 	}
 	ct := NewCostTracker(overrides)
-	cost := ct.RecordTurn("my-custom-model", 1_000_000, 500_000)
+	cost := ct.RecordTurn("my-custom-model", provider.Usage{InputTokens: 1_000_000, OutputTokens: 500_000})
 	expected := 10.0 + (500_000.0 * 20.0 / 1_000_000)
 	if cost != expected {
 		t.Fatalf("cost %f != expected %f", cost, expected)
@@ -65,7 +67,7 @@ func TestRecordTurnWithOverrides(t *testing.T) {
 func TestRecordTurnPrefixMatch(t *testing.T) {
 	ct := NewCostTracker(nil)
 	// "gpt-4o-2024-08-06" should prefix-match "gpt-4o"
-	cost := ct.RecordTurn("gpt-4o-2024-08-06", 1000, 500)
+	cost := ct.RecordTurn("gpt-4o-2024-08-06", provider.Usage{InputTokens: 1000, OutputTokens: 500})
 	if cost <= 0 {
 		t.Fatal("expected positive cost via prefix match")
 	}
@@ -78,14 +80,14 @@ func TestFormatCost(t *testing.T) {
 		t.Fatalf("expected $0.0000, got %s", got)
 	}
 	// Small cost (<$0.01) — 4 decimal places
-	ct.RecordTurn("deepseek-chat", 1000, 500)
+	ct.RecordTurn("deepseek-chat", provider.Usage{InputTokens: 1000, OutputTokens: 500})
 	fc := ct.FormatCost()
 	if !strings.HasPrefix(fc, "$0.") {
 		t.Fatalf("expected small cost format, got %s", fc)
 	}
 	// Large cost (>=$0.01) — 2 decimal places
 	ct2 := NewCostTracker(nil)
-	ct2.RecordTurn("claude-opus-4-20250514", 1_000_000, 100_000)
+	ct2.RecordTurn("claude-opus-4-20250514", provider.Usage{InputTokens: 1_000_000, OutputTokens: 100_000})
 	fc2 := ct2.FormatCost()
 	if !strings.HasPrefix(fc2, "$") {
 		t.Fatalf("expected dollar prefix, got %s", fc2)
@@ -104,8 +106,8 @@ func TestSummaryEmpty(t *testing.T) {
 
 func TestSummary(t *testing.T) {
 	ct := NewCostTracker(nil)
-	ct.RecordTurn("deepseek-chat", 1000, 500)
-	ct.RecordTurn("gpt-4o", 2000, 1000)
+	ct.RecordTurn("deepseek-chat", provider.Usage{InputTokens: 1000, OutputTokens: 500})
+	ct.RecordTurn("gpt-4o", provider.Usage{InputTokens: 2000, OutputTokens: 1000})
 
 	s := ct.Summary()
 	if !strings.Contains(s, "Session cost:") {
@@ -127,3 +129,47 @@ func TestSummary(t *testing.T) {
 		t.Fatal("summary should show total input tokens")
 	}
 }
+
+func TestRecordTurnCachePricing(t *testing.T) {
+	ct := NewCostTracker(nil)
+	cost := ct.RecordTurn("claude-sonnet-4-20250514", provider.Usage{
+		InputTokens:         1_000,
+		OutputTokens:        1_000,
+		CacheCreationTokens: 1_000_000,
+		CacheReadTokens:     1_000_000,
+	})
+	// input 3.0/M, output 15.0/M, cache write 3.75/M, cache read 0.30/M
+	expected := (1_000.0*3.0+1_000.0*15.0)/1_000_000 + 3.75 + 0.30
+	if diff := cost - expected; diff > 1e-9 || diff < -1e-9 {
+		t.Fatalf("cost %f != expected %f", cost, expected)
+	}
+}
+
+func TestRecordTurnCacheRatesDefaultToInput(t *testing.T) {
+	overrides := map[string]ModelPricing{
+		"my-custom-model": {InputPerMillion: 10.0, OutputPerMillion: 20.0},
+	}
+	ct := NewCostTracker(overrides)
+	cost := ct.RecordTurn("my-custom-model", provider.Usage{CacheCreationTokens: 500_000, CacheReadTokens: 500_000})
+	if cost != 10.0 {
+		t.Fatalf("expected cache tokens billed at input rate (10.0), got %f", cost)
+	}
+}
+
+func TestCacheHitRatio(t *testing.T) {
+	ct := NewCostTracker(nil)
+	if got := ct.CacheHitRatio(); got != 0 {
+		t.Fatalf("expected 0 ratio with no turns, got %f", got)
+	}
+	ct.RecordTurn("claude-sonnet-4-20250514", provider.Usage{InputTokens: 100, CacheCreationTokens: 900, OutputTokens: 50})
+	ct.RecordTurn("claude-sonnet-4-20250514", provider.Usage{InputTokens: 100, CacheReadTokens: 900, OutputTokens: 50})
+	if got := ct.CacheHitRatio(); got != 0.45 {
+		t.Fatalf("expected hit ratio 0.45, got %f", got)
+	}
+	s := ct.Summary()
+	for _, want := range []string{"cache_write=900 cache_read=0", "Prompt cache: 900 written, 900 read, hit ratio 45.0%", "Total tokens: 2000 input + 100 output"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("summary missing %q:\n%s", want, s)
+		}
+	}
+}
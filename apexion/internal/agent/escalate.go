@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/apexion-ai/apexion/internal/config"
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/tui"
+)
+
+// escalation tracks the model switches of one turn. The zero value means
+// the turn still runs on the model it started with.
+type escalation struct {
+	active     bool
+	rung       int // ladder index of the active model, valid when active
+	toolErrors int // consecutive failed tool calls
+
+	// The model the turn started with, restored when it ends.
+	provider     provider.Provider
+	providerName string
+	model        string
+}
+
+// trackToolErrors counts consecutive failed tool calls and reports whether
+// the configured limit was reached.
+func (a *Agent) trackToolErrors(es *escalation, results []provider.Content) bool {
+	for _, c := range results {
+		if c.Type != provider.ContentTypeToolResult {
+			continue
+		}
+		if c.IsError {
+			es.toolErrors++
+		} else {
+			es.toolErrors = 0
+		}
+	}
+	limit := a.config.Escalation.ToolErrors
+	if limit <= 0 {
+		limit = 3
+	}
+	return es.toolErrors >= limit
+}
+
+// escalate switches to the next model of the escalation ladder for the
+// rest of the turn. It returns false if there is no stronger model to
+// switch to.
+func (a *Agent) escalate(es *escalation, reason string) bool {
+	ladder := a.config.Escalation.Ladder
+	if a.subAgent || len(ladder) == 0 {
+		return false
+	}
+	from := a.activeModel()
+	next := a.ladderIndex() + 1
+	if es.active {
+		next = es.rung + 1
+	}
+	for ; next < len(ladder); next++ {
+		rung := ladder[next]
+		p, err := a.rungProvider(rung)
+		if err != nil {
+			a.io.SystemMessage(fmt.Sprintf("warning: cannot escalate to %s: %v", rung.Model, err))
+			continue
+		}
+		if !es.active {
+			es.active = true
+			es.provider, es.providerName, es.model = a.provider, a.config.Provider, a.config.Model
+		}
+		es.rung = next
+		es.toolErrors = 0
+		if rung.Provider != "" {
+			a.config.Provider = rung.Provider
+		}
+		a.provider = p
+		a.config.Model = rung.Model
+		a.rebuildSystemPrompt()
+
+		a.io.SystemMessage(fmt.Sprintf("Escalating: %s → %s (%s) for the rest of this turn", from, rung.Model, reason))
+		if st, ok := a.io.(tui.EscalationStatus); ok {
+			st.SetEscalation(rung.Model)
+		}
+		if a.eventLogger != nil {
+			a.eventLogger.Log(EventEscalation, map[string]string{
+				"from":     from,
+				"to":       rung.Model,
+				"provider": a.config.Provider,
+				"reason":   reason,
+			})
+		}
+		return true
+	}
+	return false
+}
+
+// restoreModel switches back to the model the turn started with.
+func (a *Agent) restoreModel(es *escalation) {
+	if !es.active {
+		return
+	}
+	a.provider, a.config.Provider, a.config.Model = es.provider, es.providerName, es.model
+	*es = escalation{}
+	a.rebuildSystemPrompt()
+	a.io.SystemMessage("Model restored: " + a.activeModel())
+	if st, ok := a.io.(tui.EscalationStatus); ok {
+		st.SetEscalation("")
+	}
+}
+
+// ladderIndex returns the ladder position of the active model, or -1 if
+// it is not on the ladder; escalation then starts at the first rung.
+func (a *Agent) ladderIndex() int {
+	model := a.activeModel()
+	for i, r := range a.config.Escalation.Ladder {
+		if r.Model == model && (r.Provider == "" || r.Provider == a.config.Provider) {
+			return i
+		}
+	}
+	return -1
+}
+
+// rungProvider returns the provider serving rung, building a new one
+// through the provider factory when the rung is on another provider.
+func (a *Agent) rungProvider(rung config.EscalationRung) (provider.Provider, error) {
+	if rung.Provider == "" || rung.Provider == a.config.Provider {
+		return a.provider, nil
+	}
+	if a.providerFactory == nil {
+		return nil, errors.New("provider switching is not available")
+	}
+	cfg := *a.config
+	cfg.Provider = rung.Provider
+	cfg.Model = rung.Model
+	p, err := a.providerFactory(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	a.watchFailover(p)
+	a.watchThrottle(p)
+	return p, nil
+}
+
+// activeModel returns the model requests are sent to.
+func (a *Agent) activeModel() string {
+	if a.config.Model != "" {
+		return a.config.Model
+	}
+	return a.provider.DefaultModel()
+}
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/apexion-ai/apexion/internal/config"
+	"github.com/apexion-ai/apexion/internal/permission"
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/session"
+	"github.com/apexion-ai/apexion/internal/tools"
+)
+
+func TestEscalation_CrossProviderRestores(t *testing.T) {
+	cheap, err := provider.ParseScript([]byte(`
+turns:
+  - tool_calls:
+      - name: read_file
+        input: {file_path: missing.txt}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	strong, err := provider.ParseScript([]byte(`
+turns:
+  - expect:
+      system_contains: "(provider: other, model: big)"
+    text: "The file does not exist."
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := config.DefaultConfig()
+	cfg.Provider = "scripted"
+	cfg.RepoMap.Disabled = true
+	cfg.Escalation = config.EscalationConfig{
+		ToolErrors: 1,
+		Ladder:     []config.EscalationRung{{Provider: "other", Model: "big"}},
+	}
+	ui := &scenarioIO{}
+	executor := tools.NewExecutor(tools.DefaultRegistry(nil, nil), permission.AllowAllPolicy{})
+	a := New(cheap, executor, cfg, ui, session.NullStore{})
+	var built *config.Config
+	a.SetProviderFactory(func(c *config.Config) (provider.Provider, error) {
+		built = c
+		return strong, nil
+	})
+
+	if err := a.RunOnce(t.Context(), "Read missing.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if built == nil || built.Provider != "other" || built.Model != "big" {
+		t.Errorf("factory called with %+v", built)
+	}
+	if strong.Remaining() != 0 || cheap.Remaining() != 0 {
+		t.Error("not all scripted turns were requested")
+	}
+	if a.provider != cheap || a.config.Provider != "scripted" || a.config.Model != "" {
+		t.Errorf("model not restored: provider %s, model %q", a.config.Provider, a.config.Model)
+	}
+}
@@ -16,12 +16,19 @@ type EventType string
 
 const (
 	EventUserMessage   EventType = "user_message"
+	EventUserSteering  EventType = "user_steering"
 	EventAssistantText EventType = "assistant_text"
 	EventToolCall      EventType = "tool_call"
 	EventToolResult    EventType = "tool_result"
 	EventCompaction    EventType = "compaction"
 	EventToolRoute     EventType = "tool_route"
 	EventToolRepair    EventType = "tool_repair"
+	EventFailover      EventType = "provider_failover"
+	EventThrottle      EventType = "provider_throttle"
+	EventTruncated     EventType = "output_truncated"
+	EventBudget        EventType = "budget_exceeded"
+	EventVerify        EventType = "verify"
+	EventEscalation    EventType = "model_escalation"
 	EventError         EventType = "error"
 	EventSessionStart  EventType = "session_start"
 	EventSessionEnd    EventType = "session_end"
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"sync"
 	"sync/atomic"
 	"time"
 
@@ -12,6 +11,7 @@ import (
 	"github.com/apexion-ai/apexion/internal/router"
 	"github.com/apexion-ai/apexion/internal/session"
 	"github.com/apexion-ai/apexion/internal/tools"
+	"github.com/apexion-ai/apexion/internal/tui"
 )
 
 // runAgentLoop executes the core agentic loop:
@@ -30,6 +30,10 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 	}
 	// Reset first-step policy state at the beginning of each user turn.
 	a.clearFirstStepPolicy()
+	a.turnStop = ""
+	if a.budget != nil && !a.subAgent {
+		a.budget.StartTurn(a.session.ID)
+	}
 
 	// Per-turn context: Esc cancels this, not the session.
 	turnCtx, turnCancel := context.WithCancel(ctx)
@@ -42,21 +46,40 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 	}
 
 	// Compute token budget.
-	contextWindow := a.config.ContextWindow
-	if contextWindow <= 0 {
-		contextWindow = a.provider.ContextWindow()
-	}
-	budget := session.NewTokenBudget(contextWindow, estimateTokens(a.systemPrompt))
+	contextWindow := a.contextWindow()
+	counter := a.tokenCounter()
+	budget := session.NewTokenBudget(contextWindow, counter.CountTokens(a.systemPrompt))
 
 	doomDetector := &doomLoopDetector{}
 	failDetector := &failureLoopDetector{}
 	fastpathTried := false
 	firstStepNoToolRetried := false
 
+	// Output budget; raised for the rest of the turn once a response is
+	// truncated. Responses still cut off at the ceiling are continued.
+	maxTokens, maxTokensCeiling := a.maxOutputTokens()
+	continuations := 0
+
+	// Usage of the previous call, used to estimate the next one against
+	// the budget.
+	var lastUsage provider.Usage
+
+	// Project checks once the model stops, if this turn changed files.
+	verify := verifyState{since: time.Now()}
+
+	// Stronger models tried when the turn gets stuck; the next turn starts
+	// on the original one again.
+	var esc escalation
+	defer a.restoreModel(&esc)
+
+	// Read-only tools started while the current response streams.
+	var spec *speculation
+	defer func() { spec.stop() }()
+
 	for iteration := 0; maxIter == 0 || iteration < maxIter; iteration++ {
 		// Check if the turn was cancelled before starting an iteration.
 		if turnCtx.Err() != nil {
-			a.io.SystemMessage("Interrupted.")
+			a.markInterrupted()
 			return nil
 		}
 
@@ -69,7 +92,7 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 			fastpathTried = true
 			if ran, interrupted := a.tryDeterministicFastpath(turnCtx, disableWebFetchForImageTurn && iteration == 0); ran {
 				if interrupted {
-					a.io.SystemMessage("Interrupted.")
+					a.markInterrupted()
 					return nil
 				}
 				continue
@@ -80,14 +103,19 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 		a.maybeCompact(turnCtx, budget)
 
 		// Generate compacted copy for sending (does not modify session).
-		compacted := session.CompactHistory(a.session.Messages, budget.HistoryMax, a.session.Summary)
+		compacted := session.CompactHistory(a.session.Messages, budget.HistoryMax, a.session.Summary, counter)
+		if supported, reason, _ := a.imageInputSupport(); !supported {
+			// Images from tools (or from before a model switch) would be
+			// rejected by a text-only model.
+			compacted = session.ReplaceImages(compacted, "[Image omitted: "+reason+"]")
+		}
 
 		sysPrompt := a.systemPrompt
 		if a.planMode {
 			sysPrompt += "\n\n[PLAN MODE] You are in plan mode. Analyze the request, explore the codebase " +
 				"using your read-only tools, then output a detailed implementation plan. Do NOT make any changes. " +
 				"Structure your plan as:\n1. Files to modify (with paths)\n2. Changes for each file\n" +
-				"3. Verification steps\nThe user will review your plan and switch to execution mode."
+				"3. Verification steps (shell commands where possible)\nThe user will review your plan and run it with /plan execute."
 		}
 
 		temp, topP := modelSamplingParams(a.config.Provider)
@@ -96,33 +124,50 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 			Messages:     compacted,
 			Tools:        a.buildToolSchemas(disableWebFetchForImageTurn && iteration == 0),
 			SystemPrompt: sysPrompt,
-			MaxTokens:    8192,
+			MaxTokens:    maxTokens,
 			Temperature:  temp,
 			TopP:         topP,
+
+			ThinkingBudget: a.config.ThinkingBudget,
+		}
+
+		if be := a.enforceBudget(a.spendModel(), lastUsage); be != nil {
+			a.io.SystemMessage("Stopped: " + be.Error())
+			if a.interactive {
+				a.turnStop = be.Error()
+				return nil
+			}
+			return be
 		}
 
 		var textContent strings.Builder
 		var toolCalls []*provider.ToolCallRequest
+		var thinking *thinkingStream
 		var streamErr error
+		var stopReason provider.StopReason
 
-		// Retry loop for transient API errors.
+		// Retry loop for transient API errors and truncated responses.
 		for attempt := range maxRetries + 1 {
 			textContent.Reset()
 			toolCalls = nil
+			thinking = newThinkingStream(a.io)
 			streamErr = nil
+			stopReason = ""
+			spec.stop()
+			spec = newSpeculation(turnCtx)
 
 			events, err := a.provider.Chat(turnCtx, req)
 			if err != nil {
 				// If cancelled by user Esc, exit gracefully.
 				if turnCtx.Err() != nil {
-					a.io.SystemMessage("Interrupted.")
+					a.markInterrupted()
 					return nil
 				}
 				if attempt < maxRetries && isRetryableError(err) {
 					delay := retryDelay(attempt)
 					a.io.SystemMessage(formatRetryMessage(attempt, maxRetries, delay, err))
 					if sleepErr := sleepWithContext(turnCtx, delay); sleepErr != nil {
-						a.io.SystemMessage("Interrupted.")
+						a.markInterrupted()
 						return nil
 					}
 					continue
@@ -146,32 +191,33 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 					break
 				}
 				switch event.Type {
+				case provider.EventThinkingDelta:
+					receivedContent = true
+					thinking.event(event)
+
 				case provider.EventTextDelta:
 					receivedContent = true
-					a.io.TextDelta(event.TextDelta)
-					textContent.WriteString(event.TextDelta)
+					if visible := thinking.text(event.TextDelta); visible != "" {
+						a.io.TextDelta(visible)
+						textContent.WriteString(visible)
+					}
 
 				case provider.EventToolCallDone:
 					receivedContent = true
+					thinking.flush()
+					a.speculate(spec, toolCalls, event.ToolCall)
 					toolCalls = append(toolCalls, event.ToolCall)
 
 				case provider.EventDone:
+					stopReason = event.StopReason
 					if event.Usage != nil {
-						a.session.PromptTokens = event.Usage.InputTokens
+						a.session.PromptTokens = event.Usage.PromptTokens()
 						a.session.CompletionTokens = event.Usage.OutputTokens
-						a.session.TokensUsed += event.Usage.InputTokens + event.Usage.OutputTokens
+						a.session.TokensUsed += event.Usage.PromptTokens() + event.Usage.OutputTokens
 						a.io.SetTokens(a.session.TokensUsed)
 						a.io.SetContextInfo(a.session.PromptTokens, contextWindow)
-
-						// Record cost if tracker is available.
-						if a.costTracker != nil {
-							model := a.config.Model
-							if model == "" {
-								model = a.provider.DefaultModel()
-							}
-							a.costTracker.RecordTurn(model, event.Usage.InputTokens, event.Usage.OutputTokens)
-							a.io.SetCost(a.costTracker.SessionCost())
-						}
+						a.recordSpend(a.spendModel(), *event.Usage)
+						lastUsage = *event.Usage
 					}
 
 				case provider.EventError:
@@ -179,6 +225,11 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 				}
 			}
 
+			if rest := thinking.finish(); rest != "" {
+				a.io.TextDelta(rest)
+				textContent.WriteString(rest)
+			}
+
 			// If user cancelled during streaming, exit gracefully.
 			if turnCtx.Err() != nil {
 				full := textContent.String()
@@ -186,7 +237,7 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 				if full != "" {
 					a.session.AddMessage(buildAssistantMessage(full, nil))
 				}
-				a.io.SystemMessage("Interrupted.")
+				a.markInterrupted()
 				return nil
 			}
 
@@ -195,14 +246,16 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 				delay := retryDelay(attempt)
 				a.io.SystemMessage(formatRetryMessage(attempt, maxRetries, delay, streamErr))
 				if sleepErr := sleepWithContext(turnCtx, delay); sleepErr != nil {
-					a.io.SystemMessage("Interrupted.")
+					a.markInterrupted()
 					return nil
 				}
 				continue
 			}
 
 			// Stream error after content was received — save partial content and continue.
+			// Speculative results are dropped; the received calls run again below.
 			if streamErr != nil {
+				spec.stop()
 				if a.eventLogger != nil {
 					a.eventLogger.Log(EventError, map[string]any{
 						"error_class": "model_error",
@@ -214,10 +267,42 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 				break // exit retry loop, process whatever we received
 			}
 
+			// Truncated below the model's output limit: discard the partial
+			// response and ask again with the full budget.
+			if stopReason == provider.StopMaxTokens && req.MaxTokens < maxTokensCeiling && attempt < maxRetries {
+				if d, ok := a.io.(tui.TextDiscarder); ok {
+					d.TextDiscard()
+				}
+				a.io.SystemMessage(fmt.Sprintf("Response hit the %d-token output limit; retrying with max_tokens=%d.", req.MaxTokens, maxTokensCeiling))
+				a.logTruncation(req.MaxTokens, "retry", len(toolCalls))
+				maxTokens = maxTokensCeiling
+				req.MaxTokens = maxTokens
+				continue
+			}
+
 			break // success
 		}
 
-		full := stripThinkTags(textContent.String())
+		// Still truncated: never run the cut-off tool calls. Keep the
+		// partial text and tell the model what happened.
+		if stopReason == provider.StopMaxTokens {
+			if continuations >= maxContinuations {
+				full := strings.TrimSpace(textContent.String())
+				a.io.TextDone(full)
+				if full != "" {
+					a.session.AddMessage(buildAssistantMessage(full, nil))
+				}
+				a.io.SystemMessage(fmt.Sprintf("warning: response truncated at the output limit %d times in a row, stopping", continuations+1))
+				a.turnStop = fmt.Sprintf("response truncated at the output limit %d times in a row", continuations+1)
+				return nil
+			}
+			continuations++
+			a.handleTruncatedResponse(strings.TrimSpace(textContent.String()), toolCalls, thinking, req.MaxTokens)
+			continue
+		}
+		continuations = 0
+
+		full := strings.TrimSpace(textContent.String())
 		a.io.TextDone(full)
 
 		// Log assistant text output.
@@ -226,6 +311,10 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 		}
 
 		assistantMsg := buildAssistantMessage(full, toolCalls)
+		if blocks := thinking.historyBlocks(); len(blocks) > 0 && len(assistantMsg.Content) > 0 {
+			// Signed reasoning precedes the answer so it can be replayed as-is.
+			assistantMsg.Content = append(blocks, assistantMsg.Content...)
+		}
 		a.session.AddMessage(assistantMsg)
 
 		if len(toolCalls) == 0 {
@@ -242,6 +331,26 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 				})
 				continue
 			}
+			// The model finished, but the user sent something meanwhile:
+			// answer it in the same turn.
+			if steer := a.takeSteering(); len(steer) > 0 {
+				a.session.AddMessage(provider.Message{Role: provider.RoleUser, Content: steer})
+				continue
+			}
+			if feedback := a.verifyTurn(turnCtx, &verify); feedback != "" {
+				a.session.AddMessage(provider.Message{
+					Role:    provider.RoleUser,
+					Content: []provider.Content{{Type: provider.ContentTypeText, Text: feedback}},
+				})
+				continue
+			}
+			if turnCtx.Err() != nil {
+				a.markInterrupted()
+				return nil
+			}
+			if a.planMode {
+				a.capturePlan(turnCtx, full)
+			}
 			a.clearFirstStepPolicy()
 			return nil
 		}
@@ -249,9 +358,13 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 		if maxIter > 0 && iteration == maxIter-1 {
 			a.io.SystemMessage(fmt.Sprintf(
 				"warning: reached max iterations (%d), stopping", maxIter))
+			a.turnStop = fmt.Sprintf("reached max iterations (%d)", maxIter)
 			return nil
 		}
 
+		// Why the model looks stuck, if it does; it triggers escalation.
+		stuck := ""
+
 		// Doom loop detection: catch the model issuing identical tool calls repeatedly.
 		switch doomDetector.check(toolCalls) {
 		case doomLoopWarn:
@@ -265,15 +378,27 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 					Text: "[SYSTEM] " + warning,
 				}},
 			})
+			stuck = "same tool calls repeated"
 		case doomLoopStop:
 			a.io.SystemMessage("error: doom loop detected — same tool calls repeated 5 times, stopping")
+			a.turnStop = "doom loop: same tool calls repeated 5 times"
 			return nil
 		}
 
-		toolResults, interrupted, executedAny := a.executeToolCalls(turnCtx, toolCalls)
+		toolResults, interrupted, executedAny := a.executeToolCalls(turnCtx, toolCalls, spec)
+		spec.stop()
+		verify.noteCalls(toolCalls)
+		content := toolResults
+		if !interrupted {
+			// Messages the user sent while the tools ran follow the results,
+			// so the model sees them before deciding its next step.
+			if steer := a.takeSteering(); len(steer) > 0 {
+				content = append(append([]provider.Content(nil), toolResults...), steer...)
+			}
+		}
 		a.session.AddMessage(provider.Message{
 			Role:    provider.RoleUser,
-			Content: toolResults,
+			Content: content,
 		})
 		if a.shouldRetryNoToolFirstStep() && !executedAny && !firstStepNoToolRetried {
 			firstStepNoToolRetried = true
@@ -301,22 +426,113 @@ func (a *Agent) runAgentLoop(ctx context.Context) error {
 					Text: "[SYSTEM] " + warning,
 				}},
 			})
+			stuck = "repeated tool failures"
 		case doomLoopStop:
 			a.io.SystemMessage("error: repeated tool failures detected 4 times, stopping")
+			a.turnStop = "repeated tool failures detected 4 times"
 			return nil
 		}
+		if a.trackToolErrors(&esc, toolResults) && stuck == "" {
+			stuck = fmt.Sprintf("%d consecutive tool errors", esc.toolErrors)
+		}
 
 		// If user interrupted during tool execution, stop the loop
 		// and return to user input. The partial results are already
 		// in the message history for context continuity.
 		if interrupted {
-			a.io.SystemMessage("Interrupted.")
+			a.markInterrupted()
 			return nil
 		}
+
+		// A stronger model gets a fresh start with the detectors.
+		if stuck != "" && a.escalate(&esc, stuck) {
+			doomDetector = &doomLoopDetector{}
+			failDetector = &failureLoopDetector{}
+		}
 	}
+	a.turnStop = fmt.Sprintf("reached max iterations (%d)", maxIter)
 	return nil
 }
 
+// markInterrupted reports that the turn was cancelled and remembers it
+// for callers that run several turns, like /plan execute.
+func (a *Agent) markInterrupted() {
+	a.turnStop = "interrupted"
+	a.io.SystemMessage("Interrupted.")
+}
+
+// takeSteering drains messages the user queued while the turn was running,
+// echoes them to the UI and returns them as text blocks.
+func (a *Agent) takeSteering() []provider.Content {
+	si, ok := a.io.(tui.SteeringInput)
+	if !ok {
+		return nil
+	}
+	var content []provider.Content
+	for _, text := range si.DrainSteering() {
+		a.io.UserMessage(text)
+		if a.eventLogger != nil {
+			a.eventLogger.Log(EventUserSteering, map[string]string{"text": text})
+		}
+		content = append(content, provider.Content{Type: provider.ContentTypeText, Text: text})
+	}
+	return content
+}
+
+// maxContinuations caps how many truncated responses in a row are continued
+// within one turn.
+const maxContinuations = 3
+
+// handleTruncatedResponse records a response cut off at the output limit
+// and asks the model to carry on. Tool calls from it are dropped unrun:
+// their arguments may be incomplete even when they parse.
+func (a *Agent) handleTruncatedResponse(text string, calls []*provider.ToolCallRequest, thinking *thinkingStream, limit int) {
+	a.io.TextDone(text)
+	a.logTruncation(limit, "continue", len(calls))
+
+	var prompt string
+	if len(calls) > 0 {
+		names := make([]string, len(calls))
+		for i, c := range calls {
+			names[i] = c.Name
+		}
+		a.io.SystemMessage(fmt.Sprintf("warning: response hit the %d-token output limit; dropped %d unfinished tool call(s) (%s) without running them",
+			limit, len(calls), strings.Join(names, ", ")))
+		prompt = fmt.Sprintf("[SYSTEM] Your previous response hit the output token limit (%d tokens) while writing a call to %s. "+
+			"The call was cut off and was NOT executed. Do not repeat it unchanged: split the work into smaller steps, "+
+			"e.g. write a large file in several parts or use targeted edits.", limit, strings.Join(names, ", "))
+	} else {
+		a.io.SystemMessage(fmt.Sprintf("Response hit the %d-token output limit; asking the model to continue.", limit))
+		prompt = "[SYSTEM] Your previous response was cut off at the output token limit. " +
+			"Continue exactly where you left off, without repeating what you already wrote."
+	}
+
+	if text == "" {
+		text = "(response truncated at the output limit)"
+	}
+	msg := buildAssistantMessage(text, nil)
+	if blocks := thinking.historyBlocks(); len(blocks) > 0 {
+		msg.Content = append(blocks, msg.Content...)
+	}
+	a.session.AddMessage(msg)
+	a.session.AddMessage(provider.Message{
+		Role:    provider.RoleUser,
+		Content: []provider.Content{{Type: provider.ContentTypeText, Text: prompt}},
+	})
+}
+
+// logTruncation records a max_tokens stop in the event log.
+func (a *Agent) logTruncation(limit int, action string, droppedCalls int) {
+	if a.eventLogger == nil {
+		return
+	}
+	a.eventLogger.Log(EventTruncated, map[string]any{
+		"max_tokens":    limit,
+		"action":        action,
+		"dropped_calls": droppedCalls,
+	})
+}
+
 // buildAssistantMessage creates a history message from the LLM response.
 func buildAssistantMessage(text string, toolCalls []*provider.ToolCallRequest) provider.Message {
 	var contents []provider.Content
@@ -341,98 +557,78 @@ func buildAssistantMessage(text string, toolCalls []*provider.ToolCallRequest) p
 }
 
 // executeToolCalls runs tool calls and returns tool_result content blocks.
-// When multiple calls are present, they execute concurrently via goroutines.
-// Results are kept in the same order as the input calls.
+// When multiple calls are present, the executor's scheduler runs them
+// concurrently, serializing calls that touch the same files or the working
+// tree. Results are kept in the same order as the input calls.
 // The second return value is true if the user interrupted (Esc) during execution.
 // The third return value indicates whether at least one tool was actually executed
 // (i.e. reached ToolStart, not blocked by first-step policy).
-func (a *Agent) executeToolCalls(ctx context.Context, calls []*provider.ToolCallRequest) ([]provider.Content, bool, bool) {
+// Calls already started by spec use its result instead of running again.
+func (a *Agent) executeToolCalls(ctx context.Context, calls []*provider.ToolCallRequest, spec *speculation) ([]provider.Content, bool, bool) {
 	// Single call: run inline (no goroutine overhead).
 	if len(calls) == 1 {
-		return a.executeSingleToolCall(ctx, calls[0])
+		return a.executeSingleToolCall(ctx, calls[0], spec)
 	}
 
-	// Multiple calls: run concurrently.
+	// Multiple calls: run concurrently where they don't conflict.
 	type indexedResult struct {
-		contents []provider.Content // tool_result + optional image
+		contents []provider.Content
 		executed bool
 	}
 
 	resultSlots := make([]indexedResult, len(calls))
 	var interrupted atomic.Bool
-	var wg sync.WaitGroup
 
-	for i, call := range calls {
-		wg.Add(1)
-		go func(idx int, c *provider.ToolCallRequest) {
-			defer wg.Done()
-
-			// If another goroutine was interrupted, skip this one.
-			if interrupted.Load() {
-				return
-			}
-			if msg, blocked := a.blockByFirstStepPolicy(c.Name); blocked {
-				resultSlots[idx] = indexedResult{contents: []provider.Content{{
-					Type:       provider.ContentTypeToolResult,
-					ToolUseID:  c.ID,
-					ToolResult: msg,
-					IsError:    true,
-				}}}
-				return
-			}
-			if a.eventLogger != nil {
-				a.eventLogger.Log(EventToolCall, map[string]any{
-					"tool_name": c.Name,
-					"tool_id":   c.ID,
-				})
-			}
-
-			a.io.ToolStart(c.ID, c.Name, string(c.Input))
-			started := time.Now()
-			result, executedName, _ := a.executeToolWithRepair(ctx, c)
-			latencyMs := time.Since(started).Milliseconds()
-			a.io.ToolDone(c.ID, executedName, result.Content, result.IsError)
-			if a.eventLogger != nil {
-				health := a.toolHealthSnapshot(executedName, time.Now())
-				a.eventLogger.Log(EventToolResult, map[string]any{
-					"tool_name":              c.Name,
-					"executed_tool":          executedName,
-					"tool_id":                c.ID,
-					"is_error":               result.IsError,
-					"error_class":            result.ErrorClass,
-					"tool_health_score":      health.Score,
-					"tool_circuit_open":      health.CircuitOpen,
-					"tool_cooldown_sec":      health.CooldownRemainingSec,
-					"tool_successes_total":   health.Successes,
-					"tool_failures_total":    health.Failures,
-					"tool_exec_latency_ms":   latencyMs,
-					"tool_consecutive_fails": health.ConsecutiveFails,
-				})
-			}
-
-			var contents []provider.Content
-			contents = append(contents, provider.Content{
+	a.executor.Schedule(schedulerCalls(calls), func(idx int) {
+		c := calls[idx]
+		// If another goroutine was interrupted, skip this one.
+		if interrupted.Load() {
+			return
+		}
+		if msg, blocked := a.blockByFirstStepPolicy(c.Name); blocked {
+			resultSlots[idx] = indexedResult{contents: []provider.Content{{
 				Type:       provider.ContentTypeToolResult,
 				ToolUseID:  c.ID,
-				ToolResult: result.Content,
-				IsError:    result.IsError,
+				ToolResult: msg,
+				IsError:    true,
+			}}}
+			return
+		}
+		if a.eventLogger != nil {
+			a.eventLogger.Log(EventToolCall, map[string]any{
+				"tool_name": c.Name,
+				"tool_id":   c.ID,
 			})
-			if result.ImageData != "" {
-				contents = append(contents, provider.Content{
-					Type:           provider.ContentTypeImage,
-					ImageData:      result.ImageData,
-					ImageMediaType: result.ImageMediaType,
-				})
-			}
-			resultSlots[idx] = indexedResult{contents: contents, executed: true}
+		}
 
-			if result.UserCancelled {
-				interrupted.Store(true)
-			}
-		}(i, call)
-	}
+		a.io.ToolStart(c.ID, c.Name, string(c.Input))
+		result, executedName, latencyMs := a.runToolCall(ctx, c, spec)
+		a.io.ToolDone(c.ID, executedName, result.Content, result.IsError)
+		if a.eventLogger != nil {
+			health := a.toolHealthSnapshot(executedName, time.Now())
+			a.eventLogger.Log(EventToolResult, map[string]any{
+				"tool_name":              c.Name,
+				"executed_tool":          executedName,
+				"tool_id":                c.ID,
+				"is_error":               result.IsError,
+				"error_class":            result.ErrorClass,
+				"tool_health_score":      health.Score,
+				"tool_circuit_open":      health.CircuitOpen,
+				"tool_cooldown_sec":      health.CooldownRemainingSec,
+				"tool_successes_total":   health.Successes,
+				"tool_failures_total":    health.Failures,
+				"tool_exec_latency_ms":   latencyMs,
+				"tool_consecutive_fails": health.ConsecutiveFails,
+			})
+		}
 
-	wg.Wait()
+		contents := []provider.Content{toolResultContent(c.ID, result)}
+		resultSlots[idx] = indexedResult{contents: contents, executed: true}
+
+		if result.UserCancelled {
+			interrupted.Store(true)
+		}
+	})
 
 	// Assemble results in order, filling in cancelled placeholders for skipped calls.
 	var results []provider.Content
@@ -461,7 +657,7 @@ func (a *Agent) executeToolCalls(ctx context.Context, calls []*provider.ToolCall
 }
 
 // executeSingleToolCall handles the simple case of a single tool call (no concurrency).
-func (a *Agent) executeSingleToolCall(ctx context.Context, call *provider.ToolCallRequest) ([]provider.Content, bool, bool) {
+func (a *Agent) executeSingleToolCall(ctx context.Context, call *provider.ToolCallRequest, spec *speculation) ([]provider.Content, bool, bool) {
 	if msg, blocked := a.blockByFirstStepPolicy(call.Name); blocked {
 		return []provider.Content{{
 			Type:       provider.ContentTypeToolResult,
@@ -479,9 +675,7 @@ func (a *Agent) executeSingleToolCall(ctx context.Context, call *provider.ToolCa
 	}
 
 	a.io.ToolStart(call.ID, call.Name, string(call.Input))
-	started := time.Now()
-	result, executedName, _ := a.executeToolWithRepair(ctx, call)
-	latencyMs := time.Since(started).Milliseconds()
+	result, executedName, latencyMs := a.runToolCall(ctx, call, spec)
 	a.io.ToolDone(call.ID, executedName, result.Content, result.IsError)
 
 	if a.eventLogger != nil {
@@ -502,20 +696,7 @@ func (a *Agent) executeSingleToolCall(ctx context.Context, call *provider.ToolCa
 		})
 	}
 
-	var results []provider.Content
-	results = append(results, provider.Content{
-		Type:       provider.ContentTypeToolResult,
-		ToolUseID:  call.ID,
-		ToolResult: result.Content,
-		IsError:    result.IsError,
-	})
-	if result.ImageData != "" {
-		results = append(results, provider.Content{
-			Type:           provider.ContentTypeImage,
-			ImageData:      result.ImageData,
-			ImageMediaType: result.ImageMediaType,
-		})
-	}
+	results := []provider.Content{toolResultContent(call.ID, result)}
 
 	if result.UserCancelled {
 		return results, true, true
@@ -523,6 +704,19 @@ func (a *Agent) executeSingleToolCall(ctx context.Context, call *provider.ToolCa
 	return results, false, true
 }
 
+// toolResultContent converts a tool result for history. An image the tool
+// returned stays inside the tool_result block.
+func toolResultContent(id string, result tools.ToolResult) provider.Content {
+	return provider.Content{
+		Type:           provider.ContentTypeToolResult,
+		ToolUseID:      id,
+		ToolResult:     result.Content,
+		IsError:        result.IsError,
+		ImageData:      result.ImageData,
+		ImageMediaType: result.ImageMediaType,
+	}
+}
+
 // maybeCompact runs three-stage auto-compaction if context is growing large.
 //
 // Phase 1 (70% threshold): Mask low-importance tool outputs (glob, grep, list_dir, etc.)
@@ -597,31 +791,8 @@ func (a *Agent) currentTokens(budget *session.TokenBudget) int {
 	if a.session.PromptTokens > 0 {
 		return a.session.PromptTokens
 	}
-	return a.session.EstimateTokens() + estimateTokens(a.systemPrompt)
-}
-
-// stripThinkTags removes <think>...</think> blocks that some models (e.g. MiniMax, DeepSeek)
-// include in their output. Handles partial tags from mid-stream disconnects.
-func stripThinkTags(s string) string {
-	for {
-		start := strings.Index(s, "<think>")
-		if start == -1 {
-			break
-		}
-		end := strings.Index(s[start:], "</think>")
-		if end == -1 {
-			// Partial think block (e.g. stream cut off mid-think) — strip to end.
-			s = strings.TrimSpace(s[:start])
-			break
-		}
-		s = s[:start] + s[start+end+len("</think>"):]
-	}
-	return strings.TrimSpace(s)
-}
-
-// estimateTokens returns a rough token estimate for a string (chars / 4).
-func estimateTokens(s string) int {
-	return len(s) / 4
+	counter := a.tokenCounter()
+	return a.session.EstimateTokens(counter) + counter.CountTokens(a.systemPrompt)
 }
 
 // shouldDisableWebFetchForImageTurn returns true when the latest user prompt
@@ -810,7 +981,7 @@ func (a *Agent) buildToolSchemas(disableWebFetch bool) []provider.ToolSchema {
 func latestUserTurnContext(messages []provider.Message) (text string, hasImage bool, hasToolResult bool) {
 	for i := len(messages) - 1; i >= 0; i-- {
 		msg := messages[i]
-		if msg.Role != provider.RoleUser {
+		if msg.Role != provider.RoleUser || isSystemNote(msg) {
 			continue
 		}
 		var parts []string
@@ -831,6 +1002,20 @@ func latestUserTurnContext(messages []provider.Message) (text string, hasImage b
 	return "", false, false
 }
 
+// isSystemNote reports whether msg is a "[SYSTEM]" hint injected by the
+// loop rather than something the user said; routing looks past these.
+func isSystemNote(msg provider.Message) bool {
+	if len(msg.Content) == 0 {
+		return false
+	}
+	for _, c := range msg.Content {
+		if c.Type != provider.ContentTypeText || !strings.HasPrefix(c.Text, "[SYSTEM]") {
+			return false
+		}
+	}
+	return true
+}
+
 func (a *Agent) setFirstStepAllowed(names []string) {
 	a.setFirstStepPolicy("", names)
 }
@@ -3,9 +3,12 @@ package agent
 import (
 	"testing"
 
+	"github.com/apexion-ai/apexion/internal/config"
+	"github.com/apexion-ai/apexion/internal/permission"
 	"github.com/apexion-ai/apexion/internal/provider"
 	"github.com/apexion-ai/apexion/internal/router"
 	"github.com/apexion-ai/apexion/internal/session"
+	"github.com/apexion-ai/apexion/internal/tools"
 )
 
 func TestShouldDisableWebFetchForImageTurn(t *testing.T) {
@@ -148,3 +151,48 @@ func TestShouldRetryNoToolFirstStepByIntentFallback(t *testing.T) {
 		t.Fatal("expected debug intent fallback to require a retry")
 	}
 }
+
+// textIO records completed and discarded text.
+type textIO struct {
+	scenarioIO
+	done      []string
+	discarded int
+}
+
+func (t *textIO) TextDone(text string) { t.done = append(t.done, text) }
+func (t *textIO) TextDiscard()         { t.discarded++ }
+
+func TestTruncationRetryDiscardsPartialText(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	p, err := provider.ParseScript([]byte(`
+model: claude-sonnet-4-5
+turns:
+  - expect:
+      max_tokens: 8192
+    text: "The build has"
+    stop_reason: max_tokens
+  - expect:
+      max_tokens: 64000
+    text: "The build has three stages."
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Provider = "scripted"
+	cfg.RepoMap.Disabled = true
+	ui := &textIO{}
+	executor := tools.NewExecutor(tools.DefaultRegistry(nil, nil), permission.AllowAllPolicy{})
+	a := New(p, executor, cfg, ui, session.NullStore{})
+
+	if err := a.RunOnce(t.Context(), "Explain the build."); err != nil {
+		t.Fatal(err)
+	}
+	if len(ui.done) != 1 || ui.done[0] != "The build has three stages." {
+		t.Errorf("TextDone calls = %q, want only the retried answer", ui.done)
+	}
+	if ui.discarded != 1 {
+		t.Errorf("TextDiscard calls = %d, want 1", ui.discarded)
+	}
+}
@@ -0,0 +1,375 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/session"
+	"github.com/apexion-ai/apexion/internal/tools"
+	"github.com/apexion-ai/apexion/internal/tui"
+	"gopkg.in/yaml.v3"
+)
+
+// planExtractPrompt turns the plan-mode answer into a structured plan.
+const planExtractPrompt = `Convert the implementation plan below into structured form.
+Keep the steps in their order and keep their wording; do not add work the plan does not describe.
+For each step list the files it creates or changes. If a single shell command can check the step on its own
+(a build, or a focused test), give it as verify; otherwise leave verify empty.
+verification lists shell commands that check the finished change as a whole, taken from the plan's verification steps.
+If the text is not an implementation plan, return no steps.`
+
+// planSchema is the structure requested from the model for a plan.
+var planSchema = &provider.ResponseSchema{
+	Name:        "implementation_plan",
+	Description: "a step-by-step implementation plan",
+	Schema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"summary": map[string]any{"type": "string", "description": "What the plan achieves, in one or two sentences"},
+			"steps": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"description": map[string]any{"type": "string", "description": "What this step does"},
+						"files":       map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+						"details":     map[string]any{"type": "string", "description": "What to change and how"},
+						"verify":      map[string]any{"type": "string", "description": "Shell command that checks this step, or empty"},
+					},
+					"required": []string{"description", "files", "details", "verify"},
+				},
+			},
+			"verification": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Shell commands that check the whole change",
+			},
+		},
+		"required": []string{"summary", "steps", "verification"},
+	},
+}
+
+type planReply struct {
+	Summary string `json:"summary"`
+	Steps   []struct {
+		Description string   `json:"description"`
+		Files       []string `json:"files"`
+		Details     string   `json:"details"`
+		Verify      string   `json:"verify"`
+	} `json:"steps"`
+	Verification []string `json:"verification"`
+}
+
+// handlePlan implements /plan and its subcommands.
+func (a *Agent) handlePlan(ctx context.Context, arg string) bool {
+	switch arg {
+	case "":
+		a.planMode = !a.planMode
+		if a.planMode {
+			a.io.SystemMessage("Plan mode ON — agent will analyze and propose, not execute.")
+		} else {
+			a.io.SystemMessage("Plan mode OFF — agent will execute normally.")
+		}
+		a.io.SetPlanMode(a.planMode)
+	case "show":
+		if p := a.currentPlan(); p != nil {
+			a.io.SystemMessage(renderPlan(p))
+		} else {
+			a.io.SystemMessage("No plan for this session. Use /plan to enter plan mode and ask for one.")
+		}
+	case "edit":
+		a.editPlan()
+	case "execute":
+		a.executePlan(ctx)
+	default:
+		a.io.Error("Usage: /plan [show|edit|execute]")
+	}
+	return true
+}
+
+// currentPlan returns the plan of the current session, or nil. It is read
+// from the store after a session switch, so plans survive /resume.
+func (a *Agent) currentPlan() *session.Plan {
+	if a.plan != nil && a.plan.SessionID == a.session.ID {
+		return a.plan
+	}
+	p, err := a.planStore.LoadPlan(a.session.ID)
+	if err != nil {
+		a.io.Error(err.Error())
+	}
+	a.plan = p
+	return p
+}
+
+func (a *Agent) savePlan(p *session.Plan) {
+	p.SessionID = a.session.ID
+	a.plan = p
+	if err := a.planStore.SavePlan(p); err != nil {
+		a.io.Error(err.Error())
+	}
+}
+
+// capturePlan stores the plan the model wrote in plan mode as a structured,
+// reviewable plan for /plan edit and /plan execute.
+func (a *Agent) capturePlan(ctx context.Context, text string) {
+	if a.subAgent || text == "" {
+		return
+	}
+	model := a.config.Model
+	if model == "" {
+		model = a.provider.DefaultModel()
+	}
+	req := &provider.ChatRequest{
+		Model:        model,
+		SystemPrompt: planExtractPrompt,
+		Messages: []provider.Message{{
+			Role:    provider.RoleUser,
+			Content: []provider.Content{{Type: provider.ContentTypeText, Text: text}},
+		}},
+		MaxTokens: a.config.MaxTokensFor(model),
+	}
+	var reply planReply
+	if err := provider.ChatJSON(ctx, a.provider, req, planSchema, &reply); err != nil {
+		if ctx.Err() == nil {
+			a.io.SystemMessage("warning: plan not saved: " + err.Error())
+		}
+		return
+	}
+	if len(reply.Steps) == 0 {
+		return
+	}
+
+	plan := &session.Plan{Summary: reply.Summary}
+	for _, s := range reply.Steps {
+		plan.Steps = append(plan.Steps, session.PlanStep{
+			Description: s.Description,
+			Files:       s.Files,
+			Details:     s.Details,
+			Verify:      strings.TrimSpace(s.Verify),
+			Status:      session.PlanPending,
+		})
+	}
+	for _, run := range reply.Verification {
+		if run = strings.TrimSpace(run); run != "" {
+			plan.Verification = append(plan.Verification, session.PlanCheck{Run: run, Status: session.PlanPending})
+		}
+	}
+	a.savePlan(plan)
+	a.io.SystemMessage(renderPlan(plan) + "\n\nPlan saved. /plan edit to revise it, /plan execute to run it step by step.")
+}
+
+// editPlan opens the plan as YAML in the user's editor and saves the result.
+func (a *Agent) editPlan() {
+	fe, ok := a.io.(tui.FileEditor)
+	if !ok {
+		a.io.Error("/plan edit needs an interactive terminal")
+		return
+	}
+	p := a.currentPlan()
+	if p == nil {
+		a.io.SystemMessage("No plan for this session. Use /plan to enter plan mode and ask for one.")
+		return
+	}
+
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		a.io.Error(err.Error())
+		return
+	}
+	f, err := os.CreateTemp("", "apexion-plan-*.yaml")
+	if err != nil {
+		a.io.Error(err.Error())
+		return
+	}
+	defer os.Remove(f.Name())
+	header := "# Edit the plan, then save and quit.\n# Status: pending, in_progress, done, failed or skipped.\n"
+	_, err = f.WriteString(header + string(data))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		a.io.Error(err.Error())
+		return
+	}
+
+	if err := fe.EditFile(f.Name()); err != nil {
+		a.io.Error("editor: " + err.Error())
+		return
+	}
+	data, err = os.ReadFile(f.Name())
+	if err != nil {
+		a.io.Error(err.Error())
+		return
+	}
+	var edited session.Plan
+	if err := yaml.Unmarshal(data, &edited); err != nil {
+		a.io.Error("Plan not saved: " + err.Error())
+		return
+	}
+	if err := edited.Validate(); err != nil {
+		a.io.Error("Plan not saved: " + err.Error())
+		return
+	}
+	a.savePlan(&edited)
+	a.io.SystemMessage(renderPlan(&edited))
+}
+
+// executePlan runs the remaining steps of the plan, one agent turn per
+// step, and records each outcome. It stops at the first failed step so the
+// user can fix things and run /plan execute again.
+func (a *Agent) executePlan(ctx context.Context) {
+	p := a.currentPlan()
+	if p == nil {
+		a.io.SystemMessage("No plan for this session. Use /plan to enter plan mode and ask for one.")
+		return
+	}
+	if a.planMode {
+		a.planMode = false
+		a.io.SetPlanMode(false)
+		a.io.SystemMessage("Plan mode OFF — executing the plan.")
+	}
+
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		if step.Status == session.PlanDone || step.Status == session.PlanSkipped {
+			continue
+		}
+		step.Status, step.Note = session.PlanInProgress, ""
+		a.savePlan(p)
+		a.io.SystemMessage(fmt.Sprintf("Plan step %d/%d: %s", i+1, len(p.Steps), step.Description))
+
+		a.session.AddMessage(provider.Message{
+			Role:    provider.RoleUser,
+			Content: []provider.Content{{Type: provider.ContentTypeText, Text: planStepPrompt(p, i)}},
+		})
+		var note string
+		err := a.runAgentLoop(ctx)
+		switch {
+		case err != nil:
+			note = err.Error()
+		case a.turnStop != "":
+			note = a.turnStop
+		case ctx.Err() != nil:
+			note = "interrupted"
+		case step.Verify != "":
+			if r := a.runPlanCheck(ctx, step.Verify); !r.Passed {
+				note = fmt.Sprintf("`%s` failed:\n%s", r.Command, r.Output)
+			}
+		}
+		if note != "" {
+			step.Status, step.Note = session.PlanFailed, note
+			a.savePlan(p)
+			a.io.SystemMessage(fmt.Sprintf("Plan step %d failed: %s\nFix it and run /plan execute again to resume, or /plan edit to change the plan.",
+				i+1, truncate(note, 500)))
+			return
+		}
+		step.Status = session.PlanDone
+		a.savePlan(p)
+	}
+
+	var failed []string
+	for i := range p.Verification {
+		c := &p.Verification[i]
+		if c.Status == session.PlanDone || c.Status == session.PlanSkipped {
+			continue
+		}
+		a.io.SystemMessage("Plan verification: " + c.Run)
+		if r := a.runPlanCheck(ctx, c.Run); r.Passed {
+			c.Status = session.PlanDone
+		} else {
+			c.Status = session.PlanFailed
+			failed = append(failed, fmt.Sprintf("`%s` failed:\n%s", r.Command, truncate(r.Output, 500)))
+		}
+	}
+	a.savePlan(p)
+	if len(failed) > 0 {
+		a.io.SystemMessage("All plan steps done, but verification failed:\n" + strings.Join(failed, "\n"))
+		return
+	}
+	a.io.SystemMessage(fmt.Sprintf("Plan complete: %d step(s) done.", len(p.Steps)))
+}
+
+// runPlanCheck runs one verification command of a plan. The commands are
+// written by the model, so they run through the bash tool and with it the
+// permission policy, deny rules and hooks.
+func (a *Agent) runPlanCheck(ctx context.Context, run string) tools.VerifyResult {
+	timeout := a.config.Verify.TimeoutSec
+	if timeout <= 0 {
+		timeout = 300
+	}
+	params, _ := json.Marshal(map[string]any{"command": run, "timeout": timeout})
+	start := time.Now()
+	res := a.executor.Execute(ctx, "bash", params)
+	r := tools.VerifyResult{
+		Name:     run,
+		Command:  run,
+		Passed:   !res.IsError && !res.UserCancelled,
+		Duration: time.Since(start),
+	}
+	if !r.Passed {
+		r.Output = strings.TrimSpace(res.Content)
+	}
+	return r
+}
+
+// planStepPrompt asks the model to carry out step i of the plan.
+func planStepPrompt(p *session.Plan, i int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[PLAN] Carry out step %d of %d of the plan below. Do only this step; the later steps follow separately.\n\n", i+1, len(p.Steps))
+	if p.Summary != "" {
+		fmt.Fprintf(&sb, "Plan: %s\n", p.Summary)
+	}
+	for j, s := range p.Steps {
+		fmt.Fprintf(&sb, "  %d. [%s] %s\n", j+1, s.Status, s.Description)
+	}
+	step := p.Steps[i]
+	fmt.Fprintf(&sb, "\nStep %d: %s\n", i+1, step.Description)
+	if len(step.Files) > 0 {
+		fmt.Fprintf(&sb, "Files: %s\n", strings.Join(step.Files, ", "))
+	}
+	if step.Details != "" {
+		fmt.Fprintf(&sb, "Details: %s\n", step.Details)
+	}
+	if step.Verify != "" {
+		fmt.Fprintf(&sb, "The step is checked afterwards with `%s`.\n", step.Verify)
+	}
+	sb.WriteString("When the step is done, stop calling tools and briefly say what you changed.")
+	return sb.String()
+}
+
+// renderPlan formats a plan with the status of each step.
+func renderPlan(p *session.Plan) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "=== Plan (%s) ===\n", p.Status())
+	if p.Summary != "" {
+		fmt.Fprintf(&sb, "Summary: %s\n", p.Summary)
+	}
+	sb.WriteString("\n")
+	for i, s := range p.Steps {
+		fmt.Fprintf(&sb, "  %d. [%s] %s\n", i+1, s.Status, s.Description)
+		if len(s.Files) > 0 {
+			fmt.Fprintf(&sb, "     Files: %s\n", strings.Join(s.Files, ", "))
+		}
+		if s.Details != "" {
+			fmt.Fprintf(&sb, "     Details: %s\n", truncate(s.Details, 200))
+		}
+		if s.Verify != "" {
+			fmt.Fprintf(&sb, "     Verify: %s\n", s.Verify)
+		}
+		if s.Note != "" {
+			fmt.Fprintf(&sb, "     Note: %s\n", truncate(s.Note, 200))
+		}
+	}
+	if len(p.Verification) > 0 {
+		sb.WriteString("\nVerification:\n")
+		for _, c := range p.Verification {
+			fmt.Fprintf(&sb, "  [%s] %s\n", c.Status, c.Run)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/apexion-ai/apexion/internal/config"
+	"github.com/apexion-ai/apexion/internal/permission"
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/session"
+	"github.com/apexion-ai/apexion/internal/tools"
+)
+
+const planScript = `
+turns:
+  - tool_calls:
+      - name: read_file
+        input: {file_path: status.txt}
+  - text: "1. Replace broken with fixed in status.txt. 2. Write notes.txt. Verify with test -f status.txt."
+  - expect:
+      user_contains: "Replace broken with fixed"
+    text: |
+      {"summary": "Fix the status", "steps": [
+        {"description": "Mark the status fixed", "files": ["status.txt"], "details": "", "verify": "grep -q fixed status.txt"},
+        {"description": "Write notes", "files": ["notes.txt"], "details": "", "verify": "test -f notes.txt"}
+      ], "verification": ["test -f status.txt"]}
+  - expect:
+      user_contains: "Carry out step 1 of 2"
+    tool_calls:
+      - name: read_file
+        input: {file_path: status.txt}
+  - tool_calls:
+      - name: edit_file
+        input: {file_path: status.txt, old_string: "broken", new_string: "fixed"}
+  - text: "Status fixed."
+  - expect:
+      user_contains: "Carry out step 2 of 2"
+    text: "Nothing to do."
+  - expect:
+      user_contains: "Carry out step 2 of 2"
+    tool_calls:
+      - name: read_file
+        input: {file_path: status.txt}
+  - tool_calls:
+      - name: write_file
+        input: {file_path: notes.txt, content: "notes\n"}
+  - text: "Notes written."
+`
+
+func TestPlan_CaptureAndExecute(t *testing.T) {
+	p, err := provider.ParseScript([]byte(planScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	if err := os.WriteFile("status.txt", []byte("broken\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Provider = "scripted"
+	cfg.RepoMap.Disabled = true
+	ui := &scenarioIO{}
+	executor := tools.NewExecutor(tools.DefaultRegistry(nil, nil), permission.AllowAllPolicy{})
+	a := New(p, executor, cfg, ui, session.NullStore{})
+
+	a.handlePlan(t.Context(), "")
+	if err := a.RunOnce(t.Context(), "Plan the status fix."); err != nil {
+		t.Fatal(err)
+	}
+	plan := a.currentPlan()
+	if plan == nil || len(plan.Steps) != 2 || plan.Steps[0].Verify != "grep -q fixed status.txt" ||
+		len(plan.Verification) != 1 || plan.Status() != session.PlanPending {
+		t.Fatalf("unexpected captured plan: %+v", plan)
+	}
+
+	// Step 2's check fails: execution stops there.
+	a.handlePlan(t.Context(), "execute")
+	if a.planMode {
+		t.Error("plan mode still on after /plan execute")
+	}
+	if s := plan.Steps; s[0].Status != session.PlanDone || s[1].Status != session.PlanFailed ||
+		!strings.Contains(s[1].Note, "test -f notes.txt") {
+		t.Fatalf("unexpected step statuses: %+v", s)
+	}
+
+	// Running it again resumes at the failed step.
+	a.handlePlan(t.Context(), "execute")
+	if plan.Status() != session.PlanDone || plan.Verification[0].Status != session.PlanDone {
+		t.Errorf("plan not complete: %+v", plan)
+	}
+	if n := p.Remaining(); n > 0 {
+		t.Errorf("%d scripted turn(s) were never requested", n)
+	}
+	if all := strings.Join(ui.system, "\n"); !strings.Contains(all, "Plan complete: 2 step(s) done.") {
+		t.Errorf("missing completion message; got:\n%s", all)
+	}
+}
+
+func TestPlan_StepStoppedEarlyFails(t *testing.T) {
+	p, err := provider.ParseScript([]byte(`
+turns:
+  - expect:
+      user_contains: "Carry out step 1 of 2"
+    tool_calls:
+      - name: read_file
+        input: {file_path: status.txt}
+  - tool_calls:
+      - name: read_file
+        input: {file_path: notes.txt}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := config.DefaultConfig()
+	cfg.Provider = "scripted"
+	cfg.RepoMap.Disabled = true
+	cfg.MaxIterations = 2
+	executor := tools.NewExecutor(tools.DefaultRegistry(nil, nil), permission.AllowAllPolicy{})
+	a := New(p, executor, cfg, &scenarioIO{}, session.NullStore{})
+	a.savePlan(&session.Plan{Steps: []session.PlanStep{
+		{Description: "Fix the status", Status: session.PlanPending},
+		{Description: "Write notes", Status: session.PlanPending},
+	}})
+
+	a.handlePlan(t.Context(), "execute")
+	s := a.currentPlan().Steps
+	if s[0].Status != session.PlanFailed || !strings.Contains(s[0].Note, "max iterations") {
+		t.Errorf("step cut off by the iteration cap should fail, got %+v", s[0])
+	}
+	if s[1].Status != session.PlanPending {
+		t.Errorf("later step should not run, got %+v", s[1])
+	}
+}
+
+// denyBashPolicy blocks shell commands and allows everything else.
+type denyBashPolicy struct{}
+
+func (denyBashPolicy) Check(name string, _ json.RawMessage) permission.Decision {
+	if name == "bash" {
+		return permission.Deny
+	}
+	return permission.Allow
+}
+
+func TestPlan_ChecksGoThroughPermissionPolicy(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := config.DefaultConfig()
+	cfg.Provider = "scripted"
+	cfg.RepoMap.Disabled = true
+	executor := tools.NewExecutor(tools.DefaultRegistry(nil, nil), denyBashPolicy{})
+	a := New(&provider.ScriptedProvider{}, executor, cfg, &scenarioIO{}, session.NullStore{})
+	a.savePlan(&session.Plan{
+		Steps:        []session.PlanStep{{Description: "Already done", Status: session.PlanDone}},
+		Verification: []session.PlanCheck{{Run: "touch ran.txt", Status: session.PlanPending}},
+	})
+
+	a.handlePlan(t.Context(), "execute")
+	if _, err := os.Stat("ran.txt"); err == nil {
+		t.Error("a plan check denied by the policy still ran")
+	}
+	if c := a.currentPlan().Verification[0]; c.Status != session.PlanFailed {
+		t.Errorf("denied check should fail, got %+v", c)
+	}
+}
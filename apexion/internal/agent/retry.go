@@ -2,11 +2,11 @@ package agent
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"math/rand/v2"
-	"strings"
 	"time"
+
+	"github.com/apexion-ai/apexion/internal/provider"
 )
 
 const (
@@ -18,38 +18,7 @@ const (
 
 // isRetryableError checks if an error is worth retrying (rate limit, server error, network).
 func isRetryableError(err error) bool {
-	if err == nil {
-		return false
-	}
-	msg := err.Error()
-
-	// Rate limit (429)
-	if strings.Contains(msg, "429") || strings.Contains(msg, "rate limit") || strings.Contains(msg, "rate_limit") {
-		return true
-	}
-	// Anthropic overloaded (529)
-	if strings.Contains(msg, "529") || strings.Contains(msg, "overloaded") {
-		return true
-	}
-	// Server errors (500, 502, 503, 504)
-	for _, code := range []string{"500", "502", "503", "504"} {
-		if strings.Contains(msg, code) {
-			return true
-		}
-	}
-	// Network errors
-	if strings.Contains(msg, "connection refused") ||
-		strings.Contains(msg, "connection reset") ||
-		strings.Contains(msg, "timeout") ||
-		strings.Contains(msg, "EOF") ||
-		strings.Contains(msg, "temporary failure") {
-		return true
-	}
-	// Context cancelled is NOT retryable
-	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-		return false
-	}
-	return false
+	return provider.IsRetryableError(err)
 }
 
 // retryDelay returns the delay for attempt n (0-indexed) with jitter.
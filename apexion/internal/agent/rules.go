@@ -2,9 +2,14 @@ package agent
 
 import (
 	"bufio"
+	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Rule represents a single rule loaded from .apexion/rules/*.md.
@@ -167,3 +172,64 @@ func parseFrontmatter(r *Rule, fm string) {
 		}
 	}
 }
+
+// CheckRules validates the frontmatter of every rule file loadRules would
+// read and returns the number of files checked. parseFrontmatter is
+// lenient, so a malformed header otherwise just loses its settings.
+func CheckRules(cwd string) (int, []error) {
+	return checkFrontmatterFiles(ruleDirs(cwd, findGitRoot(cwd)), func(fm string) error {
+		var meta struct {
+			Description  string   `yaml:"description"`
+			PathPatterns []string `yaml:"path_patterns"`
+		}
+		dec := yaml.NewDecoder(strings.NewReader(fm))
+		dec.KnownFields(true)
+		if err := dec.Decode(&meta); err != nil && err != io.EOF {
+			return err
+		}
+		for _, p := range meta.PathPatterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid path pattern %q", p)
+			}
+		}
+		return nil
+	})
+}
+
+// checkFrontmatterFiles runs check on the frontmatter of each *.md file in
+// dirs. A file opening with "---" but never closing it is an error.
+func checkFrontmatterFiles(dirs []string, check func(fm string) error) (int, []error) {
+	var n int
+	var errs []error
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+				continue
+			}
+			file := filepath.Join(dir, e.Name())
+			data, err := os.ReadFile(file)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			n++
+			content := strings.TrimSpace(string(data))
+			if !strings.HasPrefix(content, "---") {
+				continue
+			}
+			end := strings.Index(content[3:], "---")
+			if end < 0 {
+				errs = append(errs, fmt.Errorf("%s: frontmatter is not closed with ---", file))
+				continue
+			}
+			if err := check(content[3 : 3+end]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: frontmatter: %w", file, err))
+			}
+		}
+	}
+	return n, errs
+}
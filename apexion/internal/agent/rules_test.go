@@ -3,6 +3,7 @@ package agent
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -132,3 +133,43 @@ func TestLoadRulesSkipsEmptyFiles(t *testing.T) {
 		t.Fatalf("expected 0 rules for empty files, got %d", len(rules))
 	}
 }
+
+func TestCheckRules(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	rulesDir := filepath.Join(dir, ".apexion", "rules")
+	os.MkdirAll(rulesDir, 0755)
+
+	files := map[string]string{
+		"ok.md":       "---\ndescription: Go style\npath_patterns:\n  - \"*.go\"\n---\nUse gofmt.",
+		"plain.md":    "No frontmatter at all.",
+		"scalar.md":   "---\npath_patterns: \"*.go\"\n---\nBody",
+		"typo.md":     "---\ndescripton: misspelled\n---\nBody",
+		"badglob.md":  "---\npath_patterns:\n  - \"src/[\"\n---\nBody",
+		"unclosed.md": "---\ndescription: never closed\nBody",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(rulesDir, name), []byte(content), 0644)
+	}
+
+	n, errs := CheckRules(dir)
+	if n != len(files) {
+		t.Errorf("expected %d files checked, got %d", len(files), n)
+	}
+	bad := map[string]bool{}
+	for _, err := range errs {
+		for name := range files {
+			if strings.Contains(err.Error(), name) {
+				bad[name] = true
+			}
+		}
+	}
+	for _, name := range []string{"scalar.md", "typo.md", "badglob.md", "unclosed.md"} {
+		if !bad[name] {
+			t.Errorf("expected a problem for %s, got %v", name, errs)
+		}
+	}
+	if bad["ok.md"] || bad["plain.md"] || len(errs) != 4 {
+		t.Errorf("unexpected problems: %v", errs)
+	}
+}
@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apexion-ai/apexion/internal/config"
+	"github.com/apexion-ai/apexion/internal/permission"
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/session"
+	"github.com/apexion-ai/apexion/internal/tools"
+	"gopkg.in/yaml.v3"
+)
+
+// scenario is the harness part of a testdata/scenarios/*.yaml file; the
+// "turns" in the same file are played by provider.ScriptedProvider.
+type scenario struct {
+	Prompt             string                  `yaml:"prompt"`
+	Files              map[string]string       `yaml:"files"`      // written to the work dir first
+	WantFiles          map[string]string       `yaml:"want_files"` // file -> substring after the run
+	WantSystemMessages []string                `yaml:"want_system_messages"`
+	Steering           []string                `yaml:"steering"` // queued before the run, as if typed mid-turn
+	Verify             []config.VerifyCommand  `yaml:"verify"`   // enables the verify stage
+	Escalation         config.EscalationConfig `yaml:"escalation"`
+}
+
+// scenarioIO records what the agent shows; confirmations are auto-approved.
+type scenarioIO struct {
+	mu       sync.Mutex
+	system   []string
+	steering []string
+}
+
+func (s *scenarioIO) ReadInput() (string, error)                         { return "", nil }
+func (s *scenarioIO) UserMessage(string)                                 {}
+func (s *scenarioIO) ThinkingStart()                                     {}
+func (s *scenarioIO) TextDelta(string)                                   {}
+func (s *scenarioIO) TextDone(string)                                    {}
+func (s *scenarioIO) ToolStart(string, string, string)                   {}
+func (s *scenarioIO) ToolDone(string, string, string, bool)              {}
+func (s *scenarioIO) Confirm(string, string, tools.PermissionLevel) bool { return true }
+func (s *scenarioIO) Error(msg string)                                   { s.SystemMessage("error: " + msg) }
+func (s *scenarioIO) SetTokens(int)                                      {}
+func (s *scenarioIO) SetContextInfo(int, int)                            {}
+func (s *scenarioIO) SetPlanMode(bool)                                   {}
+func (s *scenarioIO) SetCost(float64)                                    {}
+
+func (s *scenarioIO) SystemMessage(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.system = append(s.system, text)
+}
+
+func (s *scenarioIO) DrainSteering() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msgs := s.steering
+	s.steering = nil
+	return msgs
+}
+
+func TestScenarios(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "scenarios", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no scenarios found")
+	}
+	for _, path := range paths {
+		abs, _ := filepath.Abs(path)
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".yaml"), func(t *testing.T) {
+			runScenario(t, abs)
+		})
+	}
+}
+
+func runScenario(t *testing.T, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sc scenario
+	if err := yaml.Unmarshal(data, &sc); err != nil {
+		t.Fatalf("parse scenario: %v", err)
+	}
+	p, err := provider.ParseScript(data)
+	if err != nil {
+		t.Fatalf("parse script: %v", err)
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("HOME", t.TempDir())
+	for name, content := range sc.Files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Provider = "scripted"
+	cfg.RepoMap.Disabled = true
+	if len(sc.Verify) > 0 {
+		cfg.Verify = config.VerifyConfig{Enabled: true, Commands: sc.Verify}
+	}
+	cfg.Escalation = sc.Escalation
+	ui := &scenarioIO{steering: sc.Steering}
+	executor := tools.NewExecutor(tools.DefaultRegistry(nil, nil), permission.AllowAllPolicy{})
+	a := New(p, executor, cfg, ui, session.NullStore{})
+
+	if err := a.RunOnce(t.Context(), sc.Prompt); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if n := p.Remaining(); n > 0 {
+		t.Errorf("%d scripted turn(s) were never requested", n)
+	}
+	for name, want := range sc.WantFiles {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("read %s: %v", name, err)
+			continue
+		}
+		if !strings.Contains(string(got), want) {
+			t.Errorf("%s does not contain %q:\n%s", name, want, got)
+		}
+	}
+	all := strings.Join(ui.system, "\n")
+	for _, want := range sc.WantSystemMessages {
+		if !strings.Contains(all, want) {
+			t.Errorf("missing system message %q; got:\n%s", want, all)
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package agent
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // SkillInfo describes a skill file that can be loaded by the LLM.
@@ -127,3 +130,22 @@ func extractSkillDesc(path string) string {
 	}
 	return ""
 }
+
+// CheckSkills validates the frontmatter of every skill file loadSkills
+// would list and returns the number of files checked. Skills may carry
+// extra keys (for example from other tools), so only the YAML syntax and
+// the description type are checked.
+func CheckSkills(cwd string) (int, []error) {
+	return checkFrontmatterFiles(skillDirs(cwd, findGitRoot(cwd)), func(fm string) error {
+		var meta map[string]any
+		if err := yaml.Unmarshal([]byte(fm), &meta); err != nil {
+			return err
+		}
+		if d, ok := meta["description"]; ok {
+			if _, isString := d.(string); !isString {
+				return fmt.Errorf("description must be a string")
+			}
+		}
+		return nil
+	})
+}
@@ -113,3 +113,20 @@ func TestLoadSkillsEmpty(t *testing.T) {
 		t.Fatalf("expected 0 skills, got %d", len(skills))
 	}
 }
+
+func TestCheckSkills(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	skillsDir := filepath.Join(dir, ".apexion", "skills")
+	os.MkdirAll(skillsDir, 0755)
+	os.WriteFile(filepath.Join(skillsDir, "deploy.md"), []byte("---\nname: deploy\ndescription: Ship it\nallowed-tools: [bash]\n---\nSteps"), 0644)
+	os.WriteFile(filepath.Join(skillsDir, "broken.md"), []byte("---\ndescription: [unterminated\n---\nSteps"), 0644)
+
+	n, errs := CheckSkills(dir)
+	if n != 2 {
+		t.Errorf("expected 2 skills checked, got %d", n)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken.md") {
+		t.Errorf("expected one problem for broken.md, got %v", errs)
+	}
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/apexion-ai/apexion/internal/permission"
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/tools"
+)
+
+// noSpeculate lists read-only tools that must still wait for the full
+// response: question prompts the user and task starts a paid sub-agent.
+var noSpeculate = map[string]bool{
+	"question": true,
+	"task":     true,
+}
+
+// speculation runs read-only tool calls as soon as their call event arrives,
+// while the rest of the response is still streaming. executeToolCalls picks
+// the results up in call order; stop cancels whatever was not picked up.
+type speculation struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	runs map[string]*speculativeRun // by tool call ID
+}
+
+type speculativeRun struct {
+	done         chan struct{}
+	result       tools.ToolResult
+	executedName string
+	latency      time.Duration
+}
+
+func newSpeculation(ctx context.Context) *speculation {
+	ctx, cancel := context.WithCancel(ctx)
+	return &speculation{ctx: ctx, cancel: cancel, runs: make(map[string]*speculativeRun)}
+}
+
+// stop cancels in-flight runs, waits for them and drops all results.
+// It is safe to call on a nil or already stopped speculation.
+func (s *speculation) stop() {
+	if s == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+	s.mu.Lock()
+	clear(s.runs)
+	s.mu.Unlock()
+}
+
+// take waits for the speculative run of call and returns its result. ok is
+// false if call was not started speculatively.
+func (s *speculation) take(call *provider.ToolCallRequest) (run *speculativeRun, ok bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	run, ok = s.runs[call.ID]
+	delete(s.runs, call.ID)
+	s.mu.Unlock()
+	if ok {
+		<-run.done
+	}
+	return run, ok
+}
+
+// speculate starts call in the background if it can safely run before the
+// response is complete: a read-only tool that the permission policy allows
+// without asking, that the first-step policy does not block, and that does
+// not have to wait for one of the prior calls of the same response.
+func (a *Agent) speculate(s *speculation, prior []*provider.ToolCallRequest, call *provider.ToolCallRequest) {
+	if s == nil || call.ID == "" || noSpeculate[call.Name] {
+		return
+	}
+	t, ok := a.executor.Registry().Get(call.Name)
+	if !ok || !t.IsReadOnly() {
+		return
+	}
+	if _, blocked := a.blockByFirstStepPolicy(call.Name); blocked {
+		return
+	}
+	if a.executor.Policy().Check(call.Name, call.Input) != permission.Allow {
+		return
+	}
+	if a.executor.Conflicts(schedulerCalls(prior), tools.Call{Name: call.Name, Params: call.Input}) {
+		return
+	}
+
+	s.mu.Lock()
+	if _, dup := s.runs[call.ID]; dup {
+		s.mu.Unlock()
+		return
+	}
+	run := &speculativeRun{done: make(chan struct{})}
+	s.runs[call.ID] = run
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(run.done)
+		started := time.Now()
+		run.result, run.executedName, _ = a.executeToolWithRepair(s.ctx, call)
+		run.latency = time.Since(started)
+	}()
+}
+
+// runToolCall returns the speculative result for call if there is one, and
+// otherwise executes it now.
+func (a *Agent) runToolCall(ctx context.Context, call *provider.ToolCallRequest, spec *speculation) (tools.ToolResult, string, int64) {
+	if run, ok := spec.take(call); ok {
+		return run.result, run.executedName, run.latency.Milliseconds()
+	}
+	started := time.Now()
+	result, executedName, _ := a.executeToolWithRepair(ctx, call)
+	return result, executedName, time.Since(started).Milliseconds()
+}
+
+// schedulerCalls converts calls for the executor's scheduler.
+func schedulerCalls(calls []*provider.ToolCallRequest) []tools.Call {
+	out := make([]tools.Call, len(calls))
+	for i, c := range calls {
+		out[i] = tools.Call{Name: c.Name, Params: c.Input}
+	}
+	return out
+}
@@ -0,0 +1,198 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apexion-ai/apexion/internal/config"
+	"github.com/apexion-ai/apexion/internal/permission"
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/session"
+	"github.com/apexion-ai/apexion/internal/tools"
+)
+
+// probeTool records its runs and closes started on the first one.
+type probeTool struct {
+	name     string
+	readOnly bool
+	started  chan struct{}
+	runs     atomic.Int32
+	block    bool // wait for cancellation
+}
+
+func (t *probeTool) Name() string                           { return t.name }
+func (t *probeTool) Description() string                    { return "probe" }
+func (t *probeTool) Parameters() map[string]any             { return map[string]any{} }
+func (t *probeTool) IsReadOnly() bool                       { return t.readOnly }
+func (t *probeTool) PermissionLevel() tools.PermissionLevel { return tools.PermissionRead }
+func (t *probeTool) Execute(ctx context.Context, _ json.RawMessage) (tools.ToolResult, error) {
+	if t.runs.Add(1) == 1 {
+		close(t.started)
+	}
+	if t.block {
+		<-ctx.Done()
+		return tools.ToolResult{}, ctx.Err()
+	}
+	return tools.ToolResult{Content: t.name + " ok"}, nil
+}
+
+// gatedProvider streams a read-only and a write tool call and holds back the
+// end of the response until the read-only one has started (or a timeout passes).
+type gatedProvider struct {
+	reader     *probeTool
+	calls      int
+	earlyStart bool
+}
+
+func (p *gatedProvider) Name() string         { return "gated" }
+func (p *gatedProvider) Models() []string     { return nil }
+func (p *gatedProvider) DefaultModel() string { return "gated" }
+func (p *gatedProvider) ContextWindow() int   { return 100000 }
+
+func (p *gatedProvider) Chat(ctx context.Context, req *provider.ChatRequest) (<-chan provider.Event, error) {
+	p.calls++
+	ch := make(chan provider.Event, 4)
+	if p.calls > 1 {
+		ch <- provider.Event{Type: provider.EventTextDelta, TextDelta: "done"}
+		ch <- provider.Event{Type: provider.EventDone, Usage: &provider.Usage{}}
+		close(ch)
+		return ch, nil
+	}
+	go func() {
+		defer close(ch)
+		ch <- provider.Event{Type: provider.EventToolCallDone, ToolCall: &provider.ToolCallRequest{ID: "c1", Name: "probe_read", Input: json.RawMessage(`{}`)}}
+		ch <- provider.Event{Type: provider.EventToolCallDone, ToolCall: &provider.ToolCallRequest{ID: "c2", Name: "probe_write", Input: json.RawMessage(`{}`)}}
+		select {
+		case <-p.reader.started:
+			p.earlyStart = true
+		case <-time.After(5 * time.Second):
+		}
+		ch <- provider.Event{Type: provider.EventDone, Usage: &provider.Usage{}, StopReason: provider.StopToolUse}
+	}()
+	return ch, nil
+}
+
+func TestSpeculation_StartsReadOnlyToolsWhileStreaming(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	reader := &probeTool{name: "probe_read", readOnly: true, started: make(chan struct{})}
+	writer := &probeTool{name: "probe_write", started: make(chan struct{})}
+	reg := tools.NewRegistry()
+	reg.Register(reader)
+	reg.Register(writer)
+
+	cfg := config.DefaultConfig()
+	cfg.RepoMap.Disabled = true
+	p := &gatedProvider{reader: reader}
+	a := New(p, tools.NewExecutor(reg, permission.AllowAllPolicy{}), cfg, &scenarioIO{}, session.NullStore{})
+
+	if err := a.RunOnce(t.Context(), "look"); err != nil {
+		t.Fatal(err)
+	}
+	if !p.earlyStart {
+		t.Error("read-only tool did not start before the response finished")
+	}
+	if n := reader.runs.Load(); n != 1 {
+		t.Errorf("read-only tool ran %d times, want 1", n)
+	}
+	if n := writer.runs.Load(); n != 1 {
+		t.Errorf("write tool ran %d times, want 1", n)
+	}
+
+	var results []provider.Content
+	for _, m := range a.session.Messages {
+		for _, c := range m.Content {
+			if c.Type == provider.ContentTypeToolResult {
+				results = append(results, c)
+			}
+		}
+	}
+	if len(results) != 2 || results[0].ToolResult != "probe_read ok" || results[1].ToolUseID != "c2" {
+		t.Errorf("tool results out of call order: %+v", results)
+	}
+}
+
+func TestSpeculation_StopCancelsInFlight(t *testing.T) {
+	slow := &probeTool{name: "probe_read", readOnly: true, started: make(chan struct{}), block: true}
+	reg := tools.NewRegistry()
+	reg.Register(slow)
+	a := &Agent{
+		config:           config.DefaultConfig(),
+		executor:         tools.NewExecutor(reg, permission.AllowAllPolicy{}),
+		toolHealth:       make(map[string]*toolHealthState),
+		firstStepAllowed: make(map[string]bool),
+	}
+
+	spec := newSpeculation(t.Context())
+	call := &provider.ToolCallRequest{ID: "c1", Name: "probe_read", Input: json.RawMessage(`{}`)}
+	a.speculate(spec, nil, call)
+	<-slow.started
+
+	stopped := make(chan struct{})
+	go func() {
+		spec.stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop did not cancel the running tool")
+	}
+	if _, ok := spec.take(call); ok {
+		t.Error("a stopped speculation should not return results")
+	}
+}
+
+func TestSpeculation_SkipsToolsNeedingConfirmation(t *testing.T) {
+	reader := &probeTool{name: "probe_read", readOnly: true, started: make(chan struct{})}
+	reg := tools.NewRegistry()
+	reg.Register(reader)
+	// Not in auto_approve_tools, so the interactive policy asks first.
+	policy := permission.NewDefaultPolicy(&config.PermissionConfig{Mode: "interactive"})
+	a := &Agent{
+		executor:         tools.NewExecutor(reg, policy),
+		firstStepAllowed: make(map[string]bool),
+	}
+	spec := newSpeculation(t.Context())
+	defer spec.stop()
+
+	call := &provider.ToolCallRequest{ID: "c1", Name: "probe_read", Input: json.RawMessage(`{}`)}
+	a.speculate(spec, nil, call)
+	if _, ok := spec.take(call); ok {
+		t.Error("a call that needs confirmation should not run speculatively")
+	}
+	if n := reader.runs.Load(); n != 0 {
+		t.Errorf("tool ran %d times, want 0", n)
+	}
+}
+
+func TestSpeculation_SkipsReadAfterConflictingWrite(t *testing.T) {
+	t.Chdir(t.TempDir())
+	reg := tools.NewRegistry()
+	reg.Register(&tools.ReadFileTool{})
+	reg.Register(&tools.WriteFileTool{})
+	a := &Agent{
+		config:           config.DefaultConfig(),
+		executor:         tools.NewExecutor(reg, permission.AllowAllPolicy{}),
+		toolHealth:       make(map[string]*toolHealthState),
+		firstStepAllowed: make(map[string]bool),
+	}
+	spec := newSpeculation(t.Context())
+	defer spec.stop()
+
+	write := &provider.ToolCallRequest{ID: "c1", Name: "write_file", Input: json.RawMessage(`{"file_path":"foo.txt","content":"new"}`)}
+	read := &provider.ToolCallRequest{ID: "c2", Name: "read_file", Input: json.RawMessage(`{"file_path":"./foo.txt"}`)}
+	other := &provider.ToolCallRequest{ID: "c3", Name: "read_file", Input: json.RawMessage(`{"file_path":"bar.txt"}`)}
+	prior := []*provider.ToolCallRequest{write}
+	a.speculate(spec, prior, read)
+	a.speculate(spec, prior, other)
+	if _, ok := spec.take(read); ok {
+		t.Error("a read of a file an earlier call writes should not run speculatively")
+	}
+	if _, ok := spec.take(other); !ok {
+		t.Error("a read of an unrelated file should run speculatively")
+	}
+}
@@ -0,0 +1,184 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/tui"
+)
+
+// thinkingStream routes the reasoning of one model response: it is shown via
+// tui.ThinkingOutput (when the IO supports it), and signed/redacted blocks are
+// kept so they can be replayed in history (required by Anthropic extended
+// thinking when tool use continues).
+//
+// Reasoning arrives either as EventThinkingDelta from the provider, or inline
+// as <think>...</think> in the text stream (MiniMax, Qwen, DeepSeek-R1 via
+// OpenAI-compatible APIs); text() separates the latter.
+type thinkingStream struct {
+	out     tui.ThinkingOutput // nil when the IO cannot render reasoning
+	tags    thinkTagFilter
+	shown   strings.Builder // reasoning displayed since the last flush
+	open    strings.Builder // text of the current (unsigned) provider block
+	blocks  []provider.Content
+	sawText bool
+}
+
+func newThinkingStream(io tui.IO) *thinkingStream {
+	out, _ := io.(tui.ThinkingOutput)
+	return &thinkingStream{out: out}
+}
+
+// event consumes an EventThinkingDelta.
+func (t *thinkingStream) event(ev provider.Event) {
+	switch {
+	case ev.ReasoningItem != nil:
+		t.blocks = append(t.blocks, *ev.ReasoningItem)
+		t.open.Reset()
+	case ev.RedactedThinking != "":
+		t.blocks = append(t.blocks, provider.Content{
+			Type: provider.ContentTypeRedactedThinking,
+			Text: ev.RedactedThinking,
+		})
+	case ev.ThinkingSignature != "":
+		t.blocks = append(t.blocks, provider.Content{
+			Type:      provider.ContentTypeThinking,
+			Text:      t.open.String(),
+			Signature: ev.ThinkingSignature,
+		})
+		t.open.Reset()
+	default:
+		t.open.WriteString(ev.ThinkingDelta)
+		t.show(ev.ThinkingDelta)
+	}
+}
+
+// text filters a text delta, diverting inline <think> content to the
+// reasoning display. It returns the visible part of the delta.
+func (t *thinkingStream) text(delta string) string {
+	visible, thought := t.tags.feed(delta)
+	if thought != "" {
+		t.show(thought)
+	}
+	return t.visible(visible)
+}
+
+// finish flushes any text held back by the tag filter and closes the
+// displayed block. It returns trailing visible text, if any.
+func (t *thinkingStream) finish() string {
+	visible, thought := t.tags.flush()
+	if thought != "" {
+		t.show(thought)
+	}
+	rest := t.visible(visible)
+	t.flush()
+	return rest
+}
+
+// flush closes the displayed reasoning block, e.g. when the answer or a tool
+// call starts.
+func (t *thinkingStream) flush() {
+	if t.shown.Len() == 0 {
+		return
+	}
+	if t.out != nil {
+		t.out.ThinkingDone(strings.TrimSpace(t.shown.String()))
+	}
+	t.shown.Reset()
+}
+
+// historyBlocks returns the reasoning blocks that must be replayed in the
+// assistant message.
+func (t *thinkingStream) historyBlocks() []provider.Content {
+	return t.blocks
+}
+
+func (t *thinkingStream) show(s string) {
+	t.shown.WriteString(s)
+	if t.out != nil {
+		t.out.ThinkingDelta(s)
+	}
+}
+
+// visible drops whitespace that separated reasoning from the answer and
+// closes the reasoning block once real text appears.
+func (t *thinkingStream) visible(s string) string {
+	if !t.sawText {
+		s = strings.TrimLeft(s, " \t\r\n")
+		if s == "" {
+			return ""
+		}
+		t.sawText = true
+	}
+	t.flush()
+	return s
+}
+
+// ── Inline <think> tag filter ────────────────────────────────────────────────
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// thinkTagFilter splits a streamed text into visible text and <think>
+// content. Tags may be split across deltas, so a trailing fragment that could
+// begin a tag is held back until the next delta.
+type thinkTagFilter struct {
+	inThink bool
+	pending string
+}
+
+// feed consumes one delta and returns the visible and thinking parts.
+func (f *thinkTagFilter) feed(delta string) (visible, thinking string) {
+	s := f.pending + delta
+	f.pending = ""
+	var vis, th strings.Builder
+	emit := func(part string) {
+		if f.inThink {
+			th.WriteString(part)
+		} else {
+			vis.WriteString(part)
+		}
+	}
+
+	for s != "" {
+		tag := thinkOpenTag
+		if f.inThink {
+			tag = thinkCloseTag
+		}
+		if i := strings.Index(s, tag); i >= 0 {
+			emit(s[:i])
+			s = s[i+len(tag):]
+			f.inThink = !f.inThink
+			continue
+		}
+		k := partialTagSuffix(s, tag)
+		emit(s[:len(s)-k])
+		f.pending = s[len(s)-k:]
+		break
+	}
+	return vis.String(), th.String()
+}
+
+// flush returns whatever was held back. An unterminated <think> block stays
+// reasoning (e.g. the stream was cut off mid-thought).
+func (f *thinkTagFilter) flush() (visible, thinking string) {
+	s := f.pending
+	f.pending = ""
+	if f.inThink {
+		return "", s
+	}
+	return s, ""
+}
+
+// partialTagSuffix returns the length of the longest suffix of s that is a
+// proper prefix of tag.
+func partialTagSuffix(s, tag string) int {
+	for k := min(len(tag)-1, len(s)); k > 0; k-- {
+		if strings.HasSuffix(s, tag[:k]) {
+			return k
+		}
+	}
+	return 0
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/tui"
+)
+
+func TestThinkTagFilter_SplitAcrossDeltas(t *testing.T) {
+	tests := []struct {
+		name         string
+		deltas       []string
+		wantVisible  string
+		wantThinking string
+	}{
+		{"no tags", []string{"hello ", "world"}, "hello world", ""},
+		{"whole block", []string{"<think>plan</think>answer"}, "answer", "plan"},
+		{"split open tag", []string{"<thi", "nk>plan</think>ok"}, "ok", "plan"},
+		{"split close tag", []string{"<think>pl", "an</th", "ink>ok"}, "ok", "plan"},
+		{"unterminated", []string{"<think>cut off"}, "", "cut off"},
+		{"lone angle bracket", []string{"a <", " b"}, "a < b", ""},
+	}
+	for _, tt := range tests {
+		var f thinkTagFilter
+		var vis, th strings.Builder
+		for _, d := range tt.deltas {
+			v, x := f.feed(d)
+			vis.WriteString(v)
+			th.WriteString(x)
+		}
+		v, x := f.flush()
+		vis.WriteString(v)
+		th.WriteString(x)
+		if vis.String() != tt.wantVisible || th.String() != tt.wantThinking {
+			t.Errorf("%s: got visible=%q thinking=%q, want %q / %q",
+				tt.name, vis.String(), th.String(), tt.wantVisible, tt.wantThinking)
+		}
+	}
+}
+
+// thinkingRecorder is a BufferIO that also records reasoning output.
+type thinkingRecorder struct {
+	*tui.BufferIO
+	deltas []string
+	done   []string
+}
+
+func (r *thinkingRecorder) ThinkingDelta(d string) { r.deltas = append(r.deltas, d) }
+func (r *thinkingRecorder) ThinkingDone(s string)  { r.done = append(r.done, s) }
+
+func TestThinkingStream_SignedBlocksAndDisplay(t *testing.T) {
+	rec := &thinkingRecorder{BufferIO: tui.NewBufferIO()}
+	ts := newThinkingStream(rec)
+
+	ts.event(provider.Event{Type: provider.EventThinkingDelta, ThinkingDelta: "let me "})
+	ts.event(provider.Event{Type: provider.EventThinkingDelta, ThinkingDelta: "check"})
+	ts.event(provider.Event{Type: provider.EventThinkingDelta, ThinkingSignature: "sig-1"})
+	ts.event(provider.Event{Type: provider.EventThinkingDelta, RedactedThinking: "opaque"})
+	if got := ts.text("\n\nDone."); got != "Done." {
+		t.Errorf("expected leading whitespace trimmed, got %q", got)
+	}
+	ts.finish()
+
+	if len(rec.done) != 1 || rec.done[0] != "let me check" {
+		t.Errorf("expected one displayed block, got %v", rec.done)
+	}
+	blocks := ts.historyBlocks()
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 history blocks, got %d", len(blocks))
+	}
+	if blocks[0].Type != provider.ContentTypeThinking || blocks[0].Text != "let me check" || blocks[0].Signature != "sig-1" {
+		t.Errorf("unexpected signed block %+v", blocks[0])
+	}
+	if blocks[1].Type != provider.ContentTypeRedactedThinking || blocks[1].Text != "opaque" {
+		t.Errorf("unexpected redacted block %+v", blocks[1])
+	}
+}
+
+func TestThinkingStream_InlineTagsNotPersisted(t *testing.T) {
+	rec := &thinkingRecorder{BufferIO: tui.NewBufferIO()}
+	ts := newThinkingStream(rec)
+
+	var visible strings.Builder
+	for _, d := range []string{"<think>hmm", "</think>\n", "Answer"} {
+		visible.WriteString(ts.text(d))
+	}
+	visible.WriteString(ts.finish())
+
+	if visible.String() != "Answer" {
+		t.Errorf("expected visible 'Answer', got %q", visible.String())
+	}
+	if len(rec.done) != 1 || rec.done[0] != "hmm" {
+		t.Errorf("expected inline reasoning displayed, got %v", rec.done)
+	}
+	if len(ts.historyBlocks()) != 0 {
+		t.Error("unsigned inline reasoning should not be kept for history")
+	}
+}
+
+func TestThinkingStream_PlainIOWithoutThinkingOutput(t *testing.T) {
+	ts := newThinkingStream(tui.NewBufferIO())
+	ts.event(provider.Event{Type: provider.EventThinkingDelta, ThinkingDelta: "x"})
+	if got := ts.text("y"); got != "y" {
+		t.Errorf("expected visible text passthrough, got %q", got)
+	}
+}
+
+func TestThinkingStream_ReasoningItemsKept(t *testing.T) {
+	rec := &thinkingRecorder{BufferIO: tui.NewBufferIO()}
+	ts := newThinkingStream(rec)
+
+	ts.event(provider.Event{Type: provider.EventThinkingDelta, ThinkingDelta: "plan the fix"})
+	ts.event(provider.Event{Type: provider.EventThinkingDelta, ReasoningItem: &provider.Content{
+		Type: provider.ContentTypeReasoning, ReasoningID: "rs_1", Text: "plan the fix", Signature: "enc",
+	}})
+	ts.finish()
+
+	if len(rec.done) != 1 || rec.done[0] != "plan the fix" {
+		t.Errorf("expected the summary displayed once, got %v", rec.done)
+	}
+	blocks := ts.historyBlocks()
+	if len(blocks) != 1 || blocks[0].Type != provider.ContentTypeReasoning || blocks[0].ReasoningID != "rs_1" || blocks[0].Signature != "enc" {
+		t.Errorf("unexpected history blocks %+v", blocks)
+	}
+}
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/tools"
+)
+
+// verifyState tracks the verify stage within one turn.
+type verifyState struct {
+	since  time.Time // file changes at or after this trigger a run
+	shell  bool      // bash ran since the last run and may have changed files
+	rounds int       // failures fed back to the model so far
+}
+
+// noteCalls records calls that can change files without the file tracker
+// seeing it.
+func (vs *verifyState) noteCalls(calls []*provider.ToolCallRequest) {
+	for _, c := range calls {
+		if c.Name == "bash" {
+			vs.shell = true
+		}
+	}
+}
+
+// verifyTurn runs the verify commands when the model has stopped calling
+// tools and files changed, or bash ran, since the turn started (or since
+// the last run).
+// It returns feedback for the model when a command failed and another
+// round is allowed; "" means the turn can end.
+func (a *Agent) verifyTurn(ctx context.Context, vs *verifyState) string {
+	if a.verifier == nil || a.subAgent || a.planMode {
+		return ""
+	}
+	if !vs.shell && len(a.executor.FileTracker().Since(vs.since)) == 0 {
+		return ""
+	}
+	vs.since, vs.shell = time.Now(), false
+
+	a.io.SystemMessage("Verifying changes...")
+	results := a.verifier.Run(ctx)
+	if ctx.Err() != nil {
+		return ""
+	}
+	summary := verifySummary(results)
+	failed := results[len(results)-1]
+	retry := !failed.Passed && vs.rounds < a.verifier.MaxRounds()
+
+	if a.eventLogger != nil {
+		passed := make([]string, 0, len(results))
+		for _, r := range results {
+			if r.Passed {
+				passed = append(passed, r.Name)
+			}
+		}
+		evt := map[string]any{"round": vs.rounds + 1, "passed": passed}
+		if !failed.Passed {
+			evt["failed"] = failed.Name
+		}
+		a.eventLogger.Log(EventVerify, evt)
+	}
+
+	if failed.Passed {
+		a.io.SystemMessage(summary)
+		return ""
+	}
+	if !retry {
+		a.io.SystemMessage(fmt.Sprintf("warning: %s — giving up after %d fix round(s)", summary, vs.rounds))
+		return ""
+	}
+	vs.rounds++
+	a.io.SystemMessage(summary + fmt.Sprintf(" — asking the model to fix it (round %d/%d)", vs.rounds, a.verifier.MaxRounds()))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[SYSTEM] Verification failed after your changes. `%s` (%s) failed:\n\n```\n%s\n```\n\n",
+		failed.Command, failed.Name, failed.Output)
+	if len(results) > 1 {
+		names := make([]string, 0, len(results)-1)
+		for _, r := range results[:len(results)-1] {
+			names = append(names, r.Name)
+		}
+		fmt.Fprintf(&sb, "These passed: %s. ", strings.Join(names, ", "))
+	}
+	sb.WriteString("Fix the failure. Verification runs again when you stop calling tools.")
+	return sb.String()
+}
+
+// verifySummary describes a verify run, e.g.
+// "Verify: build ok (1.2s), vet ok (0.4s), test FAILED (3.1s)".
+func verifySummary(results []tools.VerifyResult) string {
+	parts := make([]string, len(results))
+	for i, r := range results {
+		status := "ok"
+		if !r.Passed {
+			status = "FAILED"
+		}
+		parts[i] = fmt.Sprintf("%s %s (%s)", r.Name, status, r.Duration.Round(100*time.Millisecond))
+	}
+	return "Verify: " + strings.Join(parts, ", ")
+}
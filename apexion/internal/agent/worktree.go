@@ -0,0 +1,265 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// worktreeResult is the outcome of a sub-agent run in an isolated git
+// worktree: a scratch branch holding its changes, waiting to be merged or
+// discarded with /bg merge or /bg discard.
+type worktreeResult struct {
+	ID     string
+	Branch string
+	Stat   string // git diff --stat against the commit the run started from
+}
+
+// worktreeResults tracks unmerged worktree results. The zero value is ready
+// to use.
+type worktreeResults struct {
+	mu      sync.Mutex
+	results map[string]*worktreeResult
+	counter int
+}
+
+// nextID returns an ID for an isolated run that is not a background agent.
+func (w *worktreeResults) nextID() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.counter++
+	return fmt.Sprintf("task-%d", w.counter)
+}
+
+func (w *worktreeResults) add(r *worktreeResult) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.results == nil {
+		w.results = make(map[string]*worktreeResult)
+	}
+	w.results[r.ID] = r
+}
+
+func (w *worktreeResults) get(id string) (*worktreeResult, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r, ok := w.results[id]
+	return r, ok
+}
+
+func (w *worktreeResults) remove(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.results, id)
+}
+
+// summary lists the unmerged results, or returns "" if there are none.
+func (w *worktreeResults) summary() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.results) == 0 {
+		return ""
+	}
+	ids := make([]string, 0, len(w.results))
+	for id := range w.results {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var sb strings.Builder
+	sb.WriteString("Unmerged worktree results (/bg merge <id> or /bg discard <id>):")
+	for _, id := range ids {
+		r := w.results[id]
+		fmt.Fprintf(&sb, "\n  %s  %s  %s", id, r.Branch, statTotals(r.Stat))
+	}
+	return sb.String()
+}
+
+// statTotals returns the summary line of git diff --stat output.
+func statTotals(stat string) string {
+	lines := strings.Split(strings.TrimSpace(stat), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// worktree is a temporary git worktree a sub-agent runs in.
+type worktree struct {
+	id     string
+	repo   string // main working tree root
+	dir    string // worktree root
+	branch string
+	base   string // commit the worktree was created from
+}
+
+// bgIDKey carries the background agent ID to the sub-agent runner, so that
+// its worktree result can be merged under the same ID.
+type bgIDKey struct{}
+
+// sharedTreeKey marks sub-agents that must work in the main working tree
+// even with isolation on: architect steps build on the edits of the steps
+// before them, which an unmerged branch would hide.
+type sharedTreeKey struct{}
+
+// isolateSubAgent reports whether a sub-agent of the given mode runs in its
+// own worktree.
+func (a *Agent) isolateSubAgent(ctx context.Context, mode string, def *AgentDef) bool {
+	if a.config.SubAgentIsolation != "worktree" {
+		return false
+	}
+	if shared, _ := ctx.Value(sharedTreeKey{}).(bool); shared {
+		return false
+	}
+	if def != nil {
+		return !a.agentDefReadOnly(def)
+	}
+	return mode == "code"
+}
+
+// newWorktree creates a worktree of HEAD on a new scratch branch.
+func (a *Agent) newWorktree(ctx context.Context) (*worktree, error) {
+	cwd, _ := os.Getwd()
+	repo := findGitRoot(cwd)
+	if repo == "" {
+		return nil, errors.New("worktree isolation needs a git repository")
+	}
+	id, _ := ctx.Value(bgIDKey{}).(string)
+	if id == "" {
+		id = a.worktrees.nextID()
+	}
+	// The worktree starts from HEAD; uncommitted edits would be missing in
+	// it and could collide with the result when it is merged.
+	if err := checkCleanTree(ctx, repo); err != nil {
+		return nil, err
+	}
+	base, err := gitIn(ctx, repo, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "apexion-worktree-")
+	if err != nil {
+		return nil, err
+	}
+	branch := fmt.Sprintf("apexion/%s-%s", id, time.Now().Format("20060102-150405"))
+	if _, err := gitIn(ctx, repo, "worktree", "add", "-q", "-b", branch, dir, base); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &worktree{id: id, repo: repo, dir: dir, branch: branch, base: base}, nil
+}
+
+// root returns the directory in the worktree that corresponds to the
+// process working directory, so relative paths keep their meaning.
+func (wt *worktree) root(cwd string) string {
+	rel, err := filepath.Rel(wt.repo, cwd)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return wt.dir
+	}
+	return filepath.Join(wt.dir, rel)
+}
+
+// finishWorktree commits what the sub-agent left in the worktree, removes the
+// worktree and returns a summary for the task result. The branch is kept
+// until the result is merged or discarded; a run without changes deletes
+// it right away.
+func (a *Agent) finishWorktree(wt *worktree, prompt string) string {
+	// The run's context may already be cancelled; cleanup must still happen.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var commitErr error
+	if status, err := gitIn(ctx, wt.dir, "status", "--porcelain"); err == nil && status != "" {
+		if _, commitErr = gitIn(ctx, wt.dir, "add", "-A"); commitErr == nil {
+			_, commitErr = gitIn(ctx, wt.dir, "commit", "-q", "-m", worktreeCommitMessage(wt.id, prompt))
+		}
+	}
+	if commitErr != nil {
+		// Leave the worktree in place so nothing is lost.
+		return fmt.Sprintf("\n\n[Worktree %s: could not commit the changes: %v. They are left in %s on branch %s.]",
+			wt.id, commitErr, wt.dir, wt.branch)
+	}
+	stat, _ := gitIn(ctx, wt.dir, "diff", "--stat", wt.base, "HEAD")
+	if _, err := gitIn(ctx, wt.repo, "worktree", "remove", "--force", wt.dir); err != nil {
+		a.io.SystemMessage(fmt.Sprintf("warning: cannot remove worktree %s: %v", wt.dir, err))
+	}
+
+	if strings.TrimSpace(stat) == "" {
+		_, _ = gitIn(ctx, wt.repo, "branch", "-D", wt.branch)
+		return fmt.Sprintf("\n\n[Worktree %s: no changes]", wt.id)
+	}
+	a.worktrees.add(&worktreeResult{ID: wt.id, Branch: wt.branch, Stat: stat})
+	return fmt.Sprintf("\n\n[Worktree %s: changes committed to branch %s, not yet in the working tree]\n%s\n"+
+		"The user can merge them with /bg merge %s or drop them with /bg discard %s; "+
+		"to take single commits, run git cherry-pick on the branch.",
+		wt.id, wt.branch, strings.TrimRight(stat, "\n"), wt.id, wt.id)
+}
+
+// worktreeCommitMessage is the message of the commit holding a run's changes.
+func worktreeCommitMessage(id, prompt string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(prompt), "\n")
+	if len(line) > 60 {
+		line = line[:57] + "..."
+	}
+	return fmt.Sprintf("apexion %s: %s", id, line)
+}
+
+// mergeWorktree merges a worktree result into the current branch and
+// deletes its scratch branch.
+func (a *Agent) mergeWorktree(ctx context.Context, id string) error {
+	r, ok := a.worktrees.get(id)
+	if !ok {
+		return fmt.Errorf("no worktree result %s", id)
+	}
+	cwd, _ := os.Getwd()
+	if err := checkCleanTree(ctx, cwd); err != nil {
+		return err
+	}
+	if _, err := gitIn(ctx, cwd, "merge", "--no-edit", r.Branch); err != nil {
+		return fmt.Errorf("%w\nResolve the conflicts and commit, or run git merge --abort; the branch %s is kept", err, r.Branch)
+	}
+	_, _ = gitIn(ctx, cwd, "branch", "-D", r.Branch)
+	a.worktrees.remove(id)
+	return nil
+}
+
+// discardWorktree deletes a worktree result's scratch branch.
+func (a *Agent) discardWorktree(ctx context.Context, id string) error {
+	r, ok := a.worktrees.get(id)
+	if !ok {
+		return fmt.Errorf("no worktree result %s", id)
+	}
+	cwd, _ := os.Getwd()
+	if _, err := gitIn(ctx, cwd, "branch", "-D", r.Branch); err != nil {
+		return err
+	}
+	a.worktrees.remove(id)
+	return nil
+}
+
+// checkCleanTree returns an error if tracked files in dir's repository have
+// uncommitted changes.
+func checkCleanTree(ctx context.Context, dir string) error {
+	status, err := gitIn(ctx, dir, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return err
+	}
+	if status != "" {
+		return errors.New("the working tree has uncommitted changes; commit or stash them first")
+	}
+	return nil
+}
+
+// gitIn runs git in dir and returns its trimmed combined output.
+func gitIn(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, GitExecutable(), args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/apexion-ai/apexion/internal/config"
+	"github.com/apexion-ai/apexion/internal/permission"
+	"github.com/apexion-ai/apexion/internal/provider"
+	"github.com/apexion-ai/apexion/internal/session"
+	"github.com/apexion-ai/apexion/internal/tools"
+)
+
+const worktreeScript = `
+turns:
+  - tool_calls:
+      - name: task
+        input: {prompt: "Add notes.txt", mode: code}
+  - expect:
+      system_contains: "isolated git worktree"
+    tool_calls:
+      - name: write_file
+        input: {file_path: notes.txt, content: "notes\n"}
+  - text: "Wrote notes.txt."
+  - expect:
+      tool_results:
+        - tool: task
+          contains: "changes committed to branch apexion/task-1-"
+    text: "The sub-agent added notes.txt on a branch."
+`
+
+// initTestRepo makes the current directory a git repository with one commit.
+func initTestRepo(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	for _, kv := range [][2]string{
+		{"GIT_AUTHOR_NAME", "test"}, {"GIT_AUTHOR_EMAIL", "test@example.com"},
+		{"GIT_COMMITTER_NAME", "test"}, {"GIT_COMMITTER_EMAIL", "test@example.com"},
+	} {
+		t.Setenv(kv[0], kv[1])
+	}
+	if err := os.WriteFile("README", []byte("readme\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"add", "-A"}, {"commit", "-q", "-m", "initial"}} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", args[0], err, out)
+		}
+	}
+}
+
+func TestWorktreeIsolation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	initTestRepo(t)
+
+	run := func(t *testing.T) *Agent {
+		p, err := provider.ParseScript([]byte(worktreeScript))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := config.DefaultConfig()
+		cfg.Provider = "scripted"
+		cfg.RepoMap.Disabled = true
+		cfg.SubAgentIsolation = "worktree"
+		cfg.ToolRouting.Enabled = false // offer write_file on the first step
+		executor := tools.NewExecutor(tools.DefaultRegistry(nil, nil), permission.AllowAllPolicy{})
+		a := New(p, executor, cfg, &scenarioIO{}, session.NullStore{})
+		if err := a.RunOnce(t.Context(), "Add notes"); err != nil {
+			t.Fatal(err)
+		}
+		if n := p.Remaining(); n > 0 {
+			t.Fatalf("%d scripted turn(s) were never requested", n)
+		}
+		if _, err := os.Stat("notes.txt"); err == nil {
+			t.Fatal("the sub-agent wrote to the main working tree")
+		}
+		return a
+	}
+	branches := func() string {
+		out, _ := exec.Command("git", "branch", "--list", "apexion/*").Output()
+		return strings.TrimSpace(string(out))
+	}
+
+	a := run(t)
+	if s := a.worktrees.summary(); !strings.Contains(s, "task-1") || !strings.Contains(s, "1 file changed") {
+		t.Errorf("unexpected worktree summary: %q", s)
+	}
+	a.handleBG("merge task-1")
+	if data, err := os.ReadFile("notes.txt"); err != nil || string(data) != "notes\n" {
+		t.Errorf("notes.txt after merge = %q, %v", data, err)
+	}
+	if b := branches(); b != "" {
+		t.Errorf("scratch branch left after merge: %s", b)
+	}
+	if out, _ := exec.Command("git", "worktree", "list").Output(); strings.Count(string(out), "\n") != 1 {
+		t.Errorf("worktree not removed:\n%s", out)
+	}
+
+	if err := exec.Command("git", "reset", "-q", "--hard", "HEAD~1").Run(); err != nil {
+		t.Fatal(err)
+	}
+	a = run(t)
+	a.handleBG("discard task-1")
+	if b := branches(); b != "" {
+		t.Errorf("scratch branch left after discard: %s", b)
+	}
+	if _, err := os.Stat("notes.txt"); err == nil {
+		t.Error("discarded changes reached the working tree")
+	}
+	if s := a.worktrees.summary(); s != "" {
+		t.Errorf("results left after discard: %q", s)
+	}
+}
+
+func TestWorktreeIsolation_RefusesDirtyTree(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	initTestRepo(t)
+	if err := os.WriteFile("README", []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Provider = "scripted"
+	cfg.RepoMap.Disabled = true
+	cfg.SubAgentIsolation = "worktree"
+	executor := tools.NewExecutor(tools.DefaultRegistry(nil, nil), permission.AllowAllPolicy{})
+	a := New(&provider.ScriptedProvider{}, executor, cfg, &scenarioIO{}, session.NullStore{})
+	_, err := a.runSubAgent(t.Context(), "Add notes", "code")
+	if err == nil || !strings.Contains(err.Error(), "uncommitted changes") {
+		t.Fatalf("expected a dirty tree error, got %v", err)
+	}
+}
+
+const architectWorktreeScript = `
+turns:
+  - text: "The repository only has a README."
+  - text: |
+      {"summary": "Add notes", "steps": [
+        {"description": "Write notes.txt", "files": ["notes.txt"], "action": "create", "details": "", "depends_on": []},
+        {"description": "Append to notes.txt", "files": ["notes.txt"], "action": "modify", "details": "", "depends_on": [1]}
+      ]}
+  - tool_calls:
+      - name: write_file
+        input: {file_path: notes.txt, content: "one\n"}
+  - text: "Wrote notes.txt."
+  - tool_calls:
+      - name: read_file
+        input: {file_path: notes.txt}
+  - expect:
+      tool_results:
+        - tool: read_file
+          contains: "one"
+    tool_calls:
+      - name: write_file
+        input: {file_path: notes.txt, content: "one\ntwo\n"}
+  - text: "Appended to notes.txt."
+`
+
+func TestWorktreeIsolation_ArchitectStepsShareTree(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	initTestRepo(t)
+
+	p, err := provider.ParseScript([]byte(architectWorktreeScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Provider = "scripted"
+	cfg.RepoMap.Disabled = true
+	cfg.SubAgentIsolation = "worktree"
+	cfg.ToolRouting.Enabled = false // offer write_file on the first step
+	executor := tools.NewExecutor(tools.DefaultRegistry(nil, nil), permission.AllowAllPolicy{})
+	a := New(p, executor, cfg, &scenarioIO{}, session.NullStore{})
+
+	if err := NewArchitectMode(a, "", "", true).Run(t.Context(), "Add notes"); err != nil {
+		t.Fatal(err)
+	}
+	if n := p.Remaining(); n > 0 {
+		t.Fatalf("%d scripted turn(s) were never requested", n)
+	}
+	if data, err := os.ReadFile("notes.txt"); err != nil || string(data) != "one\ntwo\n" {
+		t.Errorf("notes.txt = %q, %v; want both steps' edits in the working tree", data, err)
+	}
+	if s := a.worktrees.summary(); s != "" {
+		t.Errorf("architect steps left worktree results: %q", s)
+	}
+}
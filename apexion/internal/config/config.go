@@ -7,9 +7,13 @@ package config
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -67,6 +71,48 @@ type ProviderConfig struct {
 	// Deny rules take precedence over allow rules.
 	// Supports exact match or glob patterns like "*-text".
 	ImageModelsDeny []string `yaml:"image_models_deny"`
+	// Fallback is an ordered list of "provider/model" pairs to fail over to
+	// when this provider is rate limited or unavailable. A bare provider
+	// name uses that provider's default model.
+	Fallback []string `yaml:"fallback"`
+	// Script is the YAML scenario played by the "scripted" provider.
+	Script string `yaml:"script"`
+	// Transport selects the API used by OpenAI-compatible providers:
+	// "chat" (Chat Completions, the default) or "responses" (Responses API,
+	// which keeps reasoning items between tool-call rounds).
+	Transport string `yaml:"transport"`
+	// RateLimit caps calls to this provider across the main agent,
+	// sub-agents and background agents. Zero values are learned from the
+	// provider's rate-limit response headers.
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	// HTTP holds network settings for reaching this provider, such as a
+	// corporate proxy or private root CA.
+	HTTP HTTPConfig `yaml:"http"`
+}
+
+// HTTPConfig holds per-provider HTTP transport settings.
+type HTTPConfig struct {
+	// Proxy is an http, https or socks5 proxy URL. Empty uses the
+	// HTTPS_PROXY / HTTP_PROXY / NO_PROXY environment variables.
+	Proxy string `yaml:"proxy"`
+	// CACert is a PEM bundle trusted in addition to the system roots.
+	CACert string `yaml:"ca_cert"`
+	// InsecureSkipVerify disables TLS certificate checks (local dev only).
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	// Headers are sent with every API request. Values expand $VAR and
+	// ${VAR} from the environment.
+	Headers map[string]string `yaml:"headers"`
+	// ConnectTimeoutSec bounds TCP connect plus TLS handshake.
+	ConnectTimeoutSec int `yaml:"connect_timeout_sec"`
+	// StreamIdleTimeoutSec aborts a response that sends nothing for this
+	// many seconds.
+	StreamIdleTimeoutSec int `yaml:"stream_idle_timeout_sec"`
+}
+
+// RateLimitConfig holds per-provider request and token budgets.
+type RateLimitConfig struct {
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	TokensPerMinute   int `yaml:"tokens_per_minute"`
 }
 
 // PermissionConfig holds permission system settings.
@@ -180,10 +226,28 @@ type Config struct {
 	// 0 = unlimited (default). Loop exits when model stops calling tools.
 	MaxIterations int `yaml:"max_iterations"`
 
+	// MaxParallelReads caps how many read-only tools run at once.
+	// 0 = tools.DefaultMaxParallelReads (8).
+	MaxParallelReads int `yaml:"max_parallel_reads"`
+
 	// ContextWindow overrides the provider's default context window size.
 	// 0 = use provider default.
 	ContextWindow int `yaml:"context_window"`
 
+	// ThinkingBudget enables extended thinking with the given token budget
+	// (Anthropic, Gemini; Ollama treats >0 as "think"). 0 = disabled.
+	// Reasoning that models emit on their own is shown regardless.
+	ThinkingBudget int `yaml:"thinking_budget"`
+
+	// MaxTokens is the output token budget for each model response.
+	// 0 = DefaultMaxTokens. A response cut off at the budget is retried
+	// with the model's known output limit, or continued in a new response.
+	MaxTokens int `yaml:"max_tokens"`
+
+	// ModelMaxTokens overrides MaxTokens per model, keyed by exact model
+	// name or glob pattern (e.g. "claude-sonnet-4*": 32000).
+	ModelMaxTokens map[string]int `yaml:"model_max_tokens"`
+
 	// Sandbox holds settings for bash tool sandboxing.
 	Sandbox SandboxConfig `yaml:"sandbox"`
 
@@ -194,12 +258,21 @@ type Config struct {
 	// Empty = use same model as main agent.
 	SubAgentModel string `yaml:"sub_agent_model"`
 
+	// SubAgentIsolation is "worktree" to run sub-agents that can modify
+	// files in a temporary git worktree on a scratch branch.
+	// Empty or "none" = they edit the working tree directly.
+	SubAgentIsolation string `yaml:"sub_agent_isolation"`
+
 	// Lint holds configuration for automatic linting after file edits.
 	Lint LintConfig `yaml:"lint"`
 
 	// Test holds configuration for the self-healing test loop.
 	Test TestConfig `yaml:"test"`
 
+	// Verify holds project-level checks run before a turn that changed
+	// files ends.
+	Verify VerifyConfig `yaml:"verify"`
+
 	// RepoMap holds configuration for repository map generation.
 	RepoMap RepoMapConfig `yaml:"repo_map"`
 
@@ -211,6 +284,40 @@ type Config struct {
 
 	// AutoCheckpoint creates checkpoints before code sub-agents.
 	AutoCheckpoint bool `yaml:"auto_checkpoint"`
+
+	// Budget caps spend per turn, per session and per calendar day.
+	Budget BudgetConfig `yaml:"budget"`
+
+	// Escalation switches to stronger models when a turn gets stuck.
+	Escalation EscalationConfig `yaml:"escalation"`
+}
+
+// EscalationConfig holds the model ladder climbed when the agent is stuck:
+// a loop detector trips or tool calls keep failing. The original model is
+// restored for the next user turn.
+type EscalationConfig struct {
+	Ladder     []EscalationRung `yaml:"ladder"`      // from cheapest to strongest
+	ToolErrors int              `yaml:"tool_errors"` // consecutive failed tool calls that escalate, default 3
+}
+
+// EscalationRung is one model of the escalation ladder.
+type EscalationRung struct {
+	Provider string `yaml:"provider"` // empty = the current provider
+	Model    string `yaml:"model"`
+}
+
+// BudgetConfig holds spending and token limits. Spend of sub-agents and
+// background agents counts toward the limits of the session that started them.
+type BudgetConfig struct {
+	Turn    BudgetLimit `yaml:"turn"`    // one user prompt, until the model stops
+	Session BudgetLimit `yaml:"session"` // one session, including resumed runs
+	Daily   BudgetLimit `yaml:"daily"`   // all sessions on the local calendar day
+}
+
+// BudgetLimit is a pair of limits; zero means unlimited.
+type BudgetLimit struct {
+	MaxCost   float64 `yaml:"max_cost"`   // dollars, priced like /cost
+	MaxTokens int     `yaml:"max_tokens"` // prompt + output tokens
 }
 
 // LintConfig holds configuration for the lint-fix loop.
@@ -227,6 +334,20 @@ type TestConfig struct {
 	MaxRetries int               `yaml:"max_retries"` // max auto-fix attempts per edit, default 3
 }
 
+// VerifyConfig holds configuration for the verify stage.
+type VerifyConfig struct {
+	Enabled    bool            `yaml:"enabled"`
+	Commands   []VerifyCommand `yaml:"commands"`    // run in order; the first failure stops the run
+	MaxRounds  int             `yaml:"max_rounds"`  // max fix-and-verify rounds per turn, default 3
+	TimeoutSec int             `yaml:"timeout_sec"` // per command, default 300
+}
+
+// VerifyCommand is one named shell command of the verify stage.
+type VerifyCommand struct {
+	Name string `yaml:"name"` // e.g. "build"; defaults to the command
+	Run  string `yaml:"run"`
+}
+
 // RepoMapConfig holds configuration for repository map generation.
 type RepoMapConfig struct {
 	Disabled  bool     `yaml:"disabled"`
@@ -236,15 +357,20 @@ type RepoMapConfig struct {
 
 // ArchitectConfig holds configuration for architect mode.
 type ArchitectConfig struct {
-	ArchitectModel string `yaml:"architect_model"` // big model for planning (empty = main model)
-	CoderModel     string `yaml:"coder_model"`     // small model for execution (empty = sub_agent_model)
-	AutoExecute    bool   `yaml:"auto_execute"`    // skip per-step confirmation
+	ArchitectModel    string `yaml:"architect_model"`     // big model for planning (empty = main model)
+	CoderModel        string `yaml:"coder_model"`         // small model for execution (empty = sub_agent_model)
+	AutoExecute       bool   `yaml:"auto_execute"`        // skip per-step confirmation
+	MaxParallel       int    `yaml:"max_parallel"`        // independent steps run at once (0 = 3)
+	ContinueOnFailure bool   `yaml:"continue_on_failure"` // keep starting independent steps after a failure
 }
 
 // CostPricingEntry is a user-defined pricing override for a model.
+// Cache rates default to the input rate when unset.
 type CostPricingEntry struct {
-	InputPerMillion  float64 `yaml:"input_per_million"`
-	OutputPerMillion float64 `yaml:"output_per_million"`
+	InputPerMillion      float64 `yaml:"input_per_million"`
+	OutputPerMillion     float64 `yaml:"output_per_million"`
+	CacheWritePerMillion float64 `yaml:"cache_write_per_million"`
+	CacheReadPerMillion  float64 `yaml:"cache_read_per_million"`
 }
 
 // DefaultConfig returns the default configuration.
@@ -310,6 +436,34 @@ func Load(configPath string) (*Config, error) {
 	return cfg, nil
 }
 
+// DefaultMaxTokens is the output token budget used when none is configured.
+const DefaultMaxTokens = 8192
+
+// MaxTokensFor returns the output token budget for model: an exact
+// ModelMaxTokens entry, else the longest matching glob entry, else
+// MaxTokens, else DefaultMaxTokens.
+func (c *Config) MaxTokensFor(model string) int {
+	if n := c.ModelMaxTokens[model]; n > 0 {
+		return n
+	}
+	best, bestLen := 0, -1
+	for pattern, n := range c.ModelMaxTokens {
+		if n <= 0 || !strings.ContainsAny(pattern, "*?[") || len(pattern) <= bestLen {
+			continue
+		}
+		if ok, _ := path.Match(pattern, model); ok {
+			best, bestLen = n, len(pattern)
+		}
+	}
+	if best > 0 {
+		return best
+	}
+	if c.MaxTokens > 0 {
+		return c.MaxTokens
+	}
+	return DefaultMaxTokens
+}
+
 // GetProviderConfig returns the config for the named provider, or an empty config if not found.
 func (c *Config) GetProviderConfig(name string) *ProviderConfig {
 	if pc, ok := c.Providers[name]; ok {
@@ -318,6 +472,112 @@ func (c *Config) GetProviderConfig(name string) *ProviderConfig {
 	return &ProviderConfig{}
 }
 
+// Validate reports settings that Load accepts but that would be ignored
+// or fail later, such as unknown enum values and malformed globs. All
+// problems are joined into one error.
+func (c *Config) Validate() error {
+	var errs []error
+	bad := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	oneOf := func(field, value string, allowed ...string) {
+		if value == "" {
+			return
+		}
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		bad("%s: unknown value %q (want %s)", field, value, strings.Join(allowed, ", "))
+	}
+	globs := func(field string, patterns []string) {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				bad("%s: invalid pattern %q", field, p)
+			}
+		}
+	}
+	nonNegative := func(field string, n int) {
+		if n < 0 {
+			bad("%s: must not be negative, got %d", field, n)
+		}
+	}
+
+	if c.Provider == "" {
+		bad("provider: not set")
+	}
+	oneOf("permissions.mode", c.Permissions.Mode, "interactive", "auto-approve", "yolo")
+	oneOf("tool_routing.strategy", c.ToolRouting.Strategy, "legacy", "hybrid", "capability_v2")
+	oneOf("web.search_provider", c.Web.SearchProvider, "tavily", "exa", "jina")
+	oneOf("sub_agent_isolation", c.SubAgentIsolation, "none", "worktree")
+	nonNegative("max_iterations", c.MaxIterations)
+	nonNegative("max_parallel_reads", c.MaxParallelReads)
+	nonNegative("context_window", c.ContextWindow)
+	nonNegative("thinking_budget", c.ThinkingBudget)
+	nonNegative("max_tokens", c.MaxTokens)
+	nonNegative("verify.max_rounds", c.Verify.MaxRounds)
+	nonNegative("verify.timeout_sec", c.Verify.TimeoutSec)
+	nonNegative("architect.max_parallel", c.Architect.MaxParallel)
+	nonNegative("escalation.tool_errors", c.Escalation.ToolErrors)
+	for i, r := range c.Escalation.Ladder {
+		if strings.TrimSpace(r.Model) == "" {
+			bad("escalation.ladder[%d]: model is empty", i)
+		}
+	}
+	for i, vc := range c.Verify.Commands {
+		if strings.TrimSpace(vc.Run) == "" {
+			bad("verify.commands[%d]: run is empty", i)
+		}
+	}
+	for _, b := range []struct {
+		scope string
+		limit BudgetLimit
+	}{{"turn", c.Budget.Turn}, {"session", c.Budget.Session}, {"daily", c.Budget.Daily}} {
+		if b.limit.MaxCost < 0 {
+			bad("budget.%s.max_cost: must not be negative, got %g", b.scope, b.limit.MaxCost)
+		}
+		nonNegative("budget."+b.scope+".max_tokens", b.limit.MaxTokens)
+	}
+	for pattern, n := range c.ModelMaxTokens {
+		globs("model_max_tokens", []string{pattern})
+		if n <= 0 {
+			bad("model_max_tokens[%s]: must be positive, got %d", pattern, n)
+		}
+	}
+
+	names := make([]string, 0, len(c.Providers))
+	for name := range c.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pc := c.Providers[name]
+		if pc == nil {
+			continue
+		}
+		prefix := "providers." + name
+		oneOf(prefix+".transport", pc.Transport, "chat", "responses")
+		globs(prefix+".image_models_allow", pc.ImageModelsAllow)
+		globs(prefix+".image_models_deny", pc.ImageModelsDeny)
+		nonNegative(prefix+".rate_limit.requests_per_minute", pc.RateLimit.RequestsPerMinute)
+		nonNegative(prefix+".rate_limit.tokens_per_minute", pc.RateLimit.TokensPerMinute)
+		nonNegative(prefix+".http.connect_timeout_sec", pc.HTTP.ConnectTimeoutSec)
+		nonNegative(prefix+".http.stream_idle_timeout_sec", pc.HTTP.StreamIdleTimeoutSec)
+		if pc.HTTP.CACert != "" {
+			if _, err := os.Stat(pc.HTTP.CACert); err != nil {
+				bad("%s.http.ca_cert: %v", prefix, err)
+			}
+		}
+		for _, spec := range pc.Fallback {
+			if p, _, _ := strings.Cut(spec, "/"); p == "" {
+				bad("%s.fallback: entry %q has no provider name", prefix, spec)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 var (
 	// KnownProviderBaseURLs maps well-known provider names to their base URLs.
 	// Populated from providers_default.yaml (embedded) + user overrides.
@@ -342,6 +602,16 @@ func init() {
 	}
 }
 
+// ProviderNeedsAPIKey reports whether the named provider requires an API key.
+// Local servers such as Ollama accept unauthenticated requests.
+func ProviderNeedsAPIKey(name string) bool {
+	switch name {
+	case "ollama", "scripted":
+		return false
+	}
+	return true
+}
+
 // SaveProviderToFile persists a single provider's config and the active provider
 // name into ~/.config/apexion/config.yaml, preserving all other user settings.
 func SaveProviderToFile(providerName string, pc ProviderConfig) error {
@@ -382,6 +652,12 @@ func SaveProviderToFile(providerName string, pc ProviderConfig) error {
 	if len(pc.ImageModelsDeny) > 0 {
 		entry["image_models_deny"] = pc.ImageModelsDeny
 	}
+	if pc.Transport != "" {
+		entry["transport"] = pc.Transport
+	}
+	if pc.RateLimit != (RateLimitConfig{}) {
+		entry["rate_limit"] = pc.RateLimit
+	}
 	providers[providerName] = entry
 	raw["providers"] = providers
 
@@ -433,6 +709,27 @@ func applyEnvOverrides(cfg *Config) {
 		cfg.Providers["anthropic"].APIKey = v
 	}
 
+	// Ollama-specific (same variable the ollama CLI honours)
+	if v := os.Getenv("OLLAMA_HOST"); v != "" {
+		if cfg.Providers["ollama"] == nil {
+			cfg.Providers["ollama"] = &ProviderConfig{}
+		}
+		if cfg.Providers["ollama"].BaseURL == "" {
+			if !strings.Contains(v, "://") {
+				v = "http://" + v
+			}
+			cfg.Providers["ollama"].BaseURL = v
+		}
+	}
+
+	// Scripted provider scenario
+	if v := os.Getenv("APEXION_SCRIPT"); v != "" {
+		if cfg.Providers["scripted"] == nil {
+			cfg.Providers["scripted"] = &ProviderConfig{}
+		}
+		cfg.Providers["scripted"].Script = v
+	}
+
 	// Provider selection
 	if v := os.Getenv("APEXION_PROVIDER"); v != "" {
 		cfg.Provider = v
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -104,6 +105,13 @@ providers:
       - "deepseek-vl*"
     image_models_deny:
       - "*-text"
+    http:
+      proxy: "http://proxy.corp:3128"
+      ca_cert: "/etc/ssl/corp-root.pem"
+      headers:
+        X-Gateway-Auth: "Bearer ${GATEWAY_TOKEN}"
+      connect_timeout_sec: 10
+      stream_idle_timeout_sec: 90
 permissions:
   mode: "yolo"
   denied_commands:
@@ -181,6 +189,15 @@ permissions:
 	if len(pc.ImageModelsDeny) != 1 || pc.ImageModelsDeny[0] != "*-text" {
 		t.Errorf("unexpected image_models_deny: %+v", pc.ImageModelsDeny)
 	}
+	if pc.HTTP.Proxy != "http://proxy.corp:3128" || pc.HTTP.CACert != "/etc/ssl/corp-root.pem" {
+		t.Errorf("unexpected http proxy/ca_cert: %+v", pc.HTTP)
+	}
+	if pc.HTTP.Headers["X-Gateway-Auth"] != "Bearer ${GATEWAY_TOKEN}" {
+		t.Errorf("header should be kept unexpanded until the client is built, got %q", pc.HTTP.Headers["X-Gateway-Auth"])
+	}
+	if pc.HTTP.ConnectTimeoutSec != 10 || pc.HTTP.StreamIdleTimeoutSec != 90 {
+		t.Errorf("unexpected http timeouts: %+v", pc.HTTP)
+	}
 	if cfg.Permissions.Mode != "yolo" {
 		t.Errorf("expected permission mode 'yolo', got %q", cfg.Permissions.Mode)
 	}
@@ -292,3 +309,95 @@ func TestGetProviderConfig_Unknown(t *testing.T) {
 		t.Error("expected empty api_key for unknown provider")
 	}
 }
+
+func TestLoad_OllamaHost(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "config.yaml")
+	os.WriteFile(path, []byte("provider: ollama\n"), 0644)
+
+	t.Setenv("OLLAMA_HOST", "127.0.0.1:11500")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.GetProviderConfig("ollama").BaseURL; got != "http://127.0.0.1:11500" {
+		t.Errorf("OLLAMA_HOST should set ollama base_url, got %q", got)
+	}
+	if ProviderNeedsAPIKey("ollama") || ProviderNeedsAPIKey("scripted") {
+		t.Error("ollama and scripted should not require an API key")
+	}
+	if !ProviderNeedsAPIKey("openai") {
+		t.Error("openai should require an API key")
+	}
+}
+
+func TestMaxTokensFor(t *testing.T) {
+	cfg := DefaultConfig()
+	if got := cfg.MaxTokensFor("gpt-4o"); got != DefaultMaxTokens {
+		t.Errorf("expected default %d, got %d", DefaultMaxTokens, got)
+	}
+
+	cfg.MaxTokens = 16000
+	cfg.ModelMaxTokens = map[string]int{
+		"claude-*":          32000,
+		"claude-sonnet-4*":  64000,
+		"claude-3-5-haiku":  4096,
+		"ignored-zero-rule": 0,
+	}
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"claude-3-5-haiku", 4096},
+		{"claude-sonnet-4-5", 64000},
+		{"claude-opus-4-1", 32000},
+		{"gpt-4o", 16000},
+		{"ignored-zero-rule", 16000},
+	}
+	for _, tt := range tests {
+		if got := cfg.MaxTokensFor(tt.model); got != tt.want {
+			t.Errorf("MaxTokensFor(%q) = %d, want %d", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("default config should be valid, got %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Permissions.Mode = "always"
+	cfg.MaxTokens = -1
+	cfg.ModelMaxTokens = map[string]int{"claude-[": 4096}
+	cfg.Budget.Daily.MaxCost = -5
+	cfg.Verify.Commands = []VerifyCommand{{Name: "build", Run: "go build ./..."}, {Name: "test"}}
+	cfg.Escalation.Ladder = []EscalationRung{{Model: "deepseek-chat"}, {Provider: "anthropic"}}
+	cfg.SubAgentIsolation = "container"
+	cfg.Providers = map[string]*ProviderConfig{
+		"openai": {
+			Transport: "websocket",
+			HTTP:      HTTPConfig{CACert: filepath.Join(t.TempDir(), "missing.pem")},
+		},
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{
+		"permissions.mode",
+		"max_tokens",
+		`invalid pattern "claude-["`,
+		"providers.openai.transport",
+		"providers.openai.http.ca_cert",
+		"budget.daily.max_cost",
+		"verify.commands[1]",
+		"escalation.ladder[1]",
+		"sub_agent_isolation",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error mentioning %q, got:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Package httpclient builds the HTTP transport used for provider API calls
+// and the web tools, applying proxy, TLS, header and timeout settings.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options configures a transport. The zero value behaves like
+// http.DefaultTransport.
+type Options struct {
+	// Proxy is an http, https or socks5 proxy URL. Empty honours the
+	// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.
+	Proxy string
+	// CACert is a PEM file whose certificates are trusted in addition to
+	// the system roots.
+	CACert string
+	// InsecureSkipVerify disables TLS certificate verification.
+	InsecureSkipVerify bool
+	// Headers are set on every request. Values may reference environment
+	// variables as $VAR or ${VAR}.
+	Headers map[string]string
+	// ConnectTimeout bounds the TCP dial and the TLS handshake.
+	ConnectTimeout time.Duration
+	// StreamIdleTimeout aborts a response whose body delivers no data for
+	// this long.
+	StreamIdleTimeout time.Duration
+}
+
+// IsZero reports whether o leaves every setting at its default.
+func (o Options) IsZero() bool {
+	return o.Proxy == "" && o.CACert == "" && !o.InsecureSkipVerify &&
+		len(o.Headers) == 0 && o.ConnectTimeout == 0 && o.StreamIdleTimeout == 0
+}
+
+// NewTransport returns a RoundTripper applying opts. Headers are expanded
+// here, so a referenced environment variable that is unset is reported
+// at startup rather than as an authentication failure later.
+func NewTransport(opts Options) (http.RoundTripper, error) {
+	if opts.IsZero() {
+		return http.DefaultTransport, nil
+	}
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.Proxy != "" {
+		u, err := url.Parse(opts.Proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", opts.Proxy)
+		}
+		base.Proxy = http.ProxyURL(u)
+	}
+	if opts.ConnectTimeout > 0 {
+		dialer := &net.Dialer{Timeout: opts.ConnectTimeout, KeepAlive: 30 * time.Second}
+		base.DialContext = dialer.DialContext
+		base.TLSHandshakeTimeout = opts.ConnectTimeout
+	}
+	if opts.CACert != "" || opts.InsecureSkipVerify {
+		tlsCfg := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+		if opts.CACert != "" {
+			pool, err := loadCertPool(opts.CACert)
+			if err != nil {
+				return nil, err
+			}
+			tlsCfg.RootCAs = pool
+		}
+		base.TLSClientConfig = tlsCfg
+	}
+
+	var rt http.RoundTripper = base
+	if len(opts.Headers) > 0 {
+		headers, err := expandHeaders(opts.Headers)
+		if err != nil {
+			return nil, err
+		}
+		rt = &headerTransport{base: rt, headers: headers}
+	}
+	if opts.StreamIdleTimeout > 0 {
+		rt = &idleTimeoutTransport{base: rt, timeout: opts.StreamIdleTimeout}
+	}
+	return rt, nil
+}
+
+// New returns an http.Client using NewTransport(opts). The client has no
+// overall timeout, since streamed responses can legitimately run for
+// minutes; StreamIdleTimeout is the guard against a stalled stream.
+func New(opts Options) (*http.Client, error) {
+	rt, err := NewTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: rt}, nil
+}
+
+// loadCertPool returns the system roots plus the certificates in path.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA bundle %s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// expandHeaders resolves environment references in header values.
+func expandHeaders(in map[string]string) (http.Header, error) {
+	h := make(http.Header, len(in))
+	var missing []string
+	for name, value := range in {
+		expanded := os.Expand(value, func(key string) string {
+			v, ok := os.LookupEnv(key)
+			if !ok {
+				missing = append(missing, key)
+			}
+			return v
+		})
+		h.Set(name, expanded)
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("header values reference unset environment variables: %s", strings.Join(missing, ", "))
+	}
+	return h, nil
+}
+
+// headerTransport sets fixed headers on every request.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the caller's request.
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+	return t.base.RoundTrip(req)
+}
+
+// ErrStreamIdle is returned by a response body read after no data arrived
+// for the configured StreamIdleTimeout.
+var ErrStreamIdle = errors.New("stream idle timeout")
+
+// idleTimeoutTransport wraps response bodies so a read that waits longer
+// than timeout fails with ErrStreamIdle.
+type idleTimeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *idleTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		return resp, err
+	}
+	resp.Body = newIdleBody(resp.Body, t.timeout)
+	return resp, nil
+}
+
+// idleBody closes the underlying body when the idle timer fires, which
+// unblocks a pending Read.
+type idleBody struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+
+	mu      sync.Mutex
+	expired bool
+}
+
+func newIdleBody(body io.ReadCloser, timeout time.Duration) *idleBody {
+	b := &idleBody{body: body, timeout: timeout}
+	b.timer = time.AfterFunc(timeout, b.expire)
+	return b
+}
+
+func (b *idleBody) expire() {
+	b.mu.Lock()
+	b.expired = true
+	b.mu.Unlock()
+	b.body.Close()
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.mu.Lock()
+	expired := b.expired
+	b.mu.Unlock()
+	if expired {
+		return n, fmt.Errorf("%w: no data for %s", ErrStreamIdle, b.timeout)
+	}
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	return n, err
+}
+
+func (b *idleBody) Close() error {
+	b.timer.Stop()
+	return b.body.Close()
+}
@@ -0,0 +1,162 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewTransport_ZeroIsDefault(t *testing.T) {
+	rt, err := NewTransport(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt != http.DefaultTransport {
+		t.Errorf("zero options should use http.DefaultTransport, got %T", rt)
+	}
+}
+
+func TestNewTransport_Headers(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	t.Setenv("APEXION_TEST_GATEWAY_TOKEN", "tok-123")
+	c, err := New(Options{Headers: map[string]string{
+		"X-Gateway-Auth": "Bearer ${APEXION_TEST_GATEWAY_TOKEN}",
+		"X-Team":         "platform",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got.Get("X-Gateway-Auth") != "Bearer tok-123" || got.Get("X-Team") != "platform" {
+		t.Errorf("headers not applied: %v", got)
+	}
+	if req.Header.Get("X-Team") != "" {
+		t.Error("caller's request was modified")
+	}
+}
+
+func TestNewTransport_UnsetHeaderVariable(t *testing.T) {
+	_, err := NewTransport(Options{Headers: map[string]string{"X-Auth": "$APEXION_TEST_UNSET_VAR"}})
+	if err == nil || !strings.Contains(err.Error(), "APEXION_TEST_UNSET_VAR") {
+		t.Errorf("expected error naming the unset variable, got %v", err)
+	}
+}
+
+func TestNewTransport_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		fmt.Fprint(w, "via proxy")
+	}))
+	defer proxy.Close()
+
+	c, err := New(Options{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get("http://api.example.invalid/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if proxied != "http://api.example.invalid/v1/models" || string(body) != "via proxy" {
+		t.Errorf("request did not go through the proxy: url=%q body=%q", proxied, body)
+	}
+	if _, err := NewTransport(Options{Proxy: "not a url"}); err == nil {
+		t.Error("expected error for invalid proxy URL")
+	}
+}
+
+func TestNewTransport_CACert(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(bundle, pemData, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	plain, _ := New(Options{ConnectTimeout: 5 * time.Second})
+	if _, err := plain.Get(srv.URL); err == nil {
+		t.Error("expected certificate error without the CA bundle")
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"ca bundle", Options{CACert: bundle}},
+		{"insecure", Options{InsecureSkipVerify: true}},
+	}
+	for _, tt := range tests {
+		c, err := New(tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp, err := c.Get(srv.URL)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		resp.Body.Close()
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(empty, []byte("not a cert"), 0o600)
+	if _, err := NewTransport(Options{CACert: empty}); err == nil {
+		t.Error("expected error for a bundle without certificates")
+	}
+}
+
+func TestNewTransport_StreamIdleTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c, err := New(Options{StreamIdleTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if !errors.Is(err, ErrStreamIdle) {
+		t.Errorf("expected ErrStreamIdle, got %v", err)
+	}
+	if string(body) != "data: first\n\n" {
+		t.Errorf("data before the stall should be delivered, got %q", body)
+	}
+}
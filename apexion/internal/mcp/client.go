@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"os"
@@ -144,21 +145,40 @@ func (m *Manager) EnsureConnected(ctx context.Context, names []string) ([]string
 //   - error indicates a transport/protocol-level error
 //   - isError=true means the tool itself returned error content
 func (m *Manager) CallTool(ctx context.Context, serverName, toolName string, args map[string]any) (string, bool, error) {
+	out, isError, err := m.CallToolOutput(ctx, serverName, toolName, args)
+	return out.Text, isError, err
+}
+
+// ToolOutput is the content of a tool result: its text parts joined, and
+// its images.
+type ToolOutput struct {
+	Text   string
+	Images []ToolImage
+}
+
+// ToolImage is an image returned by a tool.
+type ToolImage struct {
+	Data     string // base64-encoded
+	MIMEType string
+}
+
+// CallToolOutput is CallTool, also returning the images in the result.
+func (m *Manager) CallToolOutput(ctx context.Context, serverName, toolName string, args map[string]any) (ToolOutput, bool, error) {
 	m.disconnectIdleExcept(time.Now(), map[string]bool{serverName: true})
 
 	m.mu.RLock()
 	conn, ok := m.servers[serverName]
 	m.mu.RUnlock()
 	if !ok {
-		return "", false, fmt.Errorf("mcp server %q not found", serverName)
+		return ToolOutput{}, false, fmt.Errorf("mcp server %q not found", serverName)
 	}
 	now := time.Now()
 	if conn.inCooldown(now) {
-		return "", false, fmt.Errorf("mcp server %q temporarily unavailable (cooldown)", serverName)
+		return ToolOutput{}, false, fmt.Errorf("mcp server %q temporarily unavailable (cooldown)", serverName)
 	}
 	if err := conn.connect(ctx); err != nil {
 		conn.noteFailure(now, m.failureCooldown)
-		return "", false, fmt.Errorf("call tool %q on %q (connect): %w", toolName, serverName, err)
+		return ToolOutput{}, false, fmt.Errorf("call tool %q on %q (connect): %w", toolName, serverName, err)
 	}
 
 	result, err := conn.callTool(ctx, toolName, args)
@@ -167,13 +187,13 @@ func (m *Manager) CallTool(ctx context.Context, serverName, toolName string, arg
 		// Reconnect once and retry
 		if reconnErr := conn.connect(ctx); reconnErr != nil {
 			conn.noteFailure(time.Now(), m.failureCooldown)
-			return "", false, fmt.Errorf("call tool %q on %q (reconnect failed: %v): %w",
+			return ToolOutput{}, false, fmt.Errorf("call tool %q on %q (reconnect failed: %v): %w",
 				toolName, serverName, reconnErr, err)
 		}
 		result, err = conn.callTool(ctx, toolName, args)
 		if err != nil {
 			conn.noteFailure(time.Now(), m.failureCooldown)
-			return "", false, fmt.Errorf("call tool %q on %q: %w", toolName, serverName, err)
+			return ToolOutput{}, false, fmt.Errorf("call tool %q on %q: %w", toolName, serverName, err)
 		}
 	}
 	conn.noteSuccess(time.Now())
@@ -262,6 +282,25 @@ func (m *Manager) Close() {
 	}
 }
 
+// Probe connects to one server, counts its tools and disconnects again,
+// leaving the manager as it was. Used by `apexion doctor`.
+func (m *Manager) Probe(ctx context.Context, name string) (int, error) {
+	m.mu.RLock()
+	conn, ok := m.servers[name]
+	m.mu.RUnlock()
+	if !ok {
+		return 0, fmt.Errorf("mcp server %q not found", name)
+	}
+	if err := conn.connect(ctx); err != nil {
+		return 0, err
+	}
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	n := len(conn.tools)
+	conn.disconnect()
+	return n, nil
+}
+
 // HasServer returns true if the named server exists in config.
 func (m *Manager) HasServer(name string) bool {
 	m.mu.RLock()
@@ -546,18 +585,28 @@ func buildTransport(cfg ServerConfig) (mcp.Transport, error) {
 	}
 }
 
-// extractContent extracts text content from a CallToolResult.
-func extractContent(result *mcp.CallToolResult) string {
+// extractContent extracts text and image content from a CallToolResult.
+func extractContent(result *mcp.CallToolResult) ToolOutput {
 	if result == nil {
-		return ""
+		return ToolOutput{}
 	}
+	var out ToolOutput
 	var parts []string
 	for _, c := range result.Content {
-		if tc, ok := c.(*mcp.TextContent); ok {
-			parts = append(parts, tc.Text)
+		switch c := c.(type) {
+		case *mcp.TextContent:
+			parts = append(parts, c.Text)
+		case *mcp.ImageContent:
+			if len(c.Data) > 0 {
+				out.Images = append(out.Images, ToolImage{
+					Data:     base64.StdEncoding.EncodeToString(c.Data),
+					MIMEType: c.MIMEType,
+				})
+			}
 		}
 	}
-	return strings.Join(parts, "\n")
+	out.Text = strings.Join(parts, "\n")
+	return out
 }
 
 // headerRoundTripper injects fixed headers into every HTTP request.
@@ -2,9 +2,13 @@ package mcp
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 func TestServerConnCooldownLifecycle(t *testing.T) {
@@ -59,3 +63,33 @@ func TestEnsureConnectedSkipsCoolingDownServer(t *testing.T) {
 		t.Fatalf("expected cooldown error, got %v", errs)
 	}
 }
+
+func TestManagerProbe(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "probe-test", Version: "1.0.0"}, nil)
+	type echoArgs struct {
+		Text string `json:"text"`
+	}
+	mcp.AddTool(server, &mcp.Tool{Name: "echo", Description: "Echo text"},
+		func(ctx context.Context, req *mcp.CallToolRequest, args echoArgs) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: args.Text}}}, nil, nil
+		})
+	srv := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	defer srv.Close()
+
+	m := NewManager(&MCPConfig{MCPServers: map[string]ServerConfig{
+		"local": {Type: ServerTypeHTTP, URL: srv.URL},
+	}})
+	n, err := m.Probe(context.Background(), "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 tool, got %d", n)
+	}
+	if st := m.Status()["local"]; st != "disconnected" {
+		t.Errorf("probe should leave the server disconnected, got %q", st)
+	}
+	if _, err := m.Probe(context.Background(), "missing"); err == nil {
+		t.Error("expected error for unknown server")
+	}
+}
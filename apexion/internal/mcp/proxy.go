@@ -53,7 +53,7 @@ func (p *MCPToolProxy) Execute(ctx context.Context, params json.RawMessage) (too
 		args = map[string]any{}
 	}
 
-	output, isError, err := p.manager.CallTool(ctx, p.serverName, p.tool.Name, args)
+	output, isError, err := p.manager.CallToolOutput(ctx, p.serverName, p.tool.Name, args)
 	if err != nil {
 		return tools.ToolResult{
 			Content: fmt.Sprintf("mcp tool error: %v", err),
@@ -61,10 +61,26 @@ func (p *MCPToolProxy) Execute(ctx context.Context, params json.RawMessage) (too
 		}, nil
 	}
 
-	return tools.ToolResult{
-		Content: output,
-		IsError: isError,
-	}, nil
+	return toolResult(output, isError), nil
+}
+
+// toolResult converts MCP tool output. The first image is passed to the
+// model with the result; any others are only counted in the text.
+func toolResult(output ToolOutput, isError bool) tools.ToolResult {
+	result := tools.ToolResult{Content: output.Text, IsError: isError}
+	if len(output.Images) == 0 {
+		return result
+	}
+	img := output.Images[0]
+	result.ImageData = img.Data
+	result.ImageMediaType = img.MIMEType
+	if result.Content == "" {
+		result.Content = fmt.Sprintf("[Image: %s]", img.MIMEType)
+	}
+	if n := len(output.Images) - 1; n > 0 {
+		result.Content += fmt.Sprintf("\n[%d more image(s) not shown]", n)
+	}
+	return result
 }
 
 // IsReadOnly returns false; MCP tools are not read-only by default and require confirmation.
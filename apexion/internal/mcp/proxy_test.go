@@ -0,0 +1,29 @@
+package mcp
+
+import (
+	"testing"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestToolResult_PassesImages(t *testing.T) {
+	out := extractContent(&mcpsdk.CallToolResult{Content: []mcpsdk.Content{
+		&mcpsdk.ImageContent{Data: []byte("png-1"), MIMEType: "image/png"},
+		&mcpsdk.ImageContent{Data: []byte("png-2"), MIMEType: "image/png"},
+	}})
+	res := toolResult(out, false)
+	if res.ImageData != "cG5nLTE=" || res.ImageMediaType != "image/png" {
+		t.Errorf("expected the first image base64-encoded, got %q %q", res.ImageData, res.ImageMediaType)
+	}
+	if res.Content != "[Image: image/png]\n[1 more image(s) not shown]" {
+		t.Errorf("unexpected text %q", res.Content)
+	}
+
+	out = extractContent(&mcpsdk.CallToolResult{Content: []mcpsdk.Content{
+		&mcpsdk.TextContent{Text: "saved"},
+		&mcpsdk.TextContent{Text: "done"},
+	}})
+	if res := toolResult(out, true); res.Content != "saved\ndone" || res.ImageData != "" || !res.IsError {
+		t.Errorf("unexpected text-only result %+v", res)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -17,12 +18,18 @@ type AnthropicProvider struct {
 	model  string
 }
 
-func NewAnthropicProvider(apiKey, model string) *AnthropicProvider {
+// NewAnthropicProvider creates an Anthropic provider. httpClient may be nil
+// to use the SDK's default client.
+func NewAnthropicProvider(apiKey, model string, httpClient *http.Client) *AnthropicProvider {
 	if model == "" {
 		model = "claude-sonnet-4-20250514" // fallback; normally buildProvider passes the correct default
 	}
+	opts := []anthropicoption.RequestOption{anthropicoption.WithAPIKey(apiKey), anthropicoption.WithMiddleware(observeRateLimits)}
+	if httpClient != nil {
+		opts = append(opts, anthropicoption.WithHTTPClient(httpClient))
+	}
 	return &AnthropicProvider{
-		client: anthropic.NewClient(anthropicoption.WithAPIKey(apiKey)),
+		client: anthropic.NewClient(opts...),
 		model:  model,
 	}
 }
@@ -64,25 +71,78 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req *ChatRequest) (<-chan
 		MaxTokens: maxTokens,
 	}
 	if req.SystemPrompt != "" {
-		params.System = []anthropic.TextBlockParam{{Text: req.SystemPrompt}}
+		params.System = []anthropic.TextBlockParam{{
+			Text:         req.SystemPrompt,
+			CacheControl: anthropic.NewCacheControlEphemeralParam(),
+		}}
+	}
+	// A response schema becomes a forced call to a tool whose input is the
+	// document; processStream turns that call back into text.
+	schemaTool := ""
+	if rs := req.ResponseSchema; rs != nil {
+		schemaTool = rs.Name
+		tools = []anthropic.ToolUnionParam{anthropicSchemaTool(rs)}
+		params.ToolChoice = anthropic.ToolChoiceParamOfTool(rs.Name)
 	}
 	if len(tools) > 0 {
+		// One breakpoint after the last tool caches the whole tool list.
+		*tools[len(tools)-1].GetCacheControl() = anthropic.NewCacheControlEphemeralParam()
 		params.Tools = tools
 	}
-	if req.Temperature != nil {
-		params.Temperature = anthropic.Float(*req.Temperature)
-	}
-	if req.TopP != nil {
-		params.TopP = anthropic.Float(*req.TopP)
+	markCacheBreakpoints(msgs)
+	if req.ThinkingBudget > 0 && schemaTool == "" {
+		// Extended thinking: the budget must stay below max_tokens, and the
+		// API rejects custom temperature/top_p while thinking is enabled.
+		// It is also incompatible with forced tool use.
+		budget := int64(max(req.ThinkingBudget, 1024))
+		if params.MaxTokens <= budget {
+			params.MaxTokens = budget + 8192
+		}
+		params.Thinking = anthropic.ThinkingConfigParamOfEnabled(budget)
+	} else {
+		if req.Temperature != nil {
+			params.Temperature = anthropic.Float(*req.Temperature)
+		}
+		if req.TopP != nil {
+			params.TopP = anthropic.Float(*req.TopP)
+		}
 	}
 
 	stream := p.client.Messages.NewStreaming(ctx, params)
 
 	ch := make(chan Event, 16)
-	go p.processStream(ctx, stream, ch)
+	go p.processStream(ctx, stream, ch, schemaTool)
 	return ch, nil
 }
 
+// SupportsResponseSchema reports native schema support via forced tool use.
+func (p *AnthropicProvider) SupportsResponseSchema(string) bool { return true }
+
+// anthropicSchemaTool converts a response schema to a tool definition.
+func anthropicSchemaTool(rs *ResponseSchema) anthropic.ToolUnionParam {
+	input := anthropic.ToolInputSchemaParam{ExtraFields: map[string]any{}}
+	for k, v := range rs.Schema {
+		switch k {
+		case "type":
+		case "properties":
+			input.Properties = v
+		case "required":
+			input.Required = schemaStrings(v)
+		default:
+			input.ExtraFields[k] = v
+		}
+	}
+	desc := rs.Description
+	if desc == "" {
+		desc = "Submit the response."
+	}
+	return anthropic.ToolUnionParam{OfTool: &anthropic.ToolParam{
+		Name:        rs.Name,
+		Description: anthropic.String(desc),
+		InputSchema: input,
+	}}
+}
+
 // processStream reads the Anthropic SSE stream and emits unified events.
 //
 // Anthropic streaming event sequence:
@@ -90,8 +150,17 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req *ChatRequest) (<-chan
 //   - ContentBlockDeltaEvent (InputJSONDelta) -> accumulate JSON arguments
 //   - ContentBlockStopEvent -> tool call arguments complete, emit EventToolCallDone
 //   - ContentBlockDeltaEvent (TextDelta) -> emit EventTextDelta
-//   - MessageDeltaEvent -> emit EventDone with usage
-func (p *AnthropicProvider) processStream(ctx context.Context, stream *ssestream.Stream[anthropic.MessageStreamEventUnion], ch chan<- Event) {
+//   - ContentBlockDeltaEvent (ThinkingDelta) -> emit EventThinkingDelta
+//   - ContentBlockDeltaEvent (SignatureDelta) -> buffered until the thinking
+//     block stops, then emitted as EventThinkingDelta with ThinkingSignature
+//   - ContentBlockStartEvent (redacted_thinking) -> emit EventThinkingDelta
+//     with RedactedThinking
+//   - MessageStartEvent -> record prompt and cache usage
+//   - MessageDeltaEvent -> emit EventDone with usage and stop reason
+//
+// A completed call to schemaTool (the forced response-schema tool) is
+// emitted as EventTextDelta carrying the JSON input.
+func (p *AnthropicProvider) processStream(ctx context.Context, stream *ssestream.Stream[anthropic.MessageStreamEventUnion], ch chan<- Event, schemaTool string) {
 	defer close(ch)
 	defer stream.Close()
 
@@ -102,6 +171,10 @@ func (p *AnthropicProvider) processStream(ctx context.Context, stream *ssestream
 	}
 	// Track pending tool calls by content block index.
 	pending := make(map[int64]*pendingCall)
+	// Signatures of open thinking blocks, by content block index.
+	signatures := make(map[int64]*strings.Builder)
+	// Prompt usage reported by message_start.
+	var start Usage
 
 	for stream.Next() {
 		select {
@@ -117,12 +190,17 @@ func (p *AnthropicProvider) processStream(ctx context.Context, stream *ssestream
 		case anthropic.ContentBlockStartEvent:
 			// Check if this content block is a tool_use block.
 			cb := variant.ContentBlock
-			if cb.Type == "tool_use" {
+			switch cb.Type {
+			case "tool_use":
 				toolUse := cb.AsToolUse()
 				pending[variant.Index] = &pendingCall{
 					id:   toolUse.ID,
 					name: toolUse.Name,
 				}
+			case "thinking":
+				signatures[variant.Index] = &strings.Builder{}
+			case "redacted_thinking":
+				ch <- Event{Type: EventThinkingDelta, RedactedThinking: cb.AsRedactedThinking().Data}
 			}
 
 		case anthropic.ContentBlockDeltaEvent:
@@ -130,6 +208,12 @@ func (p *AnthropicProvider) processStream(ctx context.Context, stream *ssestream
 			switch d := delta.AsAny().(type) {
 			case anthropic.TextDelta:
 				ch <- Event{Type: EventTextDelta, TextDelta: d.Text}
+			case anthropic.ThinkingDelta:
+				ch <- Event{Type: EventThinkingDelta, ThinkingDelta: d.Thinking}
+			case anthropic.SignatureDelta:
+				if sig, ok := signatures[variant.Index]; ok {
+					sig.WriteString(d.Signature)
+				}
 			case anthropic.InputJSONDelta:
 				if pc, ok := pending[variant.Index]; ok {
 					pc.jsonBuf.WriteString(d.PartialJSON)
@@ -137,11 +221,20 @@ func (p *AnthropicProvider) processStream(ctx context.Context, stream *ssestream
 			}
 
 		case anthropic.ContentBlockStopEvent:
+			if sig, ok := signatures[variant.Index]; ok {
+				ch <- Event{Type: EventThinkingDelta, ThinkingSignature: sig.String()}
+				delete(signatures, variant.Index)
+			}
 			if pc, ok := pending[variant.Index]; ok {
 				inputJSON := pc.jsonBuf.String()
 				if inputJSON == "" {
 					inputJSON = "{}"
 				}
+				if schemaTool != "" && pc.name == schemaTool {
+					ch <- Event{Type: EventTextDelta, TextDelta: inputJSON}
+					delete(pending, variant.Index)
+					continue
+				}
 				ch <- Event{
 					Type: EventToolCallDone,
 					ToolCall: &ToolCallRequest{
@@ -153,13 +246,31 @@ func (p *AnthropicProvider) processStream(ctx context.Context, stream *ssestream
 				delete(pending, variant.Index)
 			}
 
+		case anthropic.MessageStartEvent:
+			u := variant.Message.Usage
+			start = Usage{
+				InputTokens:         int(u.InputTokens),
+				CacheCreationTokens: int(u.CacheCreationInputTokens),
+				CacheReadTokens:     int(u.CacheReadInputTokens),
+			}
+
 		case anthropic.MessageDeltaEvent:
+			// message_delta carries cumulative counts, but the input side
+			// may be zero on older API versions; message_start has them.
+			u := variant.Usage
+			stop := anthropicStopReason(variant.Delta.StopReason)
+			if stop == StopToolUse && schemaTool != "" {
+				stop = StopEndTurn
+			}
 			ch <- Event{
 				Type: EventDone,
 				Usage: &Usage{
-					InputTokens:  int(variant.Usage.InputTokens),
-					OutputTokens: int(variant.Usage.OutputTokens),
+					InputTokens:         max(int(u.InputTokens), start.InputTokens),
+					OutputTokens:        int(u.OutputTokens),
+					CacheCreationTokens: max(int(u.CacheCreationInputTokens), start.CacheCreationTokens),
+					CacheReadTokens:     max(int(u.CacheReadInputTokens), start.CacheReadTokens),
 				},
+				StopReason: stop,
 			}
 			return
 		}
@@ -173,6 +284,22 @@ func (p *AnthropicProvider) processStream(ctx context.Context, stream *ssestream
 	ch <- Event{Type: EventDone, Usage: &Usage{}}
 }
 
+// anthropicStopReason maps an Anthropic stop_reason to a StopReason.
+func anthropicStopReason(r anthropic.StopReason) StopReason {
+	switch r {
+	case anthropic.StopReasonMaxTokens:
+		return StopMaxTokens
+	case anthropic.StopReasonToolUse:
+		return StopToolUse
+	case anthropic.StopReasonRefusal:
+		return StopContentFilter
+	case "":
+		return ""
+	default: // end_turn, stop_sequence, pause_turn
+		return StopEndTurn
+	}
+}
+
 // buildMessages converts unified Message types to Anthropic API params.
 func (p *AnthropicProvider) buildMessages(msgs []Message) []anthropic.MessageParam {
 	var params []anthropic.MessageParam
@@ -184,6 +311,13 @@ func (p *AnthropicProvider) buildMessages(msgs []Message) []anthropic.MessagePar
 			switch c.Type {
 			case ContentTypeText:
 				blocks = append(blocks, anthropic.NewTextBlock(c.Text))
+			case ContentTypeThinking:
+				// Unsigned reasoning (e.g. from another provider) cannot be replayed.
+				if c.Signature != "" {
+					blocks = append(blocks, anthropic.NewThinkingBlock(c.Signature, c.Text))
+				}
+			case ContentTypeRedactedThinking:
+				blocks = append(blocks, anthropic.NewRedactedThinkingBlock(c.Text))
 			case ContentTypeToolUse:
 				// ToolInput is json.RawMessage; parse it to any for the SDK.
 				var input any
@@ -195,7 +329,7 @@ func (p *AnthropicProvider) buildMessages(msgs []Message) []anthropic.MessagePar
 				}
 				blocks = append(blocks, anthropic.NewToolUseBlock(c.ToolUseID, input, c.ToolName))
 			case ContentTypeToolResult:
-				blocks = append(blocks, anthropic.NewToolResultBlock(c.ToolUseID, c.ToolResult, c.IsError))
+				blocks = append(blocks, anthropicToolResult(c))
 			case ContentTypeImage:
 				if c.ImageData != "" {
 					blocks = append(blocks, anthropic.NewImageBlockBase64(c.ImageMediaType, c.ImageData))
@@ -213,6 +347,61 @@ func (p *AnthropicProvider) buildMessages(msgs []Message) []anthropic.MessagePar
 	return params
 }
 
+// anthropicToolResult builds a tool_result block. An image returned by the
+// tool goes inside the block, after the text.
+func anthropicToolResult(c Content) anthropic.ContentBlockParamUnion {
+	block := anthropic.NewToolResultBlock(c.ToolUseID, c.ToolResult, c.IsError)
+	if c.ImageData == "" {
+		return block
+	}
+	if c.ToolResult == "" {
+		// Empty text blocks are rejected.
+		block.OfToolResult.Content = nil
+	}
+	block.OfToolResult.Content = append(block.OfToolResult.Content, anthropic.ToolResultBlockParamContentUnion{
+		OfImage: &anthropic.ImageBlockParam{
+			Source: anthropic.ImageBlockParamSourceUnion{
+				OfBase64: &anthropic.Base64ImageSourceParam{
+					Data:      c.ImageData,
+					MediaType: anthropic.Base64ImageSourceMediaType(c.ImageMediaType),
+				},
+			},
+		},
+	})
+	return block
+}
+
+// markCacheBreakpoints places prompt-cache breakpoints in the conversation.
+// Together with the system prompt and tool list breakpoints set in Chat this
+// uses all four breakpoints the API allows:
+//   - the final message, so the next request (same history plus the new
+//     turn) reads everything up to here from the cache;
+//   - the last stable user turn before it, which the previous request wrote,
+//     so a cache hit does not depend on the 20-block lookback window.
+func markCacheBreakpoints(msgs []anthropic.MessageParam) {
+	marked := 0
+	for i := len(msgs) - 1; i >= 0 && marked < 2; i-- {
+		if marked == 1 && msgs[i].Role != anthropic.MessageParamRoleUser {
+			continue
+		}
+		if markLastCacheableBlock(msgs[i].Content) {
+			marked++
+		}
+	}
+}
+
+// markLastCacheableBlock sets cache_control on the last block that accepts
+// it (thinking blocks do not). It reports whether a block was marked.
+func markLastCacheableBlock(blocks []anthropic.ContentBlockParamUnion) bool {
+	for j := len(blocks) - 1; j >= 0; j-- {
+		if cc := blocks[j].GetCacheControl(); cc != nil {
+			*cc = anthropic.NewCacheControlEphemeralParam()
+			return true
+		}
+	}
+	return false
+}
+
 // buildTools converts unified ToolSchema to Anthropic tool params.
 func (p *AnthropicProvider) buildTools(tools []ToolSchema) []anthropic.ToolUnionParam {
 	var result []anthropic.ToolUnionParam
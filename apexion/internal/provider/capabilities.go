@@ -157,3 +157,43 @@ func matchModelList(model string, rules []string) (string, bool) {
 func isGlobRule(rule string) bool {
 	return strings.ContainsAny(rule, "*?[")
 }
+
+// maxOutputTokens lists published output-token limits by model id prefix.
+// More specific prefixes come first.
+var maxOutputTokens = []struct {
+	prefix string
+	limit  int
+}{
+	{"claude-opus-4-5", 64000},
+	{"claude-opus-4", 32000},
+	{"claude-sonnet-4", 64000},
+	{"claude-haiku-4", 64000},
+	{"claude-3-7-sonnet", 64000},
+	{"claude-3-5", 8192},
+	{"gpt-4o", 16384},
+	{"gpt-4.1", 32768},
+	{"gpt-5", 128000},
+	{"o1", 100000},
+	{"o3", 100000},
+	{"o4", 100000},
+	{"deepseek-chat", 8192},
+	{"deepseek-reasoner", 65536},
+	{"gemini-2.5", 65536},
+	{"gemini-2.0", 8192},
+}
+
+// MaxOutputTokens returns the largest output budget model accepts per
+// response, or 0 if it is not known. A "vendor/" prefix (OpenRouter-style
+// ids) is ignored.
+func MaxOutputTokens(model string) int {
+	m := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(m, "/"); i >= 0 {
+		m = m[i+1:]
+	}
+	for _, e := range maxOutputTokens {
+		if strings.HasPrefix(m, e.prefix) {
+			return e.limit
+		}
+	}
+	return 0
+}
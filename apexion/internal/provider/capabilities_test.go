@@ -88,3 +88,25 @@ func TestDetectImageSupportWithConfig_AllowListMatchAndMiss(t *testing.T) {
 		t.Fatalf("expected allow-list miss to block confidently, got %+v", miss)
 	}
 }
+
+func TestMaxOutputTokens(t *testing.T) {
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"claude-opus-4-5-20251101", 64000},
+		{"claude-opus-4-1-20250805", 32000},
+		{"claude-sonnet-4-5", 64000},
+		{"claude-3-5-haiku-latest", 8192},
+		{"gpt-4o-mini", 16384},
+		{"openai/gpt-4.1", 32768},
+		{"deepseek-reasoner", 65536},
+		{"gemini-2.5-pro", 65536},
+		{"llama3.1:8b", 0},
+	}
+	for _, tt := range tests {
+		if got := MaxOutputTokens(tt.model); got != tt.want {
+			t.Errorf("MaxOutputTokens(%q) = %d, want %d", tt.model, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+)
+
+// A cassette is a JSONL file with one recorded interaction per line: the
+// request, the full event stream it produced, and the key it is replayed by.
+//
+// The key hashes a normalized form of the request: the conversation and the
+// set of tool names. The system prompt and model are left out because they
+// embed run-specific details (date, working directory, git status, repo map)
+// that would make every replay miss.
+
+// cassetteInteraction is one line of a cassette file.
+type cassetteInteraction struct {
+	Key           string          `json:"key"`
+	Provider      string          `json:"provider"`
+	Model         string          `json:"model"`
+	ContextWindow int             `json:"context_window"`
+	Request       cassetteRequest `json:"request"`
+	Events        []cassetteEvent `json:"events"`
+}
+
+type cassetteRequest struct {
+	Model        string            `json:"model,omitempty"`
+	SystemPrompt string            `json:"system_prompt,omitempty"`
+	Tools        []string          `json:"tools,omitempty"`
+	Messages     []cassetteMessage `json:"messages"`
+}
+
+type cassetteMessage struct {
+	Role    Role              `json:"role"`
+	Content []cassetteContent `json:"content"`
+}
+
+// cassetteContent mirrors Content. ToolInput is kept as a string because it
+// may be malformed JSON; images are stored as a digest to keep cassettes small.
+type cassetteContent struct {
+	Type       ContentType `json:"type"`
+	Text       string      `json:"text,omitempty"`
+	ToolUseID  string      `json:"tool_use_id,omitempty"`
+	ToolName   string      `json:"tool_name,omitempty"`
+	ToolInput  string      `json:"tool_input,omitempty"`
+	ToolResult string      `json:"tool_result,omitempty"`
+	IsError    bool        `json:"is_error,omitempty"`
+	ImageSHA   string      `json:"image_sha256,omitempty"`
+}
+
+type cassetteEvent struct {
+	Type              string             `json:"type"`
+	TextDelta         string             `json:"text,omitempty"`
+	ThinkingDelta     string             `json:"thinking,omitempty"`
+	ThinkingSignature string             `json:"signature,omitempty"`
+	RedactedThinking  string             `json:"redacted_thinking,omitempty"`
+	ReasoningItem     *cassetteReasoning `json:"reasoning_item,omitempty"`
+	ToolCall          *cassetteCall      `json:"tool_call,omitempty"`
+	Usage             *Usage             `json:"usage,omitempty"`
+	StopReason        StopReason         `json:"stop_reason,omitempty"`
+	Error             string             `json:"error,omitempty"`
+}
+
+type cassetteReasoning struct {
+	ID        string `json:"id"`
+	Summary   string `json:"summary,omitempty"`
+	Encrypted string `json:"encrypted,omitempty"`
+}
+
+type cassetteCall struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Input string `json:"input"`
+}
+
+var cassetteEventNames = map[EventType]string{
+	EventTextDelta:     "text",
+	EventToolCallDone:  "tool_call",
+	EventDone:          "done",
+	EventError:         "error",
+	EventThinkingDelta: "thinking",
+}
+
+func encodeCassetteRequest(req *ChatRequest) cassetteRequest {
+	cr := cassetteRequest{
+		Model:        req.Model,
+		SystemPrompt: req.SystemPrompt,
+		Messages:     make([]cassetteMessage, len(req.Messages)),
+	}
+	for _, t := range req.Tools {
+		cr.Tools = append(cr.Tools, t.Name)
+	}
+	slices.Sort(cr.Tools)
+	for i, m := range req.Messages {
+		cm := cassetteMessage{Role: m.Role, Content: make([]cassetteContent, len(m.Content))}
+		for j, c := range m.Content {
+			cc := cassetteContent{
+				Type:       c.Type,
+				Text:       c.Text,
+				ToolUseID:  c.ToolUseID,
+				ToolName:   c.ToolName,
+				ToolInput:  string(c.ToolInput),
+				ToolResult: c.ToolResult,
+				IsError:    c.IsError,
+			}
+			if c.ImageData != "" {
+				sum := sha256.Sum256([]byte(c.ImageData))
+				cc.ImageSHA = hex.EncodeToString(sum[:])
+			}
+			cm.Content[j] = cc
+		}
+		cr.Messages[i] = cm
+	}
+	return cr
+}
+
+// cassetteKey returns the normalized request hash used to match replays.
+func cassetteKey(cr cassetteRequest) string {
+	data, _ := json.Marshal(struct {
+		Tools    []string          `json:"tools"`
+		Messages []cassetteMessage `json:"messages"`
+	}{cr.Tools, cr.Messages})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+func encodeCassetteEvent(ev Event) cassetteEvent {
+	ce := cassetteEvent{
+		Type:              cassetteEventNames[ev.Type],
+		TextDelta:         ev.TextDelta,
+		ThinkingDelta:     ev.ThinkingDelta,
+		ThinkingSignature: ev.ThinkingSignature,
+		RedactedThinking:  ev.RedactedThinking,
+		Usage:             ev.Usage,
+		StopReason:        ev.StopReason,
+	}
+	if r := ev.ReasoningItem; r != nil {
+		ce.ReasoningItem = &cassetteReasoning{ID: r.ReasoningID, Summary: r.Text, Encrypted: r.Signature}
+	}
+	if ev.ToolCall != nil {
+		ce.ToolCall = &cassetteCall{ID: ev.ToolCall.ID, Name: ev.ToolCall.Name, Input: string(ev.ToolCall.Input)}
+	}
+	if ev.Error != nil {
+		ce.Error = ev.Error.Error()
+	}
+	return ce
+}
+
+func decodeCassetteEvent(ce cassetteEvent) (Event, error) {
+	ev := Event{
+		TextDelta:         ce.TextDelta,
+		ThinkingDelta:     ce.ThinkingDelta,
+		ThinkingSignature: ce.ThinkingSignature,
+		RedactedThinking:  ce.RedactedThinking,
+		Usage:             ce.Usage,
+		StopReason:        ce.StopReason,
+	}
+	found := false
+	for t, name := range cassetteEventNames {
+		if name == ce.Type {
+			ev.Type, found = t, true
+			break
+		}
+	}
+	if !found {
+		return Event{}, fmt.Errorf("unknown event type %q", ce.Type)
+	}
+	if r := ce.ReasoningItem; r != nil {
+		ev.ReasoningItem = &Content{Type: ContentTypeReasoning, ReasoningID: r.ID, Text: r.Summary, Signature: r.Encrypted}
+	}
+	if ce.ToolCall != nil {
+		ev.ToolCall = &ToolCallRequest{ID: ce.ToolCall.ID, Name: ce.ToolCall.Name, Input: json.RawMessage(ce.ToolCall.Input)}
+	}
+	if ce.Error != "" {
+		ev.Error = errors.New(ce.Error)
+	}
+	return ev, nil
+}
+
+// ── Recording ────────────────────────────────────────────────────────────────
+
+// RecordingProvider wraps a Provider and appends every request and its full
+// event stream to a cassette file. Events are forwarded unchanged.
+type RecordingProvider struct {
+	inner Provider
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRecordingProvider creates (or truncates) the cassette at path.
+func NewRecordingProvider(inner Provider, path string) (*RecordingProvider, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create cassette: %w", err)
+	}
+	return &RecordingProvider{inner: inner, file: f}, nil
+}
+
+func (r *RecordingProvider) Name() string         { return r.inner.Name() }
+func (r *RecordingProvider) Models() []string     { return r.inner.Models() }
+func (r *RecordingProvider) DefaultModel() string { return r.inner.DefaultModel() }
+func (r *RecordingProvider) ContextWindow() int   { return r.inner.ContextWindow() }
+
+// SupportsResponseSchema delegates to the wrapped provider.
+func (r *RecordingProvider) SupportsResponseSchema(model string) bool {
+	return SupportsResponseSchema(r.inner, model)
+}
+
+// Close closes the cassette file.
+func (r *RecordingProvider) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+func (r *RecordingProvider) Chat(ctx context.Context, req *ChatRequest) (<-chan Event, error) {
+	rec := cassetteInteraction{
+		Provider:      r.inner.Name(),
+		Model:         req.Model,
+		ContextWindow: r.inner.ContextWindow(),
+		Request:       encodeCassetteRequest(req),
+	}
+	if rec.Model == "" {
+		rec.Model = r.inner.DefaultModel()
+	}
+	rec.Key = cassetteKey(rec.Request)
+
+	events, err := r.inner.Chat(ctx, req)
+	if err != nil {
+		// Record the failure as a stream error so replays reproduce it.
+		rec.Events = []cassetteEvent{encodeCassetteEvent(Event{Type: EventError, Error: err})}
+		r.write(rec)
+		return nil, err
+	}
+
+	out := make(chan Event, 16)
+	go func() {
+		defer close(out)
+		for ev := range events {
+			rec.Events = append(rec.Events, encodeCassetteEvent(ev))
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				// The consumer may have stopped reading. Keep draining so
+				// the partial interaction is still recorded.
+			}
+		}
+		r.write(rec)
+	}()
+	return out, nil
+}
+
+func (r *RecordingProvider) write(rec cassetteInteraction) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, _ = r.file.Write(append(line, '\n'))
+}
+
+// ── Replay ───────────────────────────────────────────────────────────────────
+
+// ReplayProvider serves recorded event streams from a cassette instead of
+// calling an API. Requests are matched by their normalized hash; repeated
+// identical requests are served in recording order.
+//
+// In strict mode an unmatched request is an error. Otherwise it is served
+// the next unused interaction in recording order, which tolerates small
+// differences such as timestamps in tool output.
+type ReplayProvider struct {
+	strict        bool
+	name          string
+	model         string
+	contextWindow int
+
+	mu           sync.Mutex
+	interactions []cassetteInteraction
+	used         []bool
+}
+
+// NewReplayProvider loads the cassette at path.
+func NewReplayProvider(path string, strict bool) (*ReplayProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open cassette: %w", err)
+	}
+	defer f.Close()
+
+	r := &ReplayProvider{strict: strict}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec cassetteInteraction
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("cassette %s line %d: %w", path, n, err)
+		}
+		r.interactions = append(r.interactions, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read cassette: %w", err)
+	}
+	if len(r.interactions) == 0 {
+		return nil, fmt.Errorf("cassette %s is empty", path)
+	}
+	r.used = make([]bool, len(r.interactions))
+	first := r.interactions[0]
+	r.name, r.model, r.contextWindow = first.Provider, first.Model, first.ContextWindow
+	return r, nil
+}
+
+func (r *ReplayProvider) Name() string         { return r.name }
+func (r *ReplayProvider) Models() []string     { return []string{r.model} }
+func (r *ReplayProvider) DefaultModel() string { return r.model }
+func (r *ReplayProvider) ContextWindow() int   { return r.contextWindow }
+
+// Remaining returns how many recorded interactions have not been replayed.
+func (r *ReplayProvider) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, u := range r.used {
+		if !u {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *ReplayProvider) Chat(ctx context.Context, req *ChatRequest) (<-chan Event, error) {
+	key := cassetteKey(encodeCassetteRequest(req))
+	rec, err := r.next(key)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, len(rec.Events))
+	for i, ce := range rec.Events {
+		ev, err := decodeCassetteEvent(ce)
+		if err != nil {
+			return nil, fmt.Errorf("replay: %w", err)
+		}
+		events[i] = ev
+	}
+
+	ch := make(chan Event, 16)
+	go func() {
+		defer close(ch)
+		for _, ev := range events {
+			select {
+			case <-ctx.Done():
+				ch <- Event{Type: EventError, Error: ctx.Err()}
+				return
+			case ch <- ev:
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// next claims the first unused interaction recorded under key, falling back
+// to the first unused interaction overall when not strict. Errors leave the
+// key out: a hex digest can contain "500" and look like a retryable error.
+func (r *ReplayProvider) next(key string) (cassetteInteraction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, rec := range r.interactions {
+		if !r.used[i] && rec.Key == key {
+			r.used[i] = true
+			return rec, nil
+		}
+	}
+	if r.strict {
+		return cassetteInteraction{}, errors.New("replay: no recorded response matches this request (strict mode)")
+	}
+	for i, rec := range r.interactions {
+		if !r.used[i] {
+			r.used[i] = true
+			return rec, nil
+		}
+	}
+	return cassetteInteraction{}, errors.New("replay: cassette exhausted")
+}
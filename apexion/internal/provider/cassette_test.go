@@ -0,0 +1,184 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func drain(ch <-chan Event) []Event {
+	var events []Event
+	for ev := range ch {
+		events = append(events, ev)
+	}
+	return events
+}
+
+func userRequest(text string) *ChatRequest {
+	return &ChatRequest{
+		SystemPrompt: "today is monday",
+		Messages:     []Message{{Role: RoleUser, Content: []Content{{Type: ContentTypeText, Text: text}}}},
+		Tools:        []ToolSchema{{Name: "read_file"}, {Name: "glob"}},
+	}
+}
+
+func TestRecordReplay_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.cassette")
+	rec, err := NewRecordingProvider(&scriptedProvider{name: "deepseek"}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := drain(mustChat(t, rec, userRequest("hello")))
+	drain(mustChat(t, rec, userRequest("bye")))
+	_ = rec.Close()
+
+	rp, err := NewReplayProvider(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rp.Name() != "deepseek" || rp.DefaultModel() != "deepseek-model" || rp.ContextWindow() != 1000 {
+		t.Errorf("unexpected replay metadata %s %s %d", rp.Name(), rp.DefaultModel(), rp.ContextWindow())
+	}
+
+	// The system prompt is not part of the key, and tool order does not matter.
+	req := userRequest("hello")
+	req.SystemPrompt = "today is tuesday"
+	req.Tools[0], req.Tools[1] = req.Tools[1], req.Tools[0]
+	replayed := drain(mustChat(t, rp, req))
+
+	if len(replayed) != len(recorded) {
+		t.Fatalf("replayed %d events, recorded %d", len(replayed), len(recorded))
+	}
+	for i := range recorded {
+		if replayed[i].Type != recorded[i].Type || replayed[i].TextDelta != recorded[i].TextDelta {
+			t.Errorf("event %d: got %+v, want %+v", i, replayed[i], recorded[i])
+		}
+	}
+	if rp.Remaining() != 1 {
+		t.Errorf("expected 1 unused interaction, got %d", rp.Remaining())
+	}
+}
+
+func TestRecordReplay_ErrorsAndToolCalls(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.cassette")
+	inner := &scriptedProvider{name: "openai", err: errors.New("status 429 slow down")}
+	rec, err := NewRecordingProvider(inner, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.Chat(context.Background(), userRequest("hi")); err == nil {
+		t.Fatal("expected error from inner provider")
+	}
+	_ = rec.Close()
+
+	rp, err := NewReplayProvider(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := drain(mustChat(t, rp, userRequest("hi")))
+	if len(events) != 1 || events[0].Type != EventError || !strings.Contains(events[0].Error.Error(), "429") {
+		t.Errorf("expected the recorded error to replay, got %+v", events)
+	}
+
+	ce := encodeCassetteEvent(Event{Type: EventToolCallDone, ToolCall: &ToolCallRequest{ID: "c1", Name: "glob", Input: json.RawMessage(`{"pattern":`)}})
+	ev, err := decodeCassetteEvent(ce)
+	if err != nil || ev.ToolCall == nil || string(ev.ToolCall.Input) != `{"pattern":` {
+		t.Errorf("malformed tool input should round-trip verbatim, got %+v (%v)", ev.ToolCall, err)
+	}
+}
+
+func TestReplay_Unmatched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.cassette")
+	rec, err := NewRecordingProvider(&scriptedProvider{name: "deepseek"}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(mustChat(t, rec, userRequest("hello")))
+	_ = rec.Close()
+
+	strict, err := NewReplayProvider(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := strict.Chat(context.Background(), userRequest("something else")); err == nil || !strings.Contains(err.Error(), "strict") {
+		t.Errorf("expected strict mode to reject unmatched request, got %v", err)
+	}
+
+	lenient, err := NewReplayProvider(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := drain(mustChat(t, lenient, userRequest("something else")))
+	if len(events) == 0 || events[0].TextDelta != "from deepseek" {
+		t.Errorf("expected sequential fallback in lenient mode, got %+v", events)
+	}
+	if _, err := lenient.Chat(context.Background(), userRequest("again")); err == nil {
+		t.Error("expected an exhausted cassette to fail")
+	}
+}
+
+func mustChat(t *testing.T, p Provider, req *ChatRequest) <-chan Event {
+	t.Helper()
+	ch, err := p.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	return ch
+}
+
+// floodProvider streams text deltas until the context ends.
+type floodProvider struct{ scriptedProvider }
+
+func (p *floodProvider) Chat(ctx context.Context, _ *ChatRequest) (<-chan Event, error) {
+	ch := make(chan Event)
+	go func() {
+		defer close(ch)
+		for range 100 {
+			select {
+			case ch <- Event{Type: EventTextDelta, TextDelta: "x"}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func TestRecord_CancelledConsumerStillRecorded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.cassette")
+	rec, err := NewRecordingProvider(&floodProvider{scriptedProvider{name: "deepseek"}}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := rec.Chat(ctx, userRequest("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-events
+	cancel() // stop reading, as on Esc
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if data, _ := os.ReadFile(path); len(data) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the interaction was never written after the consumer stopped reading")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_ = rec.Close()
+	rp, err := NewReplayProvider(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rp.Remaining() != 1 {
+		t.Errorf("expected the partial interaction in the cassette, got %d", rp.Remaining())
+	}
+}
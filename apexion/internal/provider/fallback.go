@@ -0,0 +1,264 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// circuitFailureThreshold is how many consecutive retryable failures open
+	// a target's circuit.
+	circuitFailureThreshold = 3
+	// circuitCooldown is how long an open circuit skips its target before a
+	// single trial request is let through again.
+	circuitCooldown = 60 * time.Second
+)
+
+// FallbackTarget is one provider/model pair in a fallback chain.
+type FallbackTarget struct {
+	Provider Provider
+	Model    string // empty = the provider's default model
+}
+
+// Label returns "provider/model" for display.
+func (t FallbackTarget) Label() string {
+	return t.Provider.Name() + "/" + t.model()
+}
+
+func (t FallbackTarget) model() string {
+	if t.Model == "" {
+		return t.Provider.DefaultModel()
+	}
+	return t.Model
+}
+
+// Failover describes a switch from one target to the next.
+type Failover struct {
+	From   string // "provider/model" that failed or was skipped
+	To     string // "provider/model" now being tried
+	Reason string // error text, or "circuit open"
+}
+
+// FallbackProvider wraps an ordered chain of provider/model pairs. A request
+// goes to the first target whose circuit is closed; a retryable error before
+// any content arrives fails over to the next target. After
+// circuitFailureThreshold consecutive failures a target's circuit opens and it
+// is skipped for circuitCooldown.
+//
+// Name, DefaultModel and ContextWindow describe the primary (first) target,
+// so the rest of the agent keeps treating the configured provider as active.
+type FallbackProvider struct {
+	targets []FallbackTarget
+
+	// OnFailover, if set, is called on every failover.
+	OnFailover func(Failover)
+
+	mu       sync.Mutex
+	circuits []circuit
+	active   int // index of the target that served the last request
+	now      func() time.Time
+}
+
+// circuit is the breaker state of one target.
+type circuit struct {
+	failures  int
+	openUntil time.Time
+	announced bool // the open circuit has been reported via OnFailover
+}
+
+// NewFallbackProvider creates a FallbackProvider. targets[0] is the primary.
+func NewFallbackProvider(targets ...FallbackTarget) *FallbackProvider {
+	return &FallbackProvider{
+		targets:  targets,
+		circuits: make([]circuit, len(targets)),
+		now:      time.Now,
+	}
+}
+
+func (f *FallbackProvider) Name() string         { return f.targets[0].Provider.Name() }
+func (f *FallbackProvider) Models() []string     { return f.targets[0].Provider.Models() }
+func (f *FallbackProvider) DefaultModel() string { return f.targets[0].Provider.DefaultModel() }
+func (f *FallbackProvider) ContextWindow() int   { return f.targets[0].Provider.ContextWindow() }
+
+// Targets returns the chain, primary first.
+func (f *FallbackProvider) Targets() []FallbackTarget { return f.targets }
+
+// ActiveModel returns the model of the fallback target that served the last
+// request, or "" when it was served by the primary.
+func (f *FallbackProvider) ActiveModel() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.active == 0 {
+		return ""
+	}
+	return f.targets[f.active].model()
+}
+
+// ListModels delegates to the primary provider, or returns
+// ErrModelListUnsupported if it cannot list models.
+func (f *FallbackProvider) ListModels(ctx context.Context) ([]string, error) {
+	if ml, ok := f.targets[0].Provider.(ModelLister); ok {
+		return ml.ListModels(ctx)
+	}
+	return nil, ErrModelListUnsupported
+}
+
+// ContextWindowFor delegates to the primary provider.
+func (f *FallbackProvider) ContextWindowFor(model string) int {
+	if cw, ok := f.targets[0].Provider.(ModelContextWindower); ok {
+		return cw.ContextWindowFor(model)
+	}
+	return f.targets[0].Provider.ContextWindow()
+}
+
+// SupportsResponseSchema reports native support only if every target has it,
+// since any of them may end up serving the request.
+func (f *FallbackProvider) SupportsResponseSchema(model string) bool {
+	for _, t := range f.targets {
+		m := t.Model
+		if m == "" {
+			m = model
+		}
+		if !SupportsResponseSchema(t.Provider, m) {
+			return false
+		}
+	}
+	return true
+}
+
+// Chat sends the request down the chain until a target starts streaming.
+// If every target fails, the last error is returned (or delivered as the
+// stream's EventError, matching how that target reported it).
+func (f *FallbackProvider) Chat(ctx context.Context, req *ChatRequest) (<-chan Event, error) {
+	order := f.order()
+
+	var lastErr error
+	prev := -1
+	for n, i := range order {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if prev >= 0 {
+			f.failover(prev, i, lastErr.Error())
+		}
+		prev = i
+
+		ch, err := f.targets[i].Provider.Chat(ctx, f.requestFor(i, req))
+		last := n == len(order)-1
+		if err != nil {
+			f.record(i, err)
+			if last || !IsRetryableError(err) {
+				return nil, err
+			}
+			lastErr = err
+			continue
+		}
+
+		// Errors often surface as the first stream event (the request is
+		// sent lazily), so peek before committing to this target.
+		first, ok := <-ch
+		if ok && first.Type == EventError {
+			f.record(i, first.Error)
+			if !last && IsRetryableError(first.Error) {
+				lastErr = first.Error
+				continue
+			}
+		} else {
+			f.record(i, nil)
+		}
+		return prepend(first, ok, ch), nil
+	}
+	return nil, lastErr
+}
+
+// order returns target indexes to try: closed (or half-open) circuits first
+// in chain order. If every circuit is open, all targets are tried anyway.
+// Skipping an open circuit is reported once per opening.
+func (f *FallbackProvider) order() []int {
+	f.mu.Lock()
+	now := f.now()
+	var usable []int
+	skipped := -1
+	for i := range f.targets {
+		c := &f.circuits[i]
+		if now.Before(c.openUntil) {
+			if !c.announced && skipped < 0 {
+				c.announced = true
+				skipped = i
+			}
+			continue
+		}
+		usable = append(usable, i)
+	}
+	f.mu.Unlock()
+
+	if len(usable) == 0 {
+		usable = make([]int, len(f.targets))
+		for i := range usable {
+			usable[i] = i
+		}
+		return usable
+	}
+	if skipped >= 0 && skipped < usable[0] {
+		f.failover(skipped, usable[0], "circuit open")
+	}
+	return usable
+}
+
+// record updates the circuit of target i with the outcome of a request.
+func (f *FallbackProvider) record(i int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := &f.circuits[i]
+	if err == nil || !IsRetryableError(err) {
+		*c = circuit{}
+		if err == nil {
+			f.active = i
+		}
+		return
+	}
+	c.failures++
+	if c.failures >= circuitFailureThreshold {
+		c.openUntil = f.now().Add(circuitCooldown)
+		c.announced = false
+		c.failures = 0
+	}
+}
+
+func (f *FallbackProvider) failover(from, to int, reason string) {
+	if f.OnFailover != nil {
+		f.OnFailover(Failover{
+			From:   f.targets[from].Label(),
+			To:     f.targets[to].Label(),
+			Reason: reason,
+		})
+	}
+}
+
+// requestFor returns the request for target i. The primary honours the
+// caller's model choice; fallbacks always use their own configured model.
+func (f *FallbackProvider) requestFor(i int, req *ChatRequest) *ChatRequest {
+	if i == 0 {
+		return req
+	}
+	r := *req
+	r.Model = f.targets[i].Model
+	return &r
+}
+
+// prepend returns a channel yielding first (if ok) followed by the rest of ch.
+func prepend(first Event, ok bool, ch <-chan Event) <-chan Event {
+	out := make(chan Event, 16)
+	go func() {
+		defer close(out)
+		if !ok {
+			return
+		}
+		out <- first
+		for ev := range ch {
+			out <- ev
+		}
+	}()
+	return out
+}
@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// scriptedProvider fails with err (as a stream event when streamErr is set)
+// or replies with a single text delta.
+type scriptedProvider struct {
+	name      string
+	err       error
+	streamErr bool
+	calls     int
+	lastModel string
+}
+
+func (p *scriptedProvider) Name() string         { return p.name }
+func (p *scriptedProvider) Models() []string     { return []string{p.name + "-model"} }
+func (p *scriptedProvider) DefaultModel() string { return p.name + "-model" }
+func (p *scriptedProvider) ContextWindow() int   { return 1000 }
+
+func (p *scriptedProvider) Chat(_ context.Context, req *ChatRequest) (<-chan Event, error) {
+	p.calls++
+	p.lastModel = req.Model
+	if p.err != nil && !p.streamErr {
+		return nil, p.err
+	}
+	ch := make(chan Event, 2)
+	if p.err != nil {
+		ch <- Event{Type: EventError, Error: p.err}
+	} else {
+		ch <- Event{Type: EventTextDelta, TextDelta: "from " + p.name}
+		ch <- Event{Type: EventDone, Usage: &Usage{}}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func collectText(t *testing.T, ch <-chan Event) string {
+	t.Helper()
+	var text string
+	for ev := range ch {
+		if ev.Type == EventError {
+			t.Fatalf("unexpected error event: %v", ev.Error)
+		}
+		text += ev.TextDelta
+	}
+	return text
+}
+
+func TestFallbackProvider_FailsOverOnRetryableError(t *testing.T) {
+	tests := []struct {
+		name      string
+		streamErr bool
+	}{
+		{"chat error", false},
+		{"stream error", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &scriptedProvider{name: "deepseek", err: errors.New("status 429 rate limited"), streamErr: tt.streamErr}
+			backup := &scriptedProvider{name: "anthropic"}
+			fp := NewFallbackProvider(
+				FallbackTarget{Provider: primary},
+				FallbackTarget{Provider: backup, Model: "claude-x"},
+			)
+			var failovers []Failover
+			fp.OnFailover = func(f Failover) { failovers = append(failovers, f) }
+
+			ch, err := fp.Chat(context.Background(), &ChatRequest{Model: "deepseek-chat"})
+			if err != nil {
+				t.Fatalf("Chat: %v", err)
+			}
+			if got := collectText(t, ch); got != "from anthropic" {
+				t.Errorf("expected backup reply, got %q", got)
+			}
+			if backup.lastModel != "claude-x" {
+				t.Errorf("fallback should use its own model, got %q", backup.lastModel)
+			}
+			if len(failovers) != 1 || failovers[0].From != "deepseek/deepseek-model" || failovers[0].To != "anthropic/claude-x" {
+				t.Errorf("unexpected failovers %+v", failovers)
+			}
+			if fp.ActiveModel() != "claude-x" {
+				t.Errorf("expected active model claude-x, got %q", fp.ActiveModel())
+			}
+		})
+	}
+}
+
+func TestFallbackProvider_NonRetryableErrorIsReturned(t *testing.T) {
+	primary := &scriptedProvider{name: "openai", err: errors.New("status 400 bad request")}
+	backup := &scriptedProvider{name: "anthropic"}
+	fp := NewFallbackProvider(FallbackTarget{Provider: primary}, FallbackTarget{Provider: backup})
+
+	if _, err := fp.Chat(context.Background(), &ChatRequest{}); err == nil {
+		t.Fatal("expected the non-retryable error to be returned")
+	}
+	if backup.calls != 0 {
+		t.Errorf("backup should not be called on a non-retryable error")
+	}
+}
+
+func TestFallbackProvider_AllTargetsFail(t *testing.T) {
+	fp := NewFallbackProvider(
+		FallbackTarget{Provider: &scriptedProvider{name: "a", err: errors.New("503 unavailable")}},
+		FallbackTarget{Provider: &scriptedProvider{name: "b", err: errors.New("502 bad gateway")}},
+	)
+	_, err := fp.Chat(context.Background(), &ChatRequest{})
+	if err == nil || err.Error() != "502 bad gateway" {
+		t.Errorf("expected last target's error, got %v", err)
+	}
+}
+
+func TestFallbackProvider_CircuitOpensAndRecovers(t *testing.T) {
+	primary := &scriptedProvider{name: "deepseek", err: errors.New("status 429")}
+	backup := &scriptedProvider{name: "openai"}
+	fp := NewFallbackProvider(FallbackTarget{Provider: primary}, FallbackTarget{Provider: backup})
+	now := time.Unix(0, 0)
+	fp.now = func() time.Time { return now }
+	var reasons []string
+	fp.OnFailover = func(f Failover) { reasons = append(reasons, f.Reason) }
+
+	for range circuitFailureThreshold {
+		ch, err := fp.Chat(context.Background(), &ChatRequest{})
+		if err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+		collectText(t, ch)
+	}
+
+	// Circuit is open: the primary is skipped without being called, and the
+	// skip is announced once.
+	for range 2 {
+		ch, err := fp.Chat(context.Background(), &ChatRequest{})
+		if err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+		collectText(t, ch)
+	}
+	if primary.calls != circuitFailureThreshold {
+		t.Errorf("expected primary skipped while open, got %d calls", primary.calls)
+	}
+	if got := reasons[len(reasons)-1]; got != "circuit open" {
+		t.Errorf("expected circuit-open failover, got %q", got)
+	}
+	if n := len(reasons); n != circuitFailureThreshold+1 {
+		t.Errorf("expected open circuit announced once (%d failovers), got %d", circuitFailureThreshold+1, n)
+	}
+
+	// After the cooldown the primary gets a trial request again.
+	now = now.Add(circuitCooldown)
+	primary.err = nil
+	ch, err := fp.Chat(context.Background(), &ChatRequest{})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got := collectText(t, ch); got != "from deepseek" {
+		t.Errorf("expected primary after cooldown, got %q", got)
+	}
+	if fp.ActiveModel() != "" {
+		t.Errorf("expected empty active model when primary served, got %q", fp.ActiveModel())
+	}
+}
@@ -0,0 +1,502 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// defaultGeminiBaseURL is the native Gemini API endpoint (not the
+// OpenAI-compatibility layer under /v1beta/openai/).
+const defaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// GeminiProvider implements Provider against the native Gemini
+// generateContent / streamGenerateContent wire format.
+type GeminiProvider struct {
+	httpClient *http.Client
+	apiKey     string
+	baseURL    string
+	model      string
+	callSeq    atomic.Int64
+}
+
+// NewGeminiProvider creates a Gemini provider. httpClient may be nil to use
+// the default transport.
+func NewGeminiProvider(apiKey, baseURL, model string, httpClient *http.Client) *GeminiProvider {
+	baseURL = normalizeGeminiBaseURL(baseURL)
+	if model == "" {
+		model = "gemini-2.5-flash" // fallback; normally buildProvider passes the correct default
+	}
+	return &GeminiProvider{
+		httpClient: observedClient(httpClient),
+		apiKey:     apiKey,
+		baseURL:    baseURL,
+		model:      model,
+	}
+}
+
+// normalizeGeminiBaseURL maps the legacy OpenAI-compat base URL (which older
+// configs may still carry) to the native endpoint.
+func normalizeGeminiBaseURL(baseURL string) string {
+	if baseURL == "" {
+		return defaultGeminiBaseURL
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	baseURL = strings.TrimSuffix(baseURL, "/openai")
+	return baseURL
+}
+
+func (p *GeminiProvider) Name() string         { return "gemini" }
+func (p *GeminiProvider) Models() []string     { return []string{p.model} }
+func (p *GeminiProvider) DefaultModel() string { return p.model }
+
+func (p *GeminiProvider) ContextWindow() int {
+	switch {
+	case strings.Contains(p.model, "gemini-1.5-pro"):
+		return 2097152
+	case strings.Contains(p.model, "gemini-1.0"):
+		return 32768
+	default:
+		// gemini-1.5-flash, 2.x and 2.5 families all ship a 1M window.
+		return 1048576
+	}
+}
+
+// ── Wire types ───────────────────────────────────────────────────────────────
+
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	InlineData       *geminiInlineData       `json:"inlineData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	ID       string         `json:"id,omitempty"`
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     *float64              `json:"temperature,omitempty"`
+	TopP            *float64              `json:"topP,omitempty"`
+	MaxOutputTokens int                   `json:"maxOutputTokens,omitempty"`
+	ThinkingConfig  *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+
+	ResponseMimeType string         `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]any `json:"responseSchema,omitempty"`
+}
+
+type geminiThinkingConfig struct {
+	ThinkingBudget  int  `json:"thinkingBudget"`
+	IncludeThoughts bool `json:"includeThoughts"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback,omitempty"`
+	UsageMetadata *struct {
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	} `json:"usageMetadata,omitempty"`
+	Error *geminiError `json:"error,omitempty"`
+}
+
+type geminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// ── Chat ─────────────────────────────────────────────────────────────────────
+
+// SupportsResponseSchema reports native support via responseSchema.
+func (p *GeminiProvider) SupportsResponseSchema(string) bool { return true }
+
+func (p *GeminiProvider) Chat(ctx context.Context, req *ChatRequest) (<-chan Event, error) {
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+
+	body := geminiRequest{Contents: p.buildContents(req.Messages)}
+	if req.SystemPrompt != "" {
+		body.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: req.SystemPrompt}}}
+	}
+	if decls := p.buildTools(req.Tools); len(decls) > 0 {
+		body.Tools = []geminiTool{{FunctionDeclarations: decls}}
+	}
+	if req.Temperature != nil || req.TopP != nil || req.MaxTokens > 0 || req.ThinkingBudget > 0 || req.ResponseSchema != nil {
+		body.GenerationConfig = &geminiGenerationConfig{
+			Temperature:     req.Temperature,
+			TopP:            req.TopP,
+			MaxOutputTokens: req.MaxTokens,
+		}
+		if req.ThinkingBudget > 0 {
+			body.GenerationConfig.ThinkingConfig = &geminiThinkingConfig{
+				ThinkingBudget:  req.ThinkingBudget,
+				IncludeThoughts: true,
+			}
+		}
+		if req.ResponseSchema != nil {
+			body.GenerationConfig.ResponseMimeType = "application/json"
+			body.GenerationConfig.ResponseSchema = sanitizeGeminiSchema(req.ResponseSchema.Schema)
+		}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: encode request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse", p.baseURL, model)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("gemini: build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", p.apiKey)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("gemini request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, geminiStatusError(resp)
+	}
+
+	ch := make(chan Event, 16)
+	go p.processStream(ctx, resp.Body, ch)
+	return ch, nil
+}
+
+// geminiStatusError formats a non-200 response. The status code is kept in
+// the message so the agent's retry classifier recognises 429/5xx.
+func geminiStatusError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var envelope struct {
+		Error *geminiError `json:"error"`
+	}
+	if json.Unmarshal(raw, &envelope) == nil && envelope.Error != nil && envelope.Error.Message != "" {
+		return fmt.Errorf("gemini API error (status %d %s): %s", resp.StatusCode, envelope.Error.Status, envelope.Error.Message)
+	}
+	return fmt.Errorf("gemini API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+}
+
+// processStream reads the Gemini SSE stream and emits unified events.
+//
+// Gemini streaming key behavior:
+//   - each SSE data line is a full GenerateContentResponse chunk
+//   - function calls arrive as complete parts (args are not split across
+//     chunks), but a turn may spread several calls over multiple chunks
+//   - function calls carry no ID on older models, so one is synthesized
+//   - usageMetadata is cumulative; the last chunk holds the final totals
+func (p *GeminiProvider) processStream(ctx context.Context, body io.ReadCloser, ch chan<- Event) {
+	defer close(ch)
+	defer body.Close()
+
+	var calls []*ToolCallRequest
+	var finishReason string
+	usage := &Usage{}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		select {
+		case <-ctx.Done():
+			ch <- Event{Type: EventError, Error: ctx.Err()}
+			return
+		default:
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			ch <- Event{Type: EventError, Error: fmt.Errorf("gemini: decode stream chunk: %w", err)}
+			return
+		}
+		if chunk.Error != nil {
+			ch <- Event{Type: EventError, Error: fmt.Errorf("gemini API error (status %d %s): %s", chunk.Error.Code, chunk.Error.Status, chunk.Error.Message)}
+			return
+		}
+		if chunk.PromptFeedback != nil && chunk.PromptFeedback.BlockReason != "" {
+			ch <- Event{Type: EventError, Error: fmt.Errorf("gemini blocked the prompt: %s", chunk.PromptFeedback.BlockReason)}
+			return
+		}
+		if chunk.UsageMetadata != nil {
+			// promptTokenCount includes implicitly cached tokens.
+			usage.CacheReadTokens = chunk.UsageMetadata.CachedContentTokenCount
+			usage.InputTokens = chunk.UsageMetadata.PromptTokenCount - usage.CacheReadTokens
+			usage.OutputTokens = chunk.UsageMetadata.CandidatesTokenCount + chunk.UsageMetadata.ThoughtsTokenCount
+		}
+		if len(chunk.Candidates) == 0 {
+			continue
+		}
+		if r := chunk.Candidates[0].FinishReason; r != "" {
+			finishReason = r
+		}
+
+		for _, part := range chunk.Candidates[0].Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				args := part.FunctionCall.Args
+				if len(args) == 0 || string(args) == "null" {
+					args = json.RawMessage("{}")
+				}
+				id := part.FunctionCall.ID
+				if id == "" {
+					id = fmt.Sprintf("gemini_call_%d", p.callSeq.Add(1))
+				}
+				calls = append(calls, &ToolCallRequest{
+					ID:    id,
+					Name:  part.FunctionCall.Name,
+					Input: args,
+				})
+			case part.Thought:
+				if part.Text != "" {
+					ch <- Event{Type: EventThinkingDelta, ThinkingDelta: part.Text}
+				}
+			case part.Text != "":
+				ch <- Event{Type: EventTextDelta, TextDelta: part.Text}
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		ch <- Event{Type: EventError, Error: fmt.Errorf("gemini streaming error: %w", err)}
+		return
+	}
+
+	for _, call := range calls {
+		ch <- Event{Type: EventToolCallDone, ToolCall: call}
+	}
+	ch <- Event{Type: EventDone, Usage: usage, StopReason: geminiStopReason(finishReason, len(calls) > 0)}
+}
+
+// geminiStopReason maps a candidate finishReason to a StopReason. Gemini
+// reports STOP after function calls too, so those are told apart by
+// whether any were emitted.
+func geminiStopReason(reason string, calledTools bool) StopReason {
+	switch reason {
+	case "":
+		return ""
+	case "MAX_TOKENS":
+		return StopMaxTokens
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return StopContentFilter
+	}
+	if calledTools {
+		return StopToolUse
+	}
+	return StopEndTurn
+}
+
+// buildContents converts unified Message types to Gemini contents.
+// Tool results must name the function they answer, so tool_use IDs seen in
+// assistant turns are tracked to resolve the name for each tool_result.
+func (p *GeminiProvider) buildContents(msgs []Message) []geminiContent {
+	var contents []geminiContent
+	callNames := make(map[string]string)
+
+	for _, msg := range msgs {
+		var parts []geminiPart
+		role := "user"
+		if msg.Role == RoleAssistant {
+			role = "model"
+		}
+
+		for _, c := range msg.Content {
+			switch c.Type {
+			case ContentTypeText:
+				if c.Text != "" {
+					parts = append(parts, geminiPart{Text: c.Text})
+				}
+			// Thought summaries are output-only; they are not replayed.
+			case ContentTypeImage:
+				parts = append(parts, geminiPart{InlineData: &geminiInlineData{
+					MimeType: c.ImageMediaType,
+					Data:     c.ImageData,
+				}})
+			case ContentTypeToolUse:
+				callNames[c.ToolUseID] = c.ToolName
+				args := c.ToolInput
+				if len(args) == 0 {
+					args = json.RawMessage("{}")
+				}
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{
+					ID:   geminiWireID(c.ToolUseID),
+					Name: c.ToolName,
+					Args: args,
+				}})
+			case ContentTypeToolResult:
+				key := "output"
+				if c.IsError {
+					key = "error"
+				}
+				parts = append(parts, geminiPart{FunctionResponse: &geminiFunctionResponse{
+					ID:       geminiWireID(c.ToolUseID),
+					Name:     callNames[c.ToolUseID],
+					Response: map[string]any{key: c.ToolResult},
+				}})
+				if c.ImageData != "" {
+					parts = append(parts, geminiPart{InlineData: &geminiInlineData{
+						MimeType: c.ImageMediaType,
+						Data:     c.ImageData,
+					}})
+				}
+			}
+		}
+
+		if len(parts) == 0 {
+			continue
+		}
+		contents = append(contents, geminiContent{Role: role, Parts: parts})
+	}
+	return contents
+}
+
+// geminiWireID drops the IDs synthesized by processStream; only IDs issued
+// by the API itself are meaningful to send back.
+func geminiWireID(id string) string {
+	if strings.HasPrefix(id, "gemini_call_") {
+		return ""
+	}
+	return id
+}
+
+// buildTools converts unified ToolSchema to Gemini function declarations.
+func (p *GeminiProvider) buildTools(tools []ToolSchema) []geminiFunctionDeclaration {
+	var result []geminiFunctionDeclaration
+	for _, t := range tools {
+		decl := geminiFunctionDeclaration{
+			Name:        t.Name,
+			Description: t.Description,
+		}
+		if len(t.Parameters) > 0 {
+			decl.Parameters = map[string]any{
+				"type":       "object",
+				"properties": sanitizeGeminiProperties(t.Parameters),
+			}
+		}
+		result = append(result, decl)
+	}
+	return result
+}
+
+// geminiUnsupportedSchemaKeys lists JSON Schema keywords that the Gemini
+// OpenAPI-subset schema rejects.
+var geminiUnsupportedSchemaKeys = map[string]bool{
+	"$schema":              true,
+	"$id":                  true,
+	"$ref":                 true,
+	"additionalProperties": true,
+	"default":              true,
+	"examples":             true,
+}
+
+// sanitizeGeminiSchema returns a deep copy of a schema with keywords Gemini
+// does not accept removed. Property names under "properties" are preserved.
+func sanitizeGeminiSchema(schema map[string]any) map[string]any {
+	out := make(map[string]any, len(schema))
+	for k, val := range schema {
+		if geminiUnsupportedSchemaKeys[k] {
+			continue
+		}
+		switch k {
+		case "properties":
+			if props, ok := val.(map[string]any); ok {
+				out[k] = sanitizeGeminiProperties(props)
+				continue
+			}
+		case "items":
+			if sub, ok := val.(map[string]any); ok {
+				out[k] = sanitizeGeminiSchema(sub)
+				continue
+			}
+		case "anyOf", "oneOf", "allOf":
+			if list, ok := val.([]any); ok {
+				subs := make([]any, len(list))
+				for i, item := range list {
+					if sub, ok := item.(map[string]any); ok {
+						subs[i] = sanitizeGeminiSchema(sub)
+					} else {
+						subs[i] = item
+					}
+				}
+				out[k] = subs
+				continue
+			}
+		}
+		out[k] = val
+	}
+	return out
+}
+
+// sanitizeGeminiProperties sanitizes each property schema in a properties map.
+func sanitizeGeminiProperties(props map[string]any) map[string]any {
+	out := make(map[string]any, len(props))
+	for name, val := range props {
+		if sub, ok := val.(map[string]any); ok {
+			out[name] = sanitizeGeminiSchema(sub)
+		} else {
+			out[name] = val
+		}
+	}
+	return out
+}
@@ -0,0 +1,240 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// geminiSSE writes each chunk as an SSE data line, the way
+// streamGenerateContent?alt=sse does.
+func geminiSSE(w http.ResponseWriter, chunks ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, c := range chunks {
+		fmt.Fprintf(w, "data: %s\r\n\r\n", c)
+	}
+}
+
+func collectEvents(t *testing.T, ch <-chan Event) []Event {
+	t.Helper()
+	var events []Event
+	for ev := range ch {
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestGeminiProvider_StreamTextAndUsage(t *testing.T) {
+	var gotPath, gotKey string
+	var gotBody geminiRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path + "?" + r.URL.RawQuery
+		gotKey = r.Header.Get("x-goog-api-key")
+		raw, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(raw, &gotBody)
+		geminiSSE(w,
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]}}]}`,
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"lo"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":12,"candidatesTokenCount":3}}`,
+		)
+	}))
+	defer srv.Close()
+
+	p := NewGeminiProvider("test-key", srv.URL, "gemini-2.5-flash", nil)
+	ch, err := p.Chat(context.Background(), &ChatRequest{
+		SystemPrompt: "be brief",
+		Messages:     []Message{{Role: RoleUser, Content: []Content{{Type: ContentTypeText, Text: "hi"}}}},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	events := collectEvents(t, ch)
+
+	if gotPath != "/models/gemini-2.5-flash:streamGenerateContent?alt=sse" {
+		t.Errorf("unexpected request path %q", gotPath)
+	}
+	if gotKey != "test-key" {
+		t.Errorf("expected api key header, got %q", gotKey)
+	}
+	if gotBody.SystemInstruction == nil || gotBody.SystemInstruction.Parts[0].Text != "be brief" {
+		t.Errorf("system instruction not sent: %+v", gotBody.SystemInstruction)
+	}
+
+	var text strings.Builder
+	var done *Event
+	for i, ev := range events {
+		switch ev.Type {
+		case EventTextDelta:
+			text.WriteString(ev.TextDelta)
+		case EventDone:
+			done = &events[i]
+		case EventError:
+			t.Fatalf("unexpected error event: %v", ev.Error)
+		}
+	}
+	if text.String() != "Hello" {
+		t.Errorf("expected text 'Hello', got %q", text.String())
+	}
+	if done == nil || done.Usage == nil {
+		t.Fatal("expected EventDone with usage")
+	}
+	if done.Usage.InputTokens != 12 || done.Usage.OutputTokens != 3 {
+		t.Errorf("unexpected usage %+v", *done.Usage)
+	}
+}
+
+func TestGeminiProvider_StreamFunctionCalls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		geminiSSE(w,
+			`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"read_file","args":{"path":"a.go"}}}]}}]}`,
+			`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"id":"srv-1","name":"glob","args":{"pattern":"*.go"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":7,"thoughtsTokenCount":2}}`,
+		)
+	}))
+	defer srv.Close()
+
+	p := NewGeminiProvider("k", srv.URL, "gemini-2.5-pro", nil)
+	ch, err := p.Chat(context.Background(), &ChatRequest{
+		Messages: []Message{{Role: RoleUser, Content: []Content{{Type: ContentTypeText, Text: "go"}}}},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	var calls []*ToolCallRequest
+	var usage *Usage
+	var stop StopReason
+	for _, ev := range collectEvents(t, ch) {
+		switch ev.Type {
+		case EventToolCallDone:
+			calls = append(calls, ev.ToolCall)
+		case EventDone:
+			usage, stop = ev.Usage, ev.StopReason
+		}
+	}
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 tool calls, got %d", len(calls))
+	}
+	if calls[0].Name != "read_file" || string(calls[0].Input) != `{"path":"a.go"}` {
+		t.Errorf("unexpected first call %+v", calls[0])
+	}
+	if calls[0].ID == "" {
+		t.Error("expected synthesized ID for call without id")
+	}
+	if calls[1].ID != "srv-1" || calls[1].Name != "glob" {
+		t.Errorf("unexpected second call %+v", calls[1])
+	}
+	if usage == nil || usage.InputTokens != 5 || usage.OutputTokens != 9 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+	if stop != StopToolUse {
+		t.Errorf("expected tool_use stop reason, got %q", stop)
+	}
+}
+
+func TestGeminiProvider_HTTPErrorKeepsStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"code":429,"message":"quota exceeded","status":"RESOURCE_EXHAUSTED"}}`))
+	}))
+	defer srv.Close()
+
+	p := NewGeminiProvider("k", srv.URL, "gemini-2.5-flash", nil)
+	_, err := p.Chat(context.Background(), &ChatRequest{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "429") || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("error should carry status and message, got %q", err.Error())
+	}
+}
+
+func TestGeminiProvider_BuildContents(t *testing.T) {
+	p := &GeminiProvider{}
+	msgs := []Message{
+		{Role: RoleUser, Content: []Content{{Type: ContentTypeText, Text: "list files"}}},
+		{Role: RoleAssistant, Content: []Content{
+			{Type: ContentTypeText, Text: "ok"},
+			{Type: ContentTypeToolUse, ToolUseID: "gemini_call_1", ToolName: "glob", ToolInput: json.RawMessage(`{"pattern":"*"}`)},
+		}},
+		{Role: RoleUser, Content: []Content{
+			{Type: ContentTypeToolResult, ToolUseID: "gemini_call_1", ToolResult: "a.go", IsError: false},
+			{Type: ContentTypeImage, ImageData: "AAAA", ImageMediaType: "image/png"},
+		}},
+	}
+	contents := p.buildContents(msgs)
+	if len(contents) != 3 {
+		t.Fatalf("expected 3 contents, got %d", len(contents))
+	}
+	if contents[1].Role != "model" {
+		t.Errorf("assistant role should map to 'model', got %q", contents[1].Role)
+	}
+	fc := contents[1].Parts[1].FunctionCall
+	if fc == nil || fc.Name != "glob" || fc.ID != "" {
+		t.Errorf("unexpected function call part %+v", fc)
+	}
+	fr := contents[2].Parts[0].FunctionResponse
+	if fr == nil || fr.Name != "glob" || fr.Response["output"] != "a.go" {
+		t.Errorf("unexpected function response part %+v", fr)
+	}
+	if img := contents[2].Parts[1].InlineData; img == nil || img.MimeType != "image/png" {
+		t.Errorf("expected inline image part, got %+v", img)
+	}
+}
+
+func TestGeminiProvider_BuildToolsSanitizesSchema(t *testing.T) {
+	p := &GeminiProvider{}
+	decls := p.buildTools([]ToolSchema{{
+		Name:        "edit",
+		Description: "edit a file",
+		Parameters: map[string]any{
+			"default": map[string]any{"type": "string", "default": "x"},
+			"opts": map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties":           map[string]any{"n": map[string]any{"type": "integer"}},
+			},
+		},
+	}})
+	if len(decls) != 1 {
+		t.Fatalf("expected 1 declaration, got %d", len(decls))
+	}
+	props := decls[0].Parameters["properties"].(map[string]any)
+	def, ok := props["default"].(map[string]any)
+	if !ok {
+		t.Fatal("property named 'default' must be preserved")
+	}
+	if _, has := def["default"]; has {
+		t.Error("'default' keyword should be stripped from property schema")
+	}
+	if _, has := props["opts"].(map[string]any)["additionalProperties"]; has {
+		t.Error("additionalProperties should be stripped")
+	}
+}
+
+func TestGeminiProvider_ContextWindowAndBaseURL(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected int
+	}{
+		{"gemini-2.5-pro", 1048576},
+		{"gemini-2.5-flash", 1048576},
+		{"gemini-1.5-pro-latest", 2097152},
+	}
+	for _, tt := range tests {
+		p := &GeminiProvider{model: tt.model}
+		if got := p.ContextWindow(); got != tt.expected {
+			t.Errorf("Gemini ContextWindow(%q) = %d, want %d", tt.model, got, tt.expected)
+		}
+	}
+
+	if got := normalizeGeminiBaseURL("https://generativelanguage.googleapis.com/v1beta/openai/"); got != defaultGeminiBaseURL {
+		t.Errorf("legacy compat URL should normalize to native endpoint, got %q", got)
+	}
+	if got := normalizeGeminiBaseURL(""); got != defaultGeminiBaseURL {
+		t.Errorf("empty base URL should use default, got %q", got)
+	}
+}
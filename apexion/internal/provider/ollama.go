@@ -0,0 +1,491 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultOllamaBaseURL is where a local Ollama server listens by default.
+const defaultOllamaBaseURL = "http://localhost:11434"
+
+// defaultOllamaContextWindow is used when /api/show is unreachable or reports
+// no context size (Ollama's own default num_ctx).
+const defaultOllamaContextWindow = 4096
+
+// ollamaMetadataTimeout bounds /api/tags and /api/show lookups, which are
+// made from non-context-aware Provider methods.
+const ollamaMetadataTimeout = 5 * time.Second
+
+// OllamaProvider implements Provider against a native Ollama server
+// (/api/chat, /api/tags, /api/show).
+type OllamaProvider struct {
+	httpClient *http.Client
+	baseURL    string
+	model      string
+	callSeq    atomic.Int64
+
+	mu     sync.Mutex
+	numCtx map[string]int // model → context window, from /api/show
+}
+
+// NewOllamaProvider creates an Ollama provider. httpClient may be nil to use
+// the default transport.
+func NewOllamaProvider(baseURL, model string, httpClient *http.Client) *OllamaProvider {
+	if model == "" {
+		model = "llama3.1" // fallback; normally buildProvider passes the correct default
+	}
+	return &OllamaProvider{
+		httpClient: observedClient(httpClient),
+		baseURL:    normalizeOllamaBaseURL(baseURL),
+		model:      model,
+		numCtx:     make(map[string]int),
+	}
+}
+
+// normalizeOllamaBaseURL strips the /v1 suffix used by Ollama's
+// OpenAI-compatible endpoint so older configs keep working.
+func normalizeOllamaBaseURL(baseURL string) string {
+	if baseURL == "" {
+		return defaultOllamaBaseURL
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	baseURL = strings.TrimSuffix(baseURL, "/v1")
+	return baseURL
+}
+
+func (p *OllamaProvider) Name() string         { return "ollama" }
+func (p *OllamaProvider) DefaultModel() string { return p.model }
+
+// Models returns the models installed on the Ollama server. If the server
+// cannot be reached, only the configured model is returned.
+func (p *OllamaProvider) Models() []string {
+	ctx, cancel := context.WithTimeout(context.Background(), ollamaMetadataTimeout)
+	defer cancel()
+	models, err := p.ListModels(ctx)
+	if err != nil || len(models) == 0 {
+		return []string{p.model}
+	}
+	return models
+}
+
+// ContextWindow returns the real context window of the default model.
+func (p *OllamaProvider) ContextWindow() int {
+	return p.ContextWindowFor(p.model)
+}
+
+// ListModels queries /api/tags for the installed models.
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
+	var resp struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := p.getJSON(ctx, http.MethodGet, "/api/tags", nil, &resp); err != nil {
+		return nil, err
+	}
+	models := make([]string, 0, len(resp.Models))
+	for _, m := range resp.Models {
+		models = append(models, m.Name)
+	}
+	return models, nil
+}
+
+// ContextWindowFor returns the context window of the given model, read from
+// /api/show and cached. An explicit num_ctx parameter in the Modelfile wins
+// over the architecture's trained context length.
+func (p *OllamaProvider) ContextWindowFor(model string) int {
+	if model == "" {
+		model = p.model
+	}
+	p.mu.Lock()
+	if n, ok := p.numCtx[model]; ok {
+		p.mu.Unlock()
+		return n
+	}
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), ollamaMetadataTimeout)
+	defer cancel()
+	n, err := p.showNumCtx(ctx, model)
+	if err != nil || n <= 0 {
+		// Don't cache failures: the server may simply not be up yet.
+		return defaultOllamaContextWindow
+	}
+	p.mu.Lock()
+	p.numCtx[model] = n
+	p.mu.Unlock()
+	return n
+}
+
+// showNumCtx reads a model's context size from /api/show.
+func (p *OllamaProvider) showNumCtx(ctx context.Context, model string) (int, error) {
+	var resp struct {
+		Parameters string         `json:"parameters"`
+		ModelInfo  map[string]any `json:"model_info"`
+	}
+	if err := p.getJSON(ctx, http.MethodPost, "/api/show", map[string]string{"model": model}, &resp); err != nil {
+		return 0, err
+	}
+	if n := parseOllamaNumCtx(resp.Parameters); n > 0 {
+		return n, nil
+	}
+	for key, v := range resp.ModelInfo {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		if f, ok := v.(float64); ok && f > 0 {
+			return int(f), nil
+		}
+	}
+	return 0, nil
+}
+
+// parseOllamaNumCtx extracts "num_ctx <n>" from the Modelfile parameters
+// block returned by /api/show.
+func parseOllamaNumCtx(params string) int {
+	for _, line := range strings.Split(params, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "num_ctx" {
+			if n, err := strconv.Atoi(fields[1]); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// getJSON performs a small JSON request against the Ollama API.
+func (p *OllamaProvider) getJSON(ctx context.Context, method, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ollama %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ollamaStatusError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// ollamaStatusError formats a non-200 response, keeping the status code so
+// the agent's retry classifier can recognise 5xx errors.
+func ollamaStatusError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var envelope struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(raw, &envelope) == nil && envelope.Error != "" {
+		return fmt.Errorf("ollama API error (status %d): %s", resp.StatusCode, envelope.Error)
+	}
+	return fmt.Errorf("ollama API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+}
+
+// ── Wire types ───────────────────────────────────────────────────────────────
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []ollamaTool    `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+	Think    bool            `json:"think,omitempty"`
+	Format   map[string]any  `json:"format,omitempty"` // JSON Schema for structured output
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaTool struct {
+	Type     string         `json:"type"`
+	Function ollamaFunction `json:"function"`
+}
+
+type ollamaFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+type ollamaChatChunk struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// ── Chat ─────────────────────────────────────────────────────────────────────
+
+// SupportsResponseSchema reports native support via the format field.
+func (p *OllamaProvider) SupportsResponseSchema(string) bool { return true }
+
+func (p *OllamaProvider) Chat(ctx context.Context, req *ChatRequest) (<-chan Event, error) {
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+
+	// Pin num_ctx to the window we report, so compaction and the server agree
+	// on how much history fits.
+	options := map[string]any{"num_ctx": p.ContextWindowFor(model)}
+	if req.Temperature != nil {
+		options["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		options["top_p"] = *req.TopP
+	}
+	if req.MaxTokens > 0 {
+		options["num_predict"] = req.MaxTokens
+	}
+
+	body := ollamaChatRequest{
+		Model:    model,
+		Messages: p.buildMessages(req),
+		Tools:    p.buildTools(req.Tools),
+		Stream:   true,
+		Think:    req.ThinkingBudget > 0,
+		Options:  options,
+	}
+	if req.ResponseSchema != nil {
+		body.Format = req.ResponseSchema.Schema
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("ollama: encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/chat", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("ollama: build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("ollama request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, ollamaStatusError(resp)
+	}
+
+	ch := make(chan Event, 16)
+	go p.processStream(ctx, resp.Body, ch)
+	return ch, nil
+}
+
+// processStream reads Ollama's NDJSON stream and emits unified events.
+//
+// Ollama streaming key behavior:
+//   - one JSON object per line; the last has done=true and token counts
+//   - tool calls arrive whole (arguments is a JSON object, not a string)
+//     and carry no IDs, so IDs are synthesized
+func (p *OllamaProvider) processStream(ctx context.Context, body io.ReadCloser, ch chan<- Event) {
+	defer close(ch)
+	defer body.Close()
+
+	var calls []*ToolCallRequest
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		select {
+		case <-ctx.Done():
+			ch <- Event{Type: EventError, Error: ctx.Err()}
+			return
+		default:
+		}
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaChatChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			ch <- Event{Type: EventError, Error: fmt.Errorf("ollama: decode stream chunk: %w", err)}
+			return
+		}
+		if chunk.Error != "" {
+			ch <- Event{Type: EventError, Error: fmt.Errorf("ollama API error: %s", chunk.Error)}
+			return
+		}
+
+		if chunk.Message.Thinking != "" {
+			ch <- Event{Type: EventThinkingDelta, ThinkingDelta: chunk.Message.Thinking}
+		}
+		if chunk.Message.Content != "" {
+			ch <- Event{Type: EventTextDelta, TextDelta: chunk.Message.Content}
+		}
+		for _, tc := range chunk.Message.ToolCalls {
+			args := tc.Function.Arguments
+			if len(args) == 0 || string(args) == "null" {
+				args = json.RawMessage("{}")
+			}
+			calls = append(calls, &ToolCallRequest{
+				ID:    fmt.Sprintf("ollama_call_%d", p.callSeq.Add(1)),
+				Name:  tc.Function.Name,
+				Input: args,
+			})
+		}
+
+		if chunk.Done {
+			for _, call := range calls {
+				ch <- Event{Type: EventToolCallDone, ToolCall: call}
+			}
+			ch <- Event{
+				Type: EventDone,
+				Usage: &Usage{
+					InputTokens:  chunk.PromptEvalCount,
+					OutputTokens: chunk.EvalCount,
+				},
+				StopReason: ollamaStopReason(chunk.DoneReason, len(calls) > 0),
+			}
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		ch <- Event{Type: EventError, Error: fmt.Errorf("ollama streaming error: %w", err)}
+		return
+	}
+
+	// Stream ended without a done marker: flush what we have.
+	for _, call := range calls {
+		ch <- Event{Type: EventToolCallDone, ToolCall: call}
+	}
+	ch <- Event{Type: EventDone, Usage: &Usage{}}
+}
+
+// buildMessages converts unified Message types to Ollama chat messages.
+// Tool results become separate "tool" role messages naming the tool.
+func (p *OllamaProvider) buildMessages(req *ChatRequest) []ollamaMessage {
+	var out []ollamaMessage
+	if req.SystemPrompt != "" {
+		out = append(out, ollamaMessage{Role: "system", Content: req.SystemPrompt})
+	}
+
+	callNames := make(map[string]string)
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case RoleUser:
+			var texts []string
+			var images []string
+			for _, c := range msg.Content {
+				switch c.Type {
+				case ContentTypeText:
+					texts = append(texts, c.Text)
+				case ContentTypeImage:
+					images = append(images, c.ImageData)
+				case ContentTypeToolResult:
+					out = append(out, ollamaMessage{
+						Role:     "tool",
+						Content:  c.ToolResult,
+						ToolName: callNames[c.ToolUseID],
+					})
+					if c.ImageData != "" {
+						images = append(images, c.ImageData)
+					}
+				}
+			}
+			if len(texts) > 0 || len(images) > 0 {
+				content := strings.Join(texts, "\n")
+				if content == "" {
+					content = "Image content:"
+				}
+				out = append(out, ollamaMessage{Role: "user", Content: content, Images: images})
+			}
+
+		case RoleAssistant:
+			m := ollamaMessage{Role: "assistant"}
+			for _, c := range msg.Content {
+				switch c.Type {
+				case ContentTypeText:
+					m.Content += c.Text
+				case ContentTypeToolUse:
+					callNames[c.ToolUseID] = c.ToolName
+					var tc ollamaToolCall
+					tc.Function.Name = c.ToolName
+					tc.Function.Arguments = c.ToolInput
+					if len(tc.Function.Arguments) == 0 {
+						tc.Function.Arguments = json.RawMessage("{}")
+					}
+					m.ToolCalls = append(m.ToolCalls, tc)
+				}
+			}
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// buildTools converts unified ToolSchema to Ollama tool definitions.
+func (p *OllamaProvider) buildTools(tools []ToolSchema) []ollamaTool {
+	var result []ollamaTool
+	for _, t := range tools {
+		result = append(result, ollamaTool{
+			Type: "function",
+			Function: ollamaFunction{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters: map[string]any{
+					"type":       "object",
+					"properties": t.Parameters,
+				},
+			},
+		})
+	}
+	return result
+}
+
+// ollamaStopReason maps done_reason to a StopReason. Ollama reports "stop"
+// after tool calls as well, and "length" when num_predict was reached.
+func ollamaStopReason(reason string, calledTools bool) StopReason {
+	switch {
+	case reason == "length":
+		return StopMaxTokens
+	case calledTools:
+		return StopToolUse
+	case reason == "":
+		return ""
+	default:
+		return StopEndTurn
+	}
+}
@@ -0,0 +1,201 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newOllamaStub serves /api/tags, /api/show and /api/chat like a local
+// Ollama server. chatLines are written as NDJSON for /api/chat.
+func newOllamaStub(t *testing.T, chatLines []string, gotChat *ollamaChatRequest) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var showCalls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			_, _ = w.Write([]byte(`{"models":[{"name":"llama3.1:8b"},{"name":"qwen2.5-coder:32b"}]}`))
+		case "/api/show":
+			showCalls.Add(1)
+			var req struct {
+				Model string `json:"model"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			switch req.Model {
+			case "qwen2.5-coder:32b":
+				_, _ = w.Write([]byte(`{"parameters":"stop \"<|im_end|>\"\nnum_ctx 32768","model_info":{"qwen2.context_length":131072}}`))
+			case "llama3.1:8b":
+				_, _ = w.Write([]byte(`{"parameters":"","model_info":{"general.architecture":"llama","llama.context_length":131072}}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error":"model not found"}`))
+			}
+		case "/api/chat":
+			if gotChat != nil {
+				raw, _ := io.ReadAll(r.Body)
+				_ = json.Unmarshal(raw, gotChat)
+			}
+			for _, l := range chatLines {
+				fmt.Fprintln(w, l)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return srv, &showCalls
+}
+
+func TestOllamaProvider_ListModels(t *testing.T) {
+	srv, _ := newOllamaStub(t, nil, nil)
+	defer srv.Close()
+
+	p := NewOllamaProvider(srv.URL, "llama3.1:8b", nil)
+	models := p.Models()
+	if len(models) != 2 || models[0] != "llama3.1:8b" || models[1] != "qwen2.5-coder:32b" {
+		t.Errorf("unexpected models %v", models)
+	}
+
+	var _ ModelLister = p
+	var _ ModelContextWindower = p
+}
+
+func TestOllamaProvider_ModelsFallbackWhenUnreachable(t *testing.T) {
+	srv, _ := newOllamaStub(t, nil, nil)
+	url := srv.URL
+	srv.Close()
+
+	p := NewOllamaProvider(url, "llama3.1:8b", nil)
+	models := p.Models()
+	if len(models) != 1 || models[0] != "llama3.1:8b" {
+		t.Errorf("expected fallback to configured model, got %v", models)
+	}
+	if got := p.ContextWindow(); got != defaultOllamaContextWindow {
+		t.Errorf("expected default window when unreachable, got %d", got)
+	}
+}
+
+func TestOllamaProvider_ContextWindowFromShow(t *testing.T) {
+	srv, showCalls := newOllamaStub(t, nil, nil)
+	defer srv.Close()
+
+	p := NewOllamaProvider(srv.URL, "llama3.1:8b", nil)
+	if got := p.ContextWindow(); got != 131072 {
+		t.Errorf("expected context_length from model_info, got %d", got)
+	}
+	if got := p.ContextWindowFor("qwen2.5-coder:32b"); got != 32768 {
+		t.Errorf("num_ctx parameter should win over model_info, got %d", got)
+	}
+	_ = p.ContextWindow()
+	if n := showCalls.Load(); n != 2 {
+		t.Errorf("expected /api/show results to be cached (2 calls), got %d", n)
+	}
+}
+
+func TestOllamaProvider_StreamTextAndToolCalls(t *testing.T) {
+	var got ollamaChatRequest
+	srv, _ := newOllamaStub(t, []string{
+		`{"message":{"role":"assistant","content":"Look"},"done":false}`,
+		`{"message":{"role":"assistant","content":"ing","tool_calls":[{"function":{"name":"read_file","arguments":{"path":"main.go"}}}]},"done":false}`,
+		`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":42,"eval_count":9}`,
+	}, &got)
+	defer srv.Close()
+
+	p := NewOllamaProvider(srv.URL+"/v1", "qwen2.5-coder:32b", nil)
+	ch, err := p.Chat(context.Background(), &ChatRequest{
+		SystemPrompt: "sys",
+		Messages:     []Message{{Role: RoleUser, Content: []Content{{Type: ContentTypeText, Text: "hi"}}}},
+		Tools:        []ToolSchema{{Name: "read_file", Description: "read", Parameters: map[string]any{"path": map[string]any{"type": "string"}}}},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	var text strings.Builder
+	var calls []*ToolCallRequest
+	var usage *Usage
+	var stop StopReason
+	for ev := range ch {
+		switch ev.Type {
+		case EventTextDelta:
+			text.WriteString(ev.TextDelta)
+		case EventToolCallDone:
+			calls = append(calls, ev.ToolCall)
+		case EventDone:
+			usage, stop = ev.Usage, ev.StopReason
+		case EventError:
+			t.Fatalf("unexpected error: %v", ev.Error)
+		}
+	}
+
+	if !got.Stream || got.Model != "qwen2.5-coder:32b" {
+		t.Errorf("unexpected request %+v", got)
+	}
+	if got.Options["num_ctx"] != float64(32768) {
+		t.Errorf("expected num_ctx pinned to real window, got %v", got.Options["num_ctx"])
+	}
+	if len(got.Tools) != 1 || got.Tools[0].Function.Name != "read_file" {
+		t.Errorf("tools not forwarded: %+v", got.Tools)
+	}
+	if text.String() != "Looking" {
+		t.Errorf("expected text 'Looking', got %q", text.String())
+	}
+	if len(calls) != 1 || calls[0].Name != "read_file" || string(calls[0].Input) != `{"path":"main.go"}` || calls[0].ID == "" {
+		t.Errorf("unexpected tool calls %+v", calls)
+	}
+	if usage == nil || usage.InputTokens != 42 || usage.OutputTokens != 9 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+	if stop != StopToolUse {
+		t.Errorf("expected tool_use stop reason, got %q", stop)
+	}
+}
+
+func TestOllamaProvider_BuildMessagesToolRoundTrip(t *testing.T) {
+	p := &OllamaProvider{}
+	msgs := p.buildMessages(&ChatRequest{
+		SystemPrompt: "sys",
+		Messages: []Message{
+			{Role: RoleAssistant, Content: []Content{
+				{Type: ContentTypeToolUse, ToolUseID: "ollama_call_1", ToolName: "glob", ToolInput: json.RawMessage(`{"pattern":"*"}`)},
+			}},
+			{Role: RoleUser, Content: []Content{
+				{Type: ContentTypeToolResult, ToolUseID: "ollama_call_1", ToolResult: "a.go"},
+			}},
+		},
+	})
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(msgs))
+	}
+	if msgs[0].Role != "system" {
+		t.Errorf("expected system message first, got %q", msgs[0].Role)
+	}
+	if len(msgs[1].ToolCalls) != 1 || msgs[1].ToolCalls[0].Function.Name != "glob" {
+		t.Errorf("unexpected assistant tool calls %+v", msgs[1].ToolCalls)
+	}
+	if msgs[2].Role != "tool" || msgs[2].ToolName != "glob" || msgs[2].Content != "a.go" {
+		t.Errorf("unexpected tool message %+v", msgs[2])
+	}
+}
+
+func TestParseOllamaNumCtx(t *testing.T) {
+	tests := []struct {
+		params   string
+		expected int
+	}{
+		{"num_ctx 8192", 8192},
+		{"temperature 0.7\nnum_ctx    16384\n", 16384},
+		{"stop \"<eos>\"", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := parseOllamaNumCtx(tt.params); got != tt.expected {
+			t.Errorf("parseOllamaNumCtx(%q) = %d, want %d", tt.params, got, tt.expected)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/openai/openai-go"
@@ -21,11 +22,16 @@ type OpenAIProvider struct {
 	baseURL string
 }
 
-func NewOpenAIProvider(apiKey, baseURL, model string) *OpenAIProvider {
-	opts := []option.RequestOption{option.WithAPIKey(apiKey)}
+// NewOpenAIProvider creates a provider for an OpenAI-compatible API.
+// httpClient may be nil to use the SDK's default client.
+func NewOpenAIProvider(apiKey, baseURL, model string, httpClient *http.Client) *OpenAIProvider {
+	opts := []option.RequestOption{option.WithAPIKey(apiKey), option.WithMiddleware(observeRateLimits)}
 	if baseURL != "" {
 		opts = append(opts, option.WithBaseURL(baseURL))
 	}
+	if httpClient != nil {
+		opts = append(opts, option.WithHTTPClient(httpClient))
+	}
 	name := "openai"
 	if baseURL != "" {
 		switch {
@@ -79,6 +85,11 @@ func (p *OpenAIProvider) ContextWindow() int {
 	}
 }
 
+// SupportsResponseSchema reports native json_schema support. Compatible
+// vendors differ in what response_format they accept, so only the OpenAI
+// API itself is trusted with it.
+func (p *OpenAIProvider) SupportsResponseSchema(string) bool { return p.name == "openai" }
+
 func (p *OpenAIProvider) Chat(ctx context.Context, req *ChatRequest) (<-chan Event, error) {
 	msgs := p.buildMessages(req)
 	tools := p.buildTools(req.Tools)
@@ -101,6 +112,18 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req *ChatRequest) (<-chan Eve
 	if req.TopP != nil {
 		params.TopP = openai.Float(*req.TopP)
 	}
+	if rs := req.ResponseSchema; rs != nil && p.SupportsResponseSchema(model) {
+		format := shared.ResponseFormatJSONSchemaJSONSchemaParam{
+			Name:   rs.Name,
+			Schema: rs.Schema,
+		}
+		if rs.Description != "" {
+			format.Description = openai.String(rs.Description)
+		}
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{JSONSchema: format},
+		}
+	}
 
 	stream := p.client.Chat.Completions.NewStreaming(ctx, params)
 
@@ -140,11 +163,8 @@ func (p *OpenAIProvider) processStream(ctx context.Context, stream *ssestream.St
 			// Final chunk may only carry usage.
 			if chunk.Usage.PromptTokens > 0 || chunk.Usage.CompletionTokens > 0 {
 				ch <- Event{
-					Type: EventDone,
-					Usage: &Usage{
-						InputTokens:  int(chunk.Usage.PromptTokens),
-						OutputTokens: int(chunk.Usage.CompletionTokens),
-					},
+					Type:  EventDone,
+					Usage: openAIUsage(chunk.Usage),
 				}
 			}
 			continue
@@ -153,15 +173,11 @@ func (p *OpenAIProvider) processStream(ctx context.Context, stream *ssestream.St
 		choice := chunk.Choices[0]
 		delta := choice.Delta
 
-		// Skip reasoning_content from models like DeepSeek (not in SDK struct,
-		// extract from raw JSON). We silently discard it so thinking doesn't
-		// leak into the visible output.
-		if delta.Content == "" {
-			// Check if this chunk only carries reasoning_content (no visible text).
-			// No action needed — just don't emit it as text.
-			if rc := extractReasoningContent(delta.RawJSON()); rc != "" {
-				continue
-			}
+		// reasoning_content from models like DeepSeek is not in the SDK struct;
+		// extract it from raw JSON and surface it as thinking, separate from
+		// the visible answer.
+		if rc := extractReasoningContent(delta.RawJSON()); rc != "" {
+			ch <- Event{Type: EventThinkingDelta, ThinkingDelta: rc}
 		}
 
 		// Text delta
@@ -206,11 +222,9 @@ func (p *OpenAIProvider) processStream(ctx context.Context, stream *ssestream.St
 				}
 			}
 			ch <- Event{
-				Type: EventDone,
-				Usage: &Usage{
-					InputTokens:  int(chunk.Usage.PromptTokens),
-					OutputTokens: int(chunk.Usage.CompletionTokens),
-				},
+				Type:       EventDone,
+				Usage:      openAIUsage(chunk.Usage),
+				StopReason: openAIStopReason(string(choice.FinishReason)),
 			}
 			return
 		}
@@ -240,6 +254,28 @@ func (p *OpenAIProvider) processStream(ctx context.Context, stream *ssestream.St
 	ch <- Event{Type: EventDone, Usage: &Usage{}}
 }
 
+// toolImageLabel introduces an image returned by a tool call on APIs whose
+// tool results cannot hold images.
+func toolImageLabel(callID string) string {
+	return fmt.Sprintf("Image returned by tool call %s:", callID)
+}
+
+// openAIStopReason maps a Chat Completions finish_reason to a StopReason.
+func openAIStopReason(reason string) StopReason {
+	switch reason {
+	case "length":
+		return StopMaxTokens
+	case "tool_calls", "function_call":
+		return StopToolUse
+	case "content_filter":
+		return StopContentFilter
+	case "":
+		return ""
+	default:
+		return StopEndTurn
+	}
+}
+
 // buildMessages converts unified Message types to OpenAI API params.
 func (p *OpenAIProvider) buildMessages(req *ChatRequest) []openai.ChatCompletionMessageParamUnion {
 	var params []openai.ChatCompletionMessageParamUnion
@@ -268,8 +304,27 @@ func (p *OpenAIProvider) buildMessages(req *ChatRequest) []openai.ChatCompletion
 			}
 
 			// Emit tool results first (they must be separate messages).
+			// Tool messages are text-only, so images the tools returned
+			// follow in one user message, each labelled with its call.
+			var toolImages []openai.ChatCompletionContentPartUnionParam
 			for _, c := range toolResults {
 				params = append(params, openai.ToolMessage(c.ToolResult, c.ToolUseID))
+				if c.ImageData != "" {
+					toolImages = append(toolImages,
+						openai.TextContentPart(toolImageLabel(c.ToolUseID)),
+						openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+							URL: fmt.Sprintf("data:%s;base64,%s", c.ImageMediaType, c.ImageData),
+						}))
+				}
+			}
+			if len(toolImages) > 0 {
+				params = append(params, openai.ChatCompletionMessageParamUnion{
+					OfUser: &openai.ChatCompletionUserMessageParam{
+						Content: openai.ChatCompletionUserMessageParamContentUnion{
+							OfArrayOfContentParts: toolImages,
+						},
+					},
+				})
 			}
 
 			// If we have images, create a multipart user message.
@@ -306,6 +361,8 @@ func (p *OpenAIProvider) buildMessages(req *ChatRequest) []openai.ChatCompletion
 		case RoleAssistant:
 			var text string
 			var toolCalls []openai.ChatCompletionMessageToolCallParam
+			// Thinking blocks are not replayed: OpenAI-compatible APIs either
+			// ignore reasoning in history or (DeepSeek) reject it.
 			for _, c := range msg.Content {
 				switch c.Type {
 				case ContentTypeText:
@@ -362,3 +419,14 @@ func extractReasoningContent(rawJSON string) string {
 	}
 	return raw.ReasoningContent
 }
+
+// openAIUsage converts chat completion usage. prompt_tokens includes cached
+// tokens, which are split out so they can be priced separately.
+func openAIUsage(u openai.CompletionUsage) *Usage {
+	cached := int(u.PromptTokensDetails.CachedTokens)
+	return &Usage{
+		InputTokens:     int(u.PromptTokens) - cached,
+		OutputTokens:    int(u.CompletionTokens),
+		CacheReadTokens: cached,
+	}
+}
@@ -0,0 +1,346 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/ssestream"
+	"github.com/openai/openai-go/responses"
+	"github.com/openai/openai-go/shared"
+)
+
+// OpenAIResponsesProvider speaks the OpenAI Responses API (/v1/responses)
+// instead of Chat Completions. Requests are stateless (store=false): the
+// encrypted reasoning items the API returns are kept in history as
+// ContentTypeReasoning and sent back with the next request, so reasoning
+// models keep their chain of thought across tool-call rounds and across
+// saved and resumed sessions.
+type OpenAIResponsesProvider struct {
+	*OpenAIProvider
+}
+
+// NewOpenAIResponsesProvider creates a Responses API provider. Naming and
+// context windows follow NewOpenAIProvider.
+func NewOpenAIResponsesProvider(apiKey, baseURL, model string, httpClient *http.Client) *OpenAIResponsesProvider {
+	return &OpenAIResponsesProvider{OpenAIProvider: NewOpenAIProvider(apiKey, baseURL, model, httpClient)}
+}
+
+func (p *OpenAIResponsesProvider) Chat(ctx context.Context, req *ChatRequest) (<-chan Event, error) {
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+
+	params := responses.ResponseNewParams{
+		Model: model,
+		Input: responses.ResponseNewParamsInputUnion{OfInputItemList: buildResponsesInput(req.Messages)},
+		Store: openai.Bool(false),
+	}
+	if req.SystemPrompt != "" {
+		params.Instructions = openai.String(req.SystemPrompt)
+	}
+	if req.MaxTokens > 0 {
+		params.MaxOutputTokens = openai.Int(int64(req.MaxTokens))
+	}
+	if len(req.Tools) > 0 {
+		params.Tools = buildResponsesTools(req.Tools)
+	}
+	if isOpenAIReasoningModel(model) {
+		// Reasoning models reject sampling parameters.
+		params.Reasoning = shared.ReasoningParam{
+			Summary: shared.ReasoningSummaryAuto,
+			Effort:  reasoningEffort(req.ThinkingBudget),
+		}
+		params.Include = []responses.ResponseIncludable{responses.ResponseIncludableReasoningEncryptedContent}
+	} else {
+		if req.Temperature != nil {
+			params.Temperature = openai.Float(*req.Temperature)
+		}
+		if req.TopP != nil {
+			params.TopP = openai.Float(*req.TopP)
+		}
+	}
+	if rs := req.ResponseSchema; rs != nil && p.SupportsResponseSchema(model) {
+		format := &responses.ResponseFormatTextJSONSchemaConfigParam{
+			Name:   rs.Name,
+			Schema: rs.Schema,
+		}
+		if rs.Description != "" {
+			format.Description = openai.String(rs.Description)
+		}
+		params.Text = responses.ResponseTextConfigParam{
+			Format: responses.ResponseFormatTextConfigUnionParam{OfJSONSchema: format},
+		}
+	}
+
+	stream := p.client.Responses.NewStreaming(ctx, params)
+
+	ch := make(chan Event, 16)
+	go p.processResponsesStream(ctx, stream, ch)
+	return ch, nil
+}
+
+// processResponsesStream reads the Responses SSE stream and emits unified
+// events. Unlike Chat Completions, every output item (reasoning, message,
+// function call) is delivered whole in response.output_item.done, so tool
+// call arguments need no reassembly.
+func (p *OpenAIResponsesProvider) processResponsesStream(ctx context.Context, stream *ssestream.Stream[responses.ResponseStreamEventUnion], ch chan<- Event) {
+	defer close(ch)
+
+	calledTools := false
+	for stream.Next() {
+		select {
+		case <-ctx.Done():
+			ch <- Event{Type: EventError, Error: ctx.Err()}
+			return
+		default:
+		}
+
+		ev := stream.Current()
+		switch ev.Type {
+		case "response.output_text.delta":
+			ch <- Event{Type: EventTextDelta, TextDelta: ev.Delta.OfString}
+
+		case "response.reasoning_summary_part.added":
+			if ev.SummaryIndex > 0 {
+				ch <- Event{Type: EventThinkingDelta, ThinkingDelta: "\n\n"}
+			}
+
+		case "response.reasoning_summary_text.delta":
+			ch <- Event{Type: EventThinkingDelta, ThinkingDelta: ev.Delta.OfString}
+
+		case "response.output_item.done":
+			item := ev.Item
+			switch item.Type {
+			case "reasoning":
+				summary := make([]string, 0, len(item.Summary))
+				for _, s := range item.Summary {
+					summary = append(summary, s.Text)
+				}
+				ch <- Event{Type: EventThinkingDelta, ReasoningItem: &Content{
+					Type:        ContentTypeReasoning,
+					Text:        strings.Join(summary, "\n\n"),
+					Signature:   item.EncryptedContent,
+					ReasoningID: item.ID,
+				}}
+			case "function_call":
+				args := item.Arguments
+				if args == "" {
+					args = "{}"
+				}
+				calledTools = true
+				ch <- Event{
+					Type: EventToolCallDone,
+					ToolCall: &ToolCallRequest{
+						ID:    item.CallID,
+						Name:  item.Name,
+						Input: json.RawMessage(args),
+					},
+				}
+			}
+
+		case "response.completed", "response.incomplete":
+			ch <- Event{
+				Type:       EventDone,
+				Usage:      responsesUsage(ev.Response.Usage),
+				StopReason: responsesStopReason(ev.Response.IncompleteDetails.Reason, calledTools),
+			}
+			return
+
+		case "response.failed":
+			e := ev.Response.Error
+			ch <- Event{Type: EventError, Error: fmt.Errorf("openai responses error: %s: %s", e.Code, e.Message)}
+			return
+
+		case "error":
+			ch <- Event{Type: EventError, Error: fmt.Errorf("openai responses error: %s: %s", ev.Code, ev.Message)}
+			return
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		ch <- Event{Type: EventError, Error: fmt.Errorf("openai streaming error: %w", err)}
+		return
+	}
+	ch <- Event{Type: EventDone, Usage: &Usage{}}
+}
+
+// buildResponsesInput converts history to Responses input items. Tool
+// results become function_call_output items; reasoning items are replayed
+// in place so each precedes the function calls it led to.
+func buildResponsesInput(msgs []Message) responses.ResponseInputParam {
+	var items responses.ResponseInputParam
+	for _, msg := range msgs {
+		switch msg.Role {
+		case RoleUser:
+			var parts, toolImages responses.ResponseInputMessageContentListParam
+			for _, c := range msg.Content {
+				switch c.Type {
+				case ContentTypeToolResult:
+					items = append(items, responses.ResponseInputItemUnionParam{
+						OfFunctionCallOutput: &responses.ResponseInputItemFunctionCallOutputParam{
+							CallID: c.ToolUseID,
+							Output: c.ToolResult,
+						},
+					})
+					// function_call_output is text-only; the image follows
+					// in a user message.
+					if c.ImageData != "" {
+						toolImages = append(toolImages,
+							responses.ResponseInputContentUnionParam{
+								OfInputText: &responses.ResponseInputTextParam{Text: toolImageLabel(c.ToolUseID)},
+							},
+							responses.ResponseInputContentUnionParam{
+								OfInputImage: &responses.ResponseInputImageParam{
+									ImageURL: openai.String(fmt.Sprintf("data:%s;base64,%s", c.ImageMediaType, c.ImageData)),
+									Detail:   responses.ResponseInputImageDetailAuto,
+								},
+							})
+					}
+				case ContentTypeText:
+					parts = append(parts, responses.ResponseInputContentUnionParam{
+						OfInputText: &responses.ResponseInputTextParam{Text: c.Text},
+					})
+				case ContentTypeImage:
+					dataURI := fmt.Sprintf("data:%s;base64,%s", c.ImageMediaType, c.ImageData)
+					parts = append(parts, responses.ResponseInputContentUnionParam{
+						OfInputImage: &responses.ResponseInputImageParam{
+							ImageURL: openai.String(dataURI),
+							Detail:   responses.ResponseInputImageDetailAuto,
+						},
+					})
+				}
+			}
+			parts = append(toolImages, parts...)
+			if len(parts) > 0 {
+				items = append(items, responses.ResponseInputItemUnionParam{
+					OfMessage: &responses.EasyInputMessageParam{
+						Role:    responses.EasyInputMessageRoleUser,
+						Content: responses.EasyInputMessageContentUnionParam{OfInputItemContentList: parts},
+					},
+				})
+			}
+
+		case RoleAssistant:
+			// Thinking blocks from other providers cannot be replayed here;
+			// only reasoning items produced by this API are.
+			for _, c := range msg.Content {
+				switch c.Type {
+				case ContentTypeReasoning:
+					summary := []responses.ResponseReasoningItemSummaryParam{}
+					if c.Text != "" {
+						summary = append(summary, responses.ResponseReasoningItemSummaryParam{Text: c.Text})
+					}
+					item := &responses.ResponseReasoningItemParam{ID: c.ReasoningID, Summary: summary}
+					if c.Signature != "" {
+						item.EncryptedContent = openai.String(c.Signature)
+					}
+					items = append(items, responses.ResponseInputItemUnionParam{OfReasoning: item})
+				case ContentTypeText:
+					if c.Text == "" {
+						continue
+					}
+					items = append(items, responses.ResponseInputItemUnionParam{
+						OfMessage: &responses.EasyInputMessageParam{
+							Role:    responses.EasyInputMessageRoleAssistant,
+							Content: responses.EasyInputMessageContentUnionParam{OfString: openai.String(c.Text)},
+						},
+					})
+				case ContentTypeToolUse:
+					args := string(c.ToolInput)
+					if args == "" {
+						args = "{}"
+					}
+					items = append(items, responses.ResponseInputItemUnionParam{
+						OfFunctionCall: &responses.ResponseFunctionToolCallParam{
+							CallID:    c.ToolUseID,
+							Name:      c.ToolName,
+							Arguments: args,
+						},
+					})
+				}
+			}
+		}
+	}
+	return items
+}
+
+// buildResponsesTools converts unified ToolSchema to Responses function tools.
+func buildResponsesTools(tools []ToolSchema) []responses.ToolUnionParam {
+	var result []responses.ToolUnionParam
+	for _, t := range tools {
+		result = append(result, responses.ToolUnionParam{
+			OfFunction: &responses.FunctionToolParam{
+				Name:        t.Name,
+				Description: openai.String(t.Description),
+				Parameters: map[string]any{
+					"type":       "object",
+					"properties": t.Parameters,
+				},
+				Strict: openai.Bool(false),
+			},
+		})
+	}
+	return result
+}
+
+// isOpenAIReasoningModel reports whether model is an o-series or GPT-5
+// model, which take reasoning settings and return reasoning items.
+func isOpenAIReasoningModel(model string) bool {
+	m := strings.ToLower(model)
+	if i := strings.LastIndex(m, "/"); i >= 0 {
+		m = m[i+1:]
+	}
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5", "codex"} {
+		if strings.HasPrefix(m, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// reasoningEffort maps a thinking budget to a Responses reasoning effort.
+// A zero budget leaves the API default.
+func reasoningEffort(budget int) shared.ReasoningEffort {
+	switch {
+	case budget <= 0:
+		return ""
+	case budget <= 4096:
+		return shared.ReasoningEffortLow
+	case budget <= 16384:
+		return shared.ReasoningEffortMedium
+	default:
+		return shared.ReasoningEffortHigh
+	}
+}
+
+// responsesStopReason derives a StopReason from a finished response: the
+// incomplete_details reason if it was cut short, otherwise whether it ended
+// with function calls.
+func responsesStopReason(incomplete string, calledTools bool) StopReason {
+	switch {
+	case incomplete == "max_output_tokens":
+		return StopMaxTokens
+	case incomplete == "content_filter":
+		return StopContentFilter
+	case calledTools:
+		return StopToolUse
+	default:
+		return StopEndTurn
+	}
+}
+
+// responsesUsage converts Responses usage; input_tokens includes cached
+// tokens, which are split out so they can be priced separately.
+func responsesUsage(u responses.ResponseUsage) *Usage {
+	cached := int(u.InputTokensDetails.CachedTokens)
+	return &Usage{
+		InputTokens:     int(u.InputTokens) - cached,
+		OutputTokens:    int(u.OutputTokens),
+		CacheReadTokens: cached,
+	}
+}
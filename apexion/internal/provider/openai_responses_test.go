@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newResponsesStub serves one SSE stream per request and records the
+// decoded request bodies.
+func newResponsesStub(t *testing.T, streams ...[]string) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/responses") {
+			http.NotFound(w, r)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("bad request body: %v", err)
+		}
+		n := len(bodies)
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range streams[n] {
+			fmt.Fprintf(w, "data: %s\n\n", ev)
+		}
+	}))
+	return srv, &bodies
+}
+
+var responsesToolRound = []string{
+	`{"type":"response.reasoning_summary_part.added","item_id":"rs_1","summary_index":0}`,
+	`{"type":"response.reasoning_summary_text.delta","item_id":"rs_1","summary_index":0,"delta":"Need the file first."}`,
+	`{"type":"response.output_item.done","output_index":0,"item":{"id":"rs_1","type":"reasoning","summary":[{"type":"summary_text","text":"Need the file first."}],"encrypted_content":"enc-abc"}}`,
+	`{"type":"response.output_text.delta","item_id":"msg_1","delta":"Reading it."}`,
+	`{"type":"response.output_item.done","output_index":2,"item":{"id":"fc_1","type":"function_call","call_id":"call_1","name":"read_file","arguments":"{\"path\":\"main.go\"}","status":"completed"}}`,
+	`{"type":"response.completed","response":{"id":"resp_1","status":"completed","usage":{"input_tokens":100,"input_tokens_details":{"cached_tokens":40},"output_tokens":20,"output_tokens_details":{"reasoning_tokens":12},"total_tokens":120}}}`,
+}
+
+func TestOpenAIResponses_StreamsReasoningItems(t *testing.T) {
+	srv, bodies := newResponsesStub(t, responsesToolRound)
+	defer srv.Close()
+
+	p := NewOpenAIResponsesProvider("sk-test", srv.URL, "o4-mini", nil)
+	events, err := p.Chat(context.Background(), &ChatRequest{
+		SystemPrompt:   "be brief",
+		Messages:       []Message{{Role: RoleUser, Content: []Content{{Type: ContentTypeText, Text: "fix main.go"}}}},
+		Tools:          []ToolSchema{{Name: "read_file", Description: "Read a file", Parameters: map[string]any{"path": map[string]any{"type": "string"}}}},
+		ThinkingBudget: 8000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var thinking, text string
+	var item *Content
+	var call *ToolCallRequest
+	var usage *Usage
+	for ev := range events {
+		switch ev.Type {
+		case EventThinkingDelta:
+			thinking += ev.ThinkingDelta
+			if ev.ReasoningItem != nil {
+				item = ev.ReasoningItem
+			}
+		case EventTextDelta:
+			text += ev.TextDelta
+		case EventToolCallDone:
+			call = ev.ToolCall
+		case EventDone:
+			usage = ev.Usage
+		case EventError:
+			t.Fatal(ev.Error)
+		}
+	}
+
+	if thinking != "Need the file first." || text != "Reading it." {
+		t.Errorf("thinking=%q text=%q", thinking, text)
+	}
+	if item == nil || item.Type != ContentTypeReasoning || item.ReasoningID != "rs_1" || item.Signature != "enc-abc" || item.Text != "Need the file first." {
+		t.Errorf("unexpected reasoning item %+v", item)
+	}
+	if call == nil || call.ID != "call_1" || call.Name != "read_file" || string(call.Input) != `{"path":"main.go"}` {
+		t.Errorf("unexpected tool call %+v", call)
+	}
+	if usage == nil || usage.InputTokens != 60 || usage.CacheReadTokens != 40 || usage.OutputTokens != 20 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+
+	body := (*bodies)[0]
+	if body["store"] != false || body["instructions"] != "be brief" {
+		t.Errorf("expected stateless request with instructions, got %v", body)
+	}
+	if inc, _ := body["include"].([]any); len(inc) != 1 || inc[0] != "reasoning.encrypted_content" {
+		t.Errorf("encrypted reasoning not requested: %v", body["include"])
+	}
+	if r, _ := body["reasoning"].(map[string]any); r["effort"] != "medium" || r["summary"] != "auto" {
+		t.Errorf("unexpected reasoning settings %v", body["reasoning"])
+	}
+}
+
+func TestOpenAIResponses_ReplaysReasoningItems(t *testing.T) {
+	srv, bodies := newResponsesStub(t, []string{
+		`{"type":"response.output_text.delta","delta":"Done."}`,
+		`{"type":"response.completed","response":{"usage":{"input_tokens":5,"output_tokens":1}}}`,
+	})
+	defer srv.Close()
+
+	history := []Message{
+		{Role: RoleUser, Content: []Content{{Type: ContentTypeText, Text: "fix main.go"}}},
+		{Role: RoleAssistant, Content: []Content{
+			{Type: ContentTypeReasoning, ReasoningID: "rs_1", Text: "Need the file first.", Signature: "enc-abc"},
+			{Type: ContentTypeThinking, Text: "from another provider", Signature: "sig"},
+			{Type: ContentTypeText, Text: "Reading it."},
+			{Type: ContentTypeToolUse, ToolUseID: "call_1", ToolName: "read_file", ToolInput: json.RawMessage(`{"path":"main.go"}`)},
+		}},
+		{Role: RoleUser, Content: []Content{{Type: ContentTypeToolResult, ToolUseID: "call_1", ToolResult: "package main"}}},
+	}
+	p := NewOpenAIResponsesProvider("sk-test", srv.URL, "gpt-4.1", nil)
+	if _, err := readText(context.Background(), p, &ChatRequest{Messages: history}); err != nil {
+		t.Fatal(err)
+	}
+
+	body := (*bodies)[0]
+	input, _ := body["input"].([]any)
+	var types []string
+	for _, raw := range input {
+		item, _ := raw.(map[string]any)
+		typ, _ := item["type"].(string)
+		if typ == "" {
+			typ = "message:" + item["role"].(string)
+		}
+		types = append(types, typ)
+		if typ == "reasoning" && (item["id"] != "rs_1" || item["encrypted_content"] != "enc-abc") {
+			t.Errorf("reasoning item not replayed verbatim: %v", item)
+		}
+	}
+	want := "message:user reasoning message:assistant function_call function_call_output"
+	if got := strings.Join(types, " "); got != want {
+		t.Errorf("input items = %s, want %s", got, want)
+	}
+	if _, ok := body["reasoning"]; ok {
+		t.Errorf("non-reasoning model should not get reasoning settings: %v", body["reasoning"])
+	}
+}
+
+func TestOpenAIResponses_FailedResponse(t *testing.T) {
+	srv, _ := newResponsesStub(t, []string{
+		`{"type":"response.failed","response":{"status":"failed","error":{"code":"rate_limit_exceeded","message":"slow down"}}}`,
+	})
+	defer srv.Close()
+
+	p := NewOpenAIResponsesProvider("sk-test", srv.URL, "o3", nil)
+	_, err := readText(context.Background(), p, userRequest("hi"))
+	if err == nil || !IsRetryableError(err) {
+		t.Errorf("expected a retryable error, got %v", err)
+	}
+}
+
+func TestOpenAIResponses_IncompleteIsTruncated(t *testing.T) {
+	srv, bodies := newResponsesStub(t, []string{
+		`{"type":"response.output_text.delta","delta":"The answer is"}`,
+		`{"type":"response.incomplete","response":{"status":"incomplete","incomplete_details":{"reason":"max_output_tokens"},"usage":{"input_tokens":5,"output_tokens":64}}}`,
+	})
+	defer srv.Close()
+
+	p := NewOpenAIResponsesProvider("sk-test", srv.URL, "gpt-4.1", nil)
+	req := userRequest("hi")
+	req.MaxTokens = 64
+	events, err := p.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stop StopReason
+	for ev := range events {
+		if ev.Type == EventDone {
+			stop = ev.StopReason
+		}
+	}
+	if stop != StopMaxTokens {
+		t.Errorf("expected max_tokens stop reason, got %q", stop)
+	}
+	if got := (*bodies)[0]["max_output_tokens"]; got != float64(64) {
+		t.Errorf("max_output_tokens = %v, want 64", got)
+	}
+}
@@ -6,6 +6,7 @@ package provider
 import (
 	"context"
 	"encoding/json"
+	"errors"
 )
 
 // ── Message types ────────────────────────────────────────────────────────────
@@ -24,9 +25,26 @@ const (
 	ContentTypeToolUse    ContentType = "tool_use"
 	ContentTypeToolResult ContentType = "tool_result"
 	ContentTypeImage      ContentType = "image"
+
+	// ContentTypeThinking is a model reasoning block. Text holds the reasoning;
+	// Signature is the provider's integrity token (Anthropic), which must be
+	// sent back unchanged when the block is replayed in history.
+	ContentTypeThinking ContentType = "thinking"
+
+	// ContentTypeRedactedThinking is an encrypted reasoning block. Text holds
+	// the opaque payload, which is replayed verbatim.
+	ContentTypeRedactedThinking ContentType = "redacted_thinking"
+
+	// ContentTypeReasoning is an OpenAI Responses API reasoning item. Text
+	// holds the reasoning summary, Signature the encrypted reasoning and
+	// ReasoningID the item id. Only the Responses transport replays it;
+	// other providers skip it.
+	ContentTypeReasoning ContentType = "reasoning"
 )
 
-// Content is a single content block within a message.
+// Content is a single content block within a message. A tool_result may
+// carry an image returned by the tool (a screenshot, an image file) in
+// ImageData; providers send it with the result as far as their API allows.
 type Content struct {
 	Type           ContentType
 	Text           string
@@ -35,8 +53,10 @@ type Content struct {
 	ToolInput      json.RawMessage // tool_use
 	ToolResult     string          // tool_result
 	IsError        bool            // tool_result
-	ImageData      string          // image: base64-encoded data
-	ImageMediaType string          // image: MIME type (e.g. "image/png")
+	ImageData      string          // image, tool_result: base64-encoded data
+	ImageMediaType string          // image, tool_result: MIME type (e.g. "image/png")
+	Signature      string          // thinking: provider signature for round-tripping
+	ReasoningID    string          // reasoning: Responses API item id
 }
 
 // Message is a single message in the conversation history.
@@ -65,6 +85,23 @@ type ChatRequest struct {
 	MaxTokens    int
 	Temperature  *float64 // nil = use API default
 	TopP         *float64 // nil = use API default
+
+	// ThinkingBudget is the token budget for extended thinking / reasoning.
+	// 0 disables it; providers without a budget knob treat >0 as "on".
+	ThinkingBudget int
+
+	// ResponseSchema, if set, asks for a reply that is a single JSON
+	// document matching the schema, streamed as text deltas. Only providers
+	// implementing ResponseSchemaSupporter enforce it; use ChatJSON to get a
+	// prompted, validated fallback everywhere else.
+	ResponseSchema *ResponseSchema
+}
+
+// ResponseSchema describes a structured (JSON) response.
+type ResponseSchema struct {
+	Name        string         // identifier: letters, digits, '_' and '-'
+	Description string         // what the document is, shown to the model
+	Schema      map[string]any // JSON Schema; the root must be an object
 }
 
 // ── Event types (streaming output) ───────────────────────────────────────────
@@ -78,11 +115,18 @@ const (
 	// EventToolCallDone: a complete tool call (emitted after internal JSON assembly).
 	EventToolCallDone
 
-	// EventDone: end of this message turn, includes token usage.
+	// EventDone: end of this message turn, includes token usage and the
+	// stop reason.
 	EventDone
 
 	// EventError: an error occurred.
 	EventError
+
+	// EventThinkingDelta: incremental model reasoning. A signed block is
+	// closed by an event carrying ThinkingSignature; a redacted block arrives
+	// whole in RedactedThinking, a Responses API reasoning item in
+	// ReasoningItem.
+	EventThinkingDelta
 )
 
 // Event is the unified streaming event emitted by a provider.
@@ -92,16 +136,33 @@ type Event struct {
 	// EventTextDelta
 	TextDelta string
 
+	// EventThinkingDelta
+	ThinkingDelta     string
+	ThinkingSignature string
+	RedactedThinking  string
+	ReasoningItem     *Content // ContentTypeReasoning, kept in history as-is
+
 	// EventToolCallDone
 	ToolCall *ToolCallRequest
 
 	// EventDone
-	Usage *Usage
+	Usage      *Usage
+	StopReason StopReason // empty if the provider did not say
 
 	// EventError
 	Error error
 }
 
+// StopReason is why the model stopped generating, normalized across providers.
+type StopReason string
+
+const (
+	StopEndTurn       StopReason = "end_turn"       // natural end of the answer
+	StopToolUse       StopReason = "tool_use"       // stopped to call tools
+	StopMaxTokens     StopReason = "max_tokens"     // hit ChatRequest.MaxTokens; output is truncated
+	StopContentFilter StopReason = "content_filter" // cut off or refused by a safety filter
+)
+
 // ToolCallRequest represents a tool call requested by the LLM.
 type ToolCallRequest struct {
 	ID    string
@@ -110,9 +171,20 @@ type ToolCallRequest struct {
 }
 
 // Usage records token consumption for an API call.
+//
+// InputTokens counts only uncached prompt tokens; prompt-cache writes and
+// reads are reported separately because they are billed at different rates.
+// PromptTokens returns the full prompt size.
 type Usage struct {
-	InputTokens  int
-	OutputTokens int
+	InputTokens         int
+	OutputTokens        int
+	CacheCreationTokens int // prompt tokens written to the cache
+	CacheReadTokens     int // prompt tokens served from the cache
+}
+
+// PromptTokens returns the total number of prompt tokens, cached or not.
+func (u *Usage) PromptTokens() int {
+	return u.InputTokens + u.CacheCreationTokens + u.CacheReadTokens
 }
 
 // ── Provider interface ───────────────────────────────────────────────────────
@@ -141,3 +213,26 @@ type Provider interface {
 	// ContextWindow returns the default context window size for the current model.
 	ContextWindow() int
 }
+
+// ModelLister is an optional interface for providers that can enumerate the
+// models actually available at runtime (e.g. models installed on a local
+// server), rather than the static Models() list.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// ErrModelListUnsupported is returned by ListModels on wrapper providers whose
+// wrapped provider cannot enumerate its models at runtime.
+var ErrModelListUnsupported = errors.New("provider cannot list its models")
+
+// ModelContextWindower is an optional interface for providers that know the
+// context window of models other than the default one.
+type ModelContextWindower interface {
+	ContextWindowFor(model string) int
+}
+
+// ResponseSchemaSupporter is an optional interface for providers that
+// enforce ChatRequest.ResponseSchema natively for the given model.
+type ResponseSchemaSupporter interface {
+	SupportsResponseSchema(model string) bool
+}
@@ -3,6 +3,8 @@ package provider
 import (
 	"encoding/json"
 	"testing"
+
+	"github.com/openai/openai-go"
 )
 
 // --- ContextWindow tests ---
@@ -88,7 +90,7 @@ func TestOpenAIProvider_NameDetection(t *testing.T) {
 		{"https://custom.api.com/v1", "openai"},
 	}
 	for _, tt := range tests {
-		p := NewOpenAIProvider("test-key", tt.baseURL, "test-model")
+		p := NewOpenAIProvider("test-key", tt.baseURL, "test-model", nil)
 		if p.Name() != tt.expected {
 			t.Errorf("baseURL=%q: expected name %q, got %q", tt.baseURL, tt.expected, p.Name())
 		}
@@ -151,6 +153,9 @@ func TestEventTypes(t *testing.T) {
 	if EventError != 3 {
 		t.Error("EventError should be 3")
 	}
+	if EventThinkingDelta != 4 {
+		t.Error("EventThinkingDelta should be 4")
+	}
 }
 
 func TestUsage(t *testing.T) {
@@ -158,4 +163,171 @@ func TestUsage(t *testing.T) {
 	if u.InputTokens != 1000 || u.OutputTokens != 500 {
 		t.Error("usage fields mismatch")
 	}
+	u.CacheCreationTokens, u.CacheReadTokens = 200, 300
+	if u.PromptTokens() != 1500 {
+		t.Errorf("PromptTokens should include cached tokens, got %d", u.PromptTokens())
+	}
+}
+
+func TestAnthropicBuildMessages_ThinkingRoundTrip(t *testing.T) {
+	p := &AnthropicProvider{}
+	params := p.buildMessages([]Message{{
+		Role: RoleAssistant,
+		Content: []Content{
+			{Type: ContentTypeThinking, Text: "reasoning", Signature: "sig"},
+			{Type: ContentTypeThinking, Text: "unsigned from another provider"},
+			{Type: ContentTypeRedactedThinking, Text: "opaque"},
+			{Type: ContentTypeText, Text: "answer"},
+		},
+	}})
+	if len(params) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(params))
+	}
+	blocks := params[0].Content
+	if len(blocks) != 3 {
+		t.Fatalf("expected unsigned thinking to be dropped (3 blocks), got %d", len(blocks))
+	}
+	if blocks[0].OfThinking == nil || blocks[0].OfThinking.Signature != "sig" || blocks[0].OfThinking.Thinking != "reasoning" {
+		t.Errorf("expected signed thinking block first, got %+v", blocks[0])
+	}
+	if blocks[1].OfRedactedThinking == nil || blocks[1].OfRedactedThinking.Data != "opaque" {
+		t.Errorf("expected redacted thinking block, got %+v", blocks[1])
+	}
+}
+
+var toolImageHistory = []Message{
+	{Role: RoleAssistant, Content: []Content{{Type: ContentTypeToolUse, ToolUseID: "t1", ToolName: "screenshot"}}},
+	{Role: RoleUser, Content: []Content{{
+		Type: ContentTypeToolResult, ToolUseID: "t1", ToolResult: "[Image: image/png]",
+		ImageData: "aGVsbG8=", ImageMediaType: "image/png",
+	}}},
+}
+
+func TestAnthropicBuildMessages_ToolResultImage(t *testing.T) {
+	p := &AnthropicProvider{}
+	params := p.buildMessages(toolImageHistory)
+	if len(params) != 2 || len(params[1].Content) != 1 {
+		t.Fatalf("expected the image inside a single tool_result block, got %+v", params)
+	}
+	tr := params[1].Content[0].OfToolResult
+	if tr == nil || tr.ToolUseID != "t1" || len(tr.Content) != 2 {
+		t.Fatalf("unexpected tool_result %+v", params[1].Content[0])
+	}
+	if tr.Content[0].OfText == nil || tr.Content[0].OfText.Text != "[Image: image/png]" {
+		t.Errorf("expected text first, got %+v", tr.Content[0])
+	}
+	img := tr.Content[1].OfImage
+	if img == nil || img.Source.OfBase64 == nil || img.Source.OfBase64.Data != "aGVsbG8=" || img.Source.OfBase64.MediaType != "image/png" {
+		t.Errorf("expected base64 image second, got %+v", tr.Content[1])
+	}
+
+	// An image-only result has no empty text block.
+	c := toolImageHistory[1].Content[0]
+	c.ToolResult = ""
+	if got := anthropicToolResult(c).OfToolResult.Content; len(got) != 1 || got[0].OfImage == nil {
+		t.Errorf("expected only the image, got %+v", got)
+	}
+}
+
+func TestOpenAIBuildMessages_ToolResultImage(t *testing.T) {
+	p := &OpenAIProvider{}
+	params := p.buildMessages(&ChatRequest{Messages: toolImageHistory})
+	if len(params) != 3 {
+		t.Fatalf("expected assistant, tool and user messages, got %d", len(params))
+	}
+	if params[1].OfTool == nil || params[1].OfTool.ToolCallID != "t1" {
+		t.Fatalf("expected the tool message right after the call, got %+v", params[1])
+	}
+	user := params[2].OfUser
+	if user == nil {
+		t.Fatalf("expected a user message carrying the image, got %+v", params[2])
+	}
+	parts := user.Content.OfArrayOfContentParts
+	if len(parts) != 2 || parts[0].OfText == nil || parts[0].OfText.Text != "Image returned by tool call t1:" ||
+		parts[1].OfImageURL == nil || parts[1].OfImageURL.ImageURL.URL != "data:image/png;base64,aGVsbG8=" {
+		t.Errorf("unexpected image message parts %+v", parts)
+	}
+}
+
+func TestAnthropicCacheBreakpoints(t *testing.T) {
+	p := &AnthropicProvider{}
+	params := p.buildMessages([]Message{
+		{Role: RoleUser, Content: []Content{{Type: ContentTypeText, Text: "first"}}},
+		{Role: RoleAssistant, Content: []Content{{Type: ContentTypeText, Text: "reply"}}},
+		{Role: RoleUser, Content: []Content{{Type: ContentTypeText, Text: "second"}}},
+		{Role: RoleAssistant, Content: []Content{
+			{Type: ContentTypeToolUse, ToolUseID: "t1", ToolName: "glob"},
+			{Type: ContentTypeThinking, Text: "hm", Signature: "sig"},
+		}},
+	})
+	markCacheBreakpoints(params)
+
+	cached := func(i int) bool {
+		for _, b := range params[i].Content {
+			if cc := b.GetCacheControl(); cc != nil && cc.Type == "ephemeral" {
+				return true
+			}
+		}
+		return false
+	}
+	// Final message (skipping the thinking block) and the last user turn before it.
+	want := []bool{false, false, true, true}
+	for i, w := range want {
+		if got := cached(i); got != w {
+			t.Errorf("message %d: cache breakpoint = %v, want %v", i, got, w)
+		}
+	}
+	if params[3].Content[0].OfToolUse.CacheControl.Type != "ephemeral" {
+		t.Error("expected the tool_use block to carry the breakpoint, not thinking")
+	}
+}
+
+func TestOpenAIUsage_SplitsCachedTokens(t *testing.T) {
+	var u openai.CompletionUsage
+	u.PromptTokens = 1000
+	u.CompletionTokens = 50
+	u.PromptTokensDetails.CachedTokens = 800
+	got := openAIUsage(u)
+	if got.InputTokens != 200 || got.CacheReadTokens != 800 || got.OutputTokens != 50 || got.PromptTokens() != 1000 {
+		t.Errorf("unexpected usage %+v", got)
+	}
+}
+
+func TestExtractReasoningContent(t *testing.T) {
+	if got := extractReasoningContent(`{"content":"","reasoning_content":"step 1"}`); got != "step 1" {
+		t.Errorf("expected reasoning_content, got %q", got)
+	}
+	if got := extractReasoningContent(`{"content":"hi"}`); got != "" {
+		t.Errorf("expected empty, got %q", got)
+	}
+}
+
+func TestStopReasonMapping(t *testing.T) {
+	tests := []struct {
+		name string
+		got  StopReason
+		want StopReason
+	}{
+		{"openai length", openAIStopReason("length"), StopMaxTokens},
+		{"openai tool_calls", openAIStopReason("tool_calls"), StopToolUse},
+		{"openai stop", openAIStopReason("stop"), StopEndTurn},
+		{"openai content_filter", openAIStopReason("content_filter"), StopContentFilter},
+		{"anthropic max_tokens", anthropicStopReason("max_tokens"), StopMaxTokens},
+		{"anthropic stop_sequence", anthropicStopReason("stop_sequence"), StopEndTurn},
+		{"anthropic refusal", anthropicStopReason("refusal"), StopContentFilter},
+		{"gemini MAX_TOKENS", geminiStopReason("MAX_TOKENS", true), StopMaxTokens},
+		{"gemini STOP after calls", geminiStopReason("STOP", true), StopToolUse},
+		{"gemini STOP", geminiStopReason("STOP", false), StopEndTurn},
+		{"gemini SAFETY", geminiStopReason("SAFETY", false), StopContentFilter},
+		{"ollama length", ollamaStopReason("length", true), StopMaxTokens},
+		{"ollama stop", ollamaStopReason("stop", false), StopEndTurn},
+		{"responses max_output_tokens", responsesStopReason("max_output_tokens", false), StopMaxTokens},
+		{"responses function calls", responsesStopReason("", true), StopToolUse},
+		{"unreported", openAIStopReason(""), ""},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
 }
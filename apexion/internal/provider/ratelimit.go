@@ -0,0 +1,547 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apexion-ai/apexion/internal/tokenizer"
+)
+
+// RateLimit configures a Governor. A zero field means no limit until the
+// provider's rate-limit headers announce one.
+type RateLimit struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+}
+
+// Lane identifies who is waiting for a Governor. Waiting lanes are served in
+// turn, so a burst of background agents cannot starve the foreground loop
+// and the foreground loop cannot starve them.
+type Lane int
+
+const (
+	LaneForeground Lane = iota // the interactive loop and its task sub-agents
+	LaneBackground             // agents started through the BackgroundManager
+
+	numLanes
+)
+
+func (l Lane) String() string {
+	if l == LaneBackground {
+		return "background"
+	}
+	return "foreground"
+}
+
+type laneKey struct{}
+
+// WithLane marks every provider call made with ctx as coming from lane.
+func WithLane(ctx context.Context, lane Lane) context.Context {
+	return context.WithValue(ctx, laneKey{}, lane)
+}
+
+// LaneFrom returns the lane set by WithLane, defaulting to LaneForeground.
+func LaneFrom(ctx context.Context) Lane {
+	if l, ok := ctx.Value(laneKey{}).(Lane); ok {
+		return l
+	}
+	return LaneForeground
+}
+
+// Governor paces requests to one provider with two token buckets, one for
+// requests and one for tokens per minute. Limits start from the configured
+// RateLimit and tighten to whatever the provider reports in its
+// x-ratelimit-* (or anthropic-ratelimit-*) headers; an exhausted bucket or a
+// 429 with Retry-After pauses every caller until the reset time.
+type Governor struct {
+	mu       sync.Mutex
+	limits   RateLimit // configured
+	rpm, tpm float64   // effective capacities, 0 = unlimited
+	requests float64   // available in the request bucket
+	tokens   float64   // available in the token bucket
+	refilled time.Time
+	paused   time.Time // nobody is let through before this
+
+	queues [numLanes][]*ticket
+	next   Lane // lane served first when several are waiting
+	timer  *time.Timer
+	now    func() time.Time
+}
+
+// ticket is one call waiting in a Governor queue.
+type ticket struct {
+	lane    Lane
+	tokens  float64
+	ready   chan struct{}
+	granted bool
+}
+
+// NewGovernor creates a Governor with the given configured limits.
+func NewGovernor(limits RateLimit) *Governor {
+	g := &Governor{now: time.Now}
+	g.refilled = g.now()
+	g.SetLimits(limits)
+	return g
+}
+
+var (
+	governorsMu sync.Mutex
+	governors   = map[string]*Governor{}
+)
+
+// GovernorFor returns the process-wide governor for the named provider,
+// creating it on first use, so the main agent, sub-agents, background
+// agents and fallback chains all draw from the same buckets. limits replaces
+// the configured limits of an existing governor.
+func GovernorFor(name string, limits RateLimit) *Governor {
+	governorsMu.Lock()
+	defer governorsMu.Unlock()
+	if g, ok := governors[name]; ok {
+		g.SetLimits(limits)
+		return g
+	}
+	g := NewGovernor(limits)
+	governors[name] = g
+	return g
+}
+
+// SetLimits replaces the configured limits. A zero field keeps whatever
+// limit has been learned from response headers.
+func (g *Governor) SetLimits(limits RateLimit) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refillLocked(g.now())
+	g.limits = limits
+	if limits.RequestsPerMinute > 0 {
+		setCapacity(&g.rpm, &g.requests, float64(limits.RequestsPerMinute))
+	}
+	if limits.TokensPerMinute > 0 {
+		setCapacity(&g.tpm, &g.tokens, float64(limits.TokensPerMinute))
+	}
+	g.dispatchLocked()
+}
+
+// Waiting returns the number of calls currently held back.
+func (g *Governor) Waiting() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	n := 0
+	for _, q := range g.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// Acquire blocks until a call estimated at tokens may be sent. If the call
+// has to wait, onWait (when non-nil) is called once with the expected delay
+// before blocking.
+func (g *Governor) Acquire(ctx context.Context, lane Lane, tokens int, onWait func(time.Duration)) error {
+	t := &ticket{lane: lane, tokens: float64(tokens), ready: make(chan struct{})}
+
+	g.mu.Lock()
+	g.queues[lane] = append(g.queues[lane], t)
+	g.dispatchLocked()
+	if t.granted {
+		g.mu.Unlock()
+		return nil
+	}
+	wait := g.delayLocked(t, g.now())
+	g.mu.Unlock()
+
+	if onWait != nil {
+		onWait(wait)
+	}
+	select {
+	case <-t.ready:
+		return nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if t.granted {
+			return nil
+		}
+		q := g.queues[lane]
+		for i, w := range q {
+			if w == t {
+				g.queues[lane] = append(q[:i:i], q[i+1:]...)
+				break
+			}
+		}
+		g.dispatchLocked()
+		return ctx.Err()
+	}
+}
+
+// Settle corrects the token bucket once a call's real usage is known.
+// actual is 0 when the provider reported no usage (e.g. the call failed),
+// which refunds the estimate.
+func (g *Governor) Settle(estimated, actual int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.tpm == 0 || estimated == actual {
+		return
+	}
+	g.refillLocked(g.now())
+	g.tokens = math.Min(g.tpm, g.tokens-float64(actual-estimated))
+	g.dispatchLocked()
+}
+
+// Observe learns limits from a provider response.
+func (g *Governor) Observe(status int, h http.Header) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	g.refillLocked(now)
+
+	g.observeBucket(h, now, &g.rpm, &g.requests, float64(g.limits.RequestsPerMinute), "requests")
+	g.observeBucket(h, now, &g.tpm, &g.tokens, float64(g.limits.TokensPerMinute), "tokens")
+	if status == http.StatusTooManyRequests {
+		if d, ok := retryAfter(h, now); ok {
+			g.pauseLocked(now.Add(d))
+		}
+	}
+	g.dispatchLocked()
+}
+
+// observeBucket applies the limit/remaining/reset headers for one bucket
+// ("requests" or "tokens").
+func (g *Governor) observeBucket(h http.Header, now time.Time, capacity, avail *float64, configured float64, kind string) {
+	if limit, ok := headerFloat(h, "x-ratelimit-limit-"+kind, "anthropic-ratelimit-"+kind+"-limit"); ok && limit > 0 {
+		if configured > 0 && configured < limit {
+			limit = configured
+		}
+		setCapacity(capacity, avail, limit)
+	}
+	remaining, ok := headerFloat(h, "x-ratelimit-remaining-"+kind, "anthropic-ratelimit-"+kind+"-remaining")
+	if !ok {
+		return
+	}
+	if *capacity > 0 {
+		*avail = math.Min(*avail, remaining)
+	}
+	if remaining < 1 {
+		reset := h.Get("x-ratelimit-reset-" + kind)
+		if reset == "" {
+			reset = h.Get("anthropic-ratelimit-" + kind + "-reset")
+		}
+		if d, ok := parseReset(reset, now); ok {
+			g.pauseLocked(now.Add(d))
+		}
+	}
+}
+
+func (g *Governor) pauseLocked(until time.Time) {
+	if until.After(g.paused) {
+		g.paused = until
+	}
+}
+
+// setCapacity changes a bucket's size. A bucket that was unlimited starts full.
+func setCapacity(capacity, avail *float64, limit float64) {
+	if *capacity == 0 {
+		*avail = limit
+	}
+	*capacity = limit
+	*avail = math.Min(*avail, limit)
+}
+
+func (g *Governor) refillLocked(now time.Time) {
+	elapsed := now.Sub(g.refilled).Minutes()
+	g.refilled = now
+	if elapsed <= 0 {
+		return
+	}
+	if g.rpm > 0 {
+		g.requests = math.Min(g.rpm, g.requests+g.rpm*elapsed)
+	}
+	if g.tpm > 0 {
+		g.tokens = math.Min(g.tpm, g.tokens+g.tpm*elapsed)
+	}
+}
+
+// delayLocked returns how long t has to wait for the buckets, ignoring the
+// calls queued ahead of it.
+func (g *Governor) delayLocked(t *ticket, now time.Time) time.Duration {
+	var d time.Duration
+	if g.paused.After(now) {
+		d = g.paused.Sub(now)
+	}
+	if g.rpm > 0 && g.requests < 1 {
+		d = max(d, refillTime(1-g.requests, g.rpm))
+	}
+	if g.tpm > 0 {
+		// A call larger than the whole bucket only waits for a full bucket.
+		if need := math.Min(t.tokens, g.tpm); g.tokens < need {
+			d = max(d, refillTime(need-g.tokens, g.tpm))
+		}
+	}
+	return d
+}
+
+func refillTime(deficit, perMinute float64) time.Duration {
+	return time.Duration(math.Ceil(deficit / perMinute * float64(time.Minute)))
+}
+
+// dispatchLocked lets queued calls through while the buckets allow,
+// alternating between lanes, and arms a timer for the next one otherwise.
+func (g *Governor) dispatchLocked() {
+	now := g.now()
+	g.refillLocked(now)
+	for {
+		lane, ok := g.nextLaneLocked()
+		if !ok {
+			return
+		}
+		t := g.queues[lane][0]
+		if d := g.delayLocked(t, now); d > 0 {
+			if g.timer != nil {
+				g.timer.Stop()
+			}
+			g.timer = time.AfterFunc(d, g.wake)
+			return
+		}
+		g.queues[lane] = g.queues[lane][1:]
+		if g.rpm > 0 {
+			g.requests--
+		}
+		if g.tpm > 0 {
+			g.tokens -= t.tokens
+		}
+		t.granted = true
+		close(t.ready)
+		g.next = (lane + 1) % numLanes
+	}
+}
+
+func (g *Governor) nextLaneLocked() (Lane, bool) {
+	for i := Lane(0); i < numLanes; i++ {
+		l := (g.next + i) % numLanes
+		if len(g.queues[l]) > 0 {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
+func (g *Governor) wake() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.dispatchLocked()
+}
+
+// headerFloat returns the first of names present in h as a number.
+func headerFloat(h http.Header, names ...string) (float64, bool) {
+	for _, name := range names {
+		if v := h.Get(name); v != "" {
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			return f, err == nil
+		}
+	}
+	return 0, false
+}
+
+// parseReset reads a reset header: a Go-style duration ("1s", "6m0s",
+// OpenAI), an RFC 3339 timestamp (Anthropic) or a number of seconds.
+func parseReset(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return d, true
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.Sub(now), true
+	}
+	if s, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(s * float64(time.Second)), true
+	}
+	return 0, false
+}
+
+// retryAfter reads retry-after-ms or Retry-After (seconds or an HTTP date).
+func retryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	if v := h.Get("retry-after-ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil {
+			return time.Duration(ms * float64(time.Millisecond)), true
+		}
+	}
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(s * float64(time.Second)), true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t.Sub(now), true
+	}
+	return 0, false
+}
+
+// ── Response observation ─────────────────────────────────────────────────────
+
+type governorKey struct{}
+
+// observeRateLimits is an HTTP middleware that reports every response to the
+// Governor attached to the request context by RateLimitedProvider. Its
+// signature matches the openai-go and anthropic-sdk-go option.Middleware.
+func observeRateLimits(req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	resp, err := next(req)
+	if resp != nil {
+		if g, ok := req.Context().Value(governorKey{}).(*Governor); ok {
+			g.Observe(resp.StatusCode, resp.Header)
+		}
+	}
+	return resp, err
+}
+
+// rateLimitTransport applies observeRateLimits to plain http.Client providers.
+type rateLimitTransport struct {
+	base http.RoundTripper
+}
+
+func (t rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return observeRateLimits(req, t.base.RoundTrip)
+}
+
+// observedClient returns a copy of c (or of a default client when c is nil)
+// whose transport reports responses through observeRateLimits.
+func observedClient(c *http.Client) *http.Client {
+	var out http.Client
+	if c != nil {
+		out = *c
+	}
+	base := out.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	out.Transport = rateLimitTransport{base: base}
+	return &out
+}
+
+// ── Provider wrapper ─────────────────────────────────────────────────────────
+
+// Throttle reports a provider call held back by its Governor.
+type Throttle struct {
+	Provider string
+	Lane     Lane
+	Wait     time.Duration // expected delay; 0 once the call is released
+	Waiting  int           // calls still held back for this provider
+}
+
+// RateLimitedProvider sends every Chat call through a Governor.
+type RateLimitedProvider struct {
+	inner Provider
+	gov   *Governor
+
+	// OnThrottle, if set, is called when a call starts waiting and again
+	// when it is released.
+	OnThrottle func(Throttle)
+}
+
+// NewRateLimitedProvider wraps inner with gov.
+func NewRateLimitedProvider(inner Provider, gov *Governor) *RateLimitedProvider {
+	return &RateLimitedProvider{inner: inner, gov: gov}
+}
+
+func (r *RateLimitedProvider) Name() string         { return r.inner.Name() }
+func (r *RateLimitedProvider) Models() []string     { return r.inner.Models() }
+func (r *RateLimitedProvider) DefaultModel() string { return r.inner.DefaultModel() }
+func (r *RateLimitedProvider) ContextWindow() int   { return r.inner.ContextWindow() }
+
+// Governor returns the governor pacing this provider.
+func (r *RateLimitedProvider) Governor() *Governor { return r.gov }
+
+// ListModels delegates to the wrapped provider, or returns
+// ErrModelListUnsupported if it cannot list models.
+func (r *RateLimitedProvider) ListModels(ctx context.Context) ([]string, error) {
+	if ml, ok := r.inner.(ModelLister); ok {
+		return ml.ListModels(ctx)
+	}
+	return nil, ErrModelListUnsupported
+}
+
+// ContextWindowFor delegates to the wrapped provider.
+func (r *RateLimitedProvider) ContextWindowFor(model string) int {
+	if cw, ok := r.inner.(ModelContextWindower); ok {
+		return cw.ContextWindowFor(model)
+	}
+	return r.inner.ContextWindow()
+}
+
+// SupportsResponseSchema delegates to the wrapped provider.
+func (r *RateLimitedProvider) SupportsResponseSchema(model string) bool {
+	return SupportsResponseSchema(r.inner, model)
+}
+
+// Chat waits for the governor, then streams from the wrapped provider and
+// settles the token estimate against the reported usage.
+func (r *RateLimitedProvider) Chat(ctx context.Context, req *ChatRequest) (<-chan Event, error) {
+	lane := LaneFrom(ctx)
+	estimate := estimateRequestTokens(r.inner.Name(), req)
+	waited := false
+	err := r.gov.Acquire(ctx, lane, estimate, func(d time.Duration) {
+		waited = true
+		r.throttle(Throttle{Provider: r.Name(), Lane: lane, Wait: d, Waiting: r.gov.Waiting()})
+	})
+	if waited {
+		r.throttle(Throttle{Provider: r.Name(), Lane: lane, Waiting: r.gov.Waiting()})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := r.inner.Chat(context.WithValue(ctx, governorKey{}, r.gov), req)
+	if err != nil {
+		r.gov.Settle(estimate, 0)
+		return nil, err
+	}
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		actual := 0
+		for ev := range ch {
+			if ev.Type == EventDone && ev.Usage != nil {
+				actual = ev.Usage.PromptTokens() + ev.Usage.OutputTokens
+			}
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				for range ch {
+				}
+			}
+		}
+		r.gov.Settle(estimate, actual)
+	}()
+	return out, nil
+}
+
+func (r *RateLimitedProvider) throttle(t Throttle) {
+	if r.OnThrottle != nil {
+		r.OnThrottle(t)
+	}
+}
+
+// estimateRequestTokens approximates the prompt size of req for the token
+// bucket; Settle corrects it once usage is reported.
+func estimateRequestTokens(providerName string, req *ChatRequest) int {
+	c := tokenizer.ForModel(providerName, req.Model)
+	n := c.CountTokens(req.SystemPrompt)
+	for _, m := range req.Messages {
+		for _, part := range m.Content {
+			n += c.CountTokens(part.Text) + c.CountTokens(part.ToolResult) + c.CountTokens(string(part.ToolInput))
+		}
+	}
+	for _, t := range req.Tools {
+		params, _ := json.Marshal(t.Parameters)
+		n += c.CountTokens(t.Name) + c.CountTokens(t.Description) + c.CountTokens(string(params))
+	}
+	return n
+}
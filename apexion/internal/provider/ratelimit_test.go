@@ -0,0 +1,203 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// drained returns a governor whose request bucket is empty and refills one
+// request every 10ms.
+func drained() *Governor {
+	g := NewGovernor(RateLimit{RequestsPerMinute: 6000})
+	g.mu.Lock()
+	g.requests = 0
+	g.mu.Unlock()
+	return g
+}
+
+func TestGovernor_AlternatesLanes(t *testing.T) {
+	g := drained()
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	enqueue := func(lane Lane, label string) {
+		queued := g.Waiting()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := g.Acquire(context.Background(), lane, 0, nil); err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			order = append(order, label)
+			mu.Unlock()
+		}()
+		for g.Waiting() == queued {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// A foreground burst queues first; background calls still get every
+	// other slot.
+	enqueue(LaneForeground, "f1")
+	enqueue(LaneForeground, "f2")
+	enqueue(LaneForeground, "f3")
+	enqueue(LaneBackground, "b1")
+	enqueue(LaneBackground, "b2")
+	wg.Wait()
+
+	if got := strings.Join(order, " "); got != "f1 b1 f2 b2 f3" && got != "b1 f1 b2 f2 f3" {
+		t.Errorf("grant order = %q, want lanes to alternate", got)
+	}
+}
+
+func TestGovernor_CancelWhileWaiting(t *testing.T) {
+	g := NewGovernor(RateLimit{RequestsPerMinute: 1})
+	if err := g.Acquire(context.Background(), LaneForeground, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var wait time.Duration
+	err := g.Acquire(ctx, LaneBackground, 0, func(d time.Duration) { wait = d })
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+	if wait < 50*time.Second {
+		t.Errorf("expected about a minute of expected wait, got %s", wait)
+	}
+	if g.Waiting() != 0 {
+		t.Error("cancelled call still queued")
+	}
+}
+
+func TestGovernor_TokenBucket(t *testing.T) {
+	g := NewGovernor(RateLimit{TokensPerMinute: 60000}) // 1000 tokens/s
+	if err := g.Acquire(context.Background(), LaneForeground, 59900, nil); err != nil {
+		t.Fatal(err)
+	}
+	var wait time.Duration
+	start := time.Now()
+	if err := g.Acquire(context.Background(), LaneForeground, 150, func(d time.Duration) { wait = d }); err != nil {
+		t.Fatal(err)
+	}
+	if wait <= 0 || time.Since(start) < 40*time.Millisecond {
+		t.Errorf("expected to wait about 50ms for tokens, waited %s (expected %s)", time.Since(start), wait)
+	}
+
+	// Reported usage below the estimate is refunded.
+	g.Settle(60000, 100)
+	if err := g.Acquire(context.Background(), LaneForeground, 50000, func(time.Duration) {
+		t.Error("refunded tokens should be available immediately")
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGovernor_ObserveHeaders(t *testing.T) {
+	g := NewGovernor(RateLimit{})
+	h := http.Header{}
+	h.Set("x-ratelimit-limit-requests", "500")
+	h.Set("x-ratelimit-remaining-requests", "499")
+	h.Set("x-ratelimit-limit-tokens", "30000")
+	h.Set("x-ratelimit-remaining-tokens", "0")
+	h.Set("x-ratelimit-reset-tokens", "60ms")
+	g.Observe(http.StatusOK, h)
+
+	g.mu.Lock()
+	rpm, tpm, tokens := g.rpm, g.tpm, g.tokens
+	g.mu.Unlock()
+	if rpm != 500 || tpm != 30000 || tokens >= 1 {
+		t.Errorf("learned rpm=%v tpm=%v tokens=%v", rpm, tpm, tokens)
+	}
+	start := time.Now()
+	if err := g.Acquire(context.Background(), LaneForeground, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("exhausted token bucket should pause until reset, waited %s", elapsed)
+	}
+
+	// A configured limit below the advertised one wins.
+	g = NewGovernor(RateLimit{RequestsPerMinute: 50})
+	g.Observe(http.StatusOK, h)
+	if g.rpm != 50 {
+		t.Errorf("configured limit overridden: rpm=%v", g.rpm)
+	}
+}
+
+func TestGovernor_RetryAfter(t *testing.T) {
+	g := NewGovernor(RateLimit{})
+	h := http.Header{}
+	h.Set("Retry-After", "0.05")
+	g.Observe(http.StatusTooManyRequests, h)
+
+	var wait time.Duration
+	if err := g.Acquire(context.Background(), LaneForeground, 0, func(d time.Duration) { wait = d }); err != nil {
+		t.Fatal(err)
+	}
+	if wait <= 0 || wait > 50*time.Millisecond {
+		t.Errorf("expected a Retry-After pause, got %s", wait)
+	}
+}
+
+func TestParseReset(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"1s", time.Second, true},
+		{"6m0s", 6 * time.Minute, true},
+		{"20ms", 20 * time.Millisecond, true},
+		{"2025-01-01T12:00:30Z", 30 * time.Second, true},
+		{"2", 2 * time.Second, true},
+		{"", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseReset(tt.in, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseReset(%q) = %s %v, want %s %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRateLimitedProvider_LearnsFromResponses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("anthropic-ratelimit-requests-limit", "40")
+		w.Header().Set("anthropic-ratelimit-requests-remaining", "39")
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"hi"},"done":true,"prompt_eval_count":12,"eval_count":3}` + "\n"))
+	}))
+	defer srv.Close()
+
+	gov := NewGovernor(RateLimit{})
+	p := NewRateLimitedProvider(NewOllamaProvider(srv.URL, "llama3.1:8b", nil), gov)
+	var throttled []Throttle
+	p.OnThrottle = func(th Throttle) { throttled = append(throttled, th) }
+
+	text, err := readText(context.Background(), p, userRequest("hello"))
+	if err != nil || text != "hi" {
+		t.Fatalf("readText = %q, %v", text, err)
+	}
+	gov.mu.Lock()
+	rpm, requests := gov.rpm, gov.requests
+	gov.mu.Unlock()
+	if rpm != 40 || requests >= 39.5 {
+		t.Errorf("governor did not learn from headers: rpm=%v requests=%v", rpm, requests)
+	}
+	if len(throttled) != 0 {
+		t.Errorf("unexpected throttle reports %+v", throttled)
+	}
+}
@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// permanentError marks an error that must not be retried or failed over,
+// whatever its text says (e.g. a failed scripted assertion quoting a tool
+// result that mentions "timeout").
+type permanentError struct{ error }
+
+func (e permanentError) Unwrap() error { return e.error }
+
+// IsRetryableError reports whether an API error is transient (rate limit,
+// overload, server error, network) and worth retrying or failing over.
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pe permanentError
+	if errors.As(err, &pe) {
+		return false
+	}
+	msg := err.Error()
+
+	// Rate limit (429)
+	if strings.Contains(msg, "429") || strings.Contains(msg, "rate limit") || strings.Contains(msg, "rate_limit") {
+		return true
+	}
+	// Anthropic overloaded (529)
+	if strings.Contains(msg, "529") || strings.Contains(msg, "overloaded") {
+		return true
+	}
+	// Server errors (500, 502, 503, 504)
+	for _, code := range []string{"500", "502", "503", "504"} {
+		if strings.Contains(msg, code) {
+			return true
+		}
+	}
+	// Network errors
+	if strings.Contains(msg, "connection refused") ||
+		strings.Contains(msg, "connection reset") ||
+		strings.Contains(msg, "timeout") ||
+		strings.Contains(msg, "EOF") ||
+		strings.Contains(msg, "temporary failure") {
+		return true
+	}
+	// Context cancelled is NOT retryable
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return false
+}
@@ -0,0 +1,267 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxSchemaAttempts bounds how often ChatJSON asks again after a reply fails
+// validation.
+const maxSchemaAttempts = 3
+
+// ChatJSON sends req with schema attached and decodes the reply into out.
+//
+// Providers that enforce schemas natively (see ResponseSchemaSupporter) get
+// the schema as-is. Everyone else gets it spelled out in the system prompt.
+// Either way the reply is validated, and an invalid one is sent back with
+// the validation error for another attempt. Tools are dropped: the model is
+// expected to answer directly.
+func ChatJSON(ctx context.Context, p Provider, req *ChatRequest, schema *ResponseSchema, out any) error {
+	r := *req
+	r.Tools = nil
+	r.ResponseSchema = schema
+	model := r.Model
+	if model == "" {
+		model = p.DefaultModel()
+	}
+	if !SupportsResponseSchema(p, model) {
+		r.SystemPrompt = appendSchemaInstructions(r.SystemPrompt, schema)
+	}
+
+	msgs := slices.Clone(req.Messages)
+	var lastErr error
+	for attempt := 0; attempt < maxSchemaAttempts; attempt++ {
+		r.Messages = msgs
+		reply, err := readText(ctx, p, &r)
+		if err != nil {
+			return err
+		}
+		doc, err := decodeSchemaReply(reply, schema.Schema)
+		if err == nil {
+			return json.Unmarshal(doc, out)
+		}
+		lastErr = err
+		msgs = append(msgs,
+			Message{Role: RoleAssistant, Content: []Content{{Type: ContentTypeText, Text: reply}}},
+			Message{Role: RoleUser, Content: []Content{{Type: ContentTypeText, Text: fmt.Sprintf(
+				"That reply is not a valid %s document: %v. Reply with only the corrected JSON.", schema.Name, err)}}},
+		)
+	}
+	return fmt.Errorf("no valid %s after %d attempts: %w", schema.Name, maxSchemaAttempts, lastErr)
+}
+
+// SupportsResponseSchema reports whether p enforces response schemas for model.
+func SupportsResponseSchema(p Provider, model string) bool {
+	s, ok := p.(ResponseSchemaSupporter)
+	return ok && s.SupportsResponseSchema(model)
+}
+
+func appendSchemaInstructions(system string, schema *ResponseSchema) string {
+	data, _ := json.MarshalIndent(schema.Schema, "", "  ")
+	var sb strings.Builder
+	sb.WriteString(system)
+	if system != "" {
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("Respond with a single JSON document")
+	if schema.Description != "" {
+		sb.WriteString(" (" + schema.Description + ")")
+	}
+	sb.WriteString(" that matches this JSON Schema. Output only the JSON, no prose and no code fences.\n\n")
+	sb.Write(data)
+	return sb.String()
+}
+
+// readText drains a chat stream into its text.
+func readText(ctx context.Context, p Provider, req *ChatRequest) (string, error) {
+	events, err := p.Chat(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for ev := range events {
+		switch ev.Type {
+		case EventTextDelta:
+			sb.WriteString(ev.TextDelta)
+		case EventError:
+			return "", ev.Error
+		}
+	}
+	return sb.String(), nil
+}
+
+// decodeSchemaReply extracts the JSON document from reply and validates it.
+func decodeSchemaReply(reply string, schema map[string]any) ([]byte, error) {
+	doc := extractJSONDocument(reply)
+	if doc == "" {
+		return nil, errors.New("no JSON object found")
+	}
+	var v any
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := validateSchema(v, schema, "$"); err != nil {
+		return nil, err
+	}
+	return []byte(doc), nil
+}
+
+// extractJSONDocument returns the JSON object in text, tolerating the code
+// fences and preambles that prompted models add.
+func extractJSONDocument(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "{") && json.Valid([]byte(text)) {
+		return text
+	}
+	if idx := strings.Index(text, "```"); idx >= 0 {
+		start := idx + 3
+		// Skip a language identifier on the fence line.
+		if nl := strings.Index(text[start:], "\n"); nl >= 0 {
+			start += nl + 1
+		}
+		if end := strings.Index(text[start:], "```"); end >= 0 {
+			if candidate := strings.TrimSpace(text[start : start+end]); strings.HasPrefix(candidate, "{") {
+				return candidate
+			}
+		}
+	}
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end <= start {
+		return ""
+	}
+	return text[start : end+1]
+}
+
+// validateSchema checks v against the subset of JSON Schema used for
+// response schemas: type, enum, properties, required, items, minItems and
+// maxItems. Unknown keywords are ignored.
+func validateSchema(v any, schema map[string]any, path string) error {
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return jsonTypeMatches(v, t) }) {
+		return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonTypeName(v))
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(v) }) {
+		return fmt.Errorf("%s: %v is not one of %v", path, v, enum)
+	}
+	if enum, ok := schema["enum"].([]string); ok && !slices.Contains(enum, fmt.Sprint(v)) {
+		return fmt.Errorf("%s: %v is not one of %v", path, v, enum)
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		for _, name := range schemaStrings(schema["required"]) {
+			if _, ok := val[name]; !ok {
+				return fmt.Errorf("%s: missing required field %q", path, name)
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for name, sub := range props {
+			field, ok := val[name]
+			subSchema, isMap := sub.(map[string]any)
+			if !ok || !isMap || field == nil {
+				continue
+			}
+			if err := validateSchema(field, subSchema, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if n, ok := schemaInt(schema["minItems"]); ok && len(val) < n {
+			return fmt.Errorf("%s: expected at least %d items, got %d", path, n, len(val))
+		}
+		if n, ok := schemaInt(schema["maxItems"]); ok && len(val) > n {
+			return fmt.Errorf("%s: expected at most %d items, got %d", path, n, len(val))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range val {
+				if err := validateSchema(item, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func jsonTypeMatches(v any, t string) bool {
+	switch t {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	}
+	return true
+}
+
+func jsonTypeName(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// schemaTypes, schemaStrings and schemaInt accept both Go-literal schemas
+// ([]string, int) and decoded JSON ([]any, float64).
+func schemaTypes(v any) []string {
+	if s, ok := v.(string); ok {
+		return []string{s}
+	}
+	return schemaStrings(v)
+}
+
+func schemaStrings(v any) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []any:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func schemaInt(v any) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
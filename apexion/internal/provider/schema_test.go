@@ -0,0 +1,148 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var testPlanSchema = &ResponseSchema{
+	Name: "plan",
+	Schema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"summary": map[string]any{"type": "string"},
+			"steps": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"action": map[string]any{"type": "string", "enum": []string{"create", "modify"}}},
+					"required":   []string{"action"},
+				},
+			},
+		},
+		"required": []string{"summary", "steps"},
+	},
+}
+
+type testPlan struct {
+	Summary string `json:"summary"`
+	Steps   []struct {
+		Action string `json:"action"`
+	} `json:"steps"`
+}
+
+func TestChatJSON_PromptedFallbackRetriesInvalidReplies(t *testing.T) {
+	p, err := ParseScript([]byte(`
+turns:
+  - expect:
+      system_contains: "matches this JSON Schema"
+    text: "Sure! {\"summary\": \"add flag\"}"
+  - expect:
+      user_contains: "missing required field \"steps\""
+    text: "` + "```json\\n" + `{\"summary\": \"add flag\", \"steps\": [{\"action\": \"modify\"}]}` + "\\n```" + `"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := userRequest("plan it")
+	var plan testPlan
+	if err := ChatJSON(context.Background(), p, req, testPlanSchema, &plan); err != nil {
+		t.Fatalf("ChatJSON: %v", err)
+	}
+	if plan.Summary != "add flag" || len(plan.Steps) != 1 || plan.Steps[0].Action != "modify" {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+	if len(req.Messages) != 1 || len(req.Tools) != 2 {
+		t.Error("ChatJSON must not modify the caller's request")
+	}
+}
+
+func TestChatJSON_GivesUp(t *testing.T) {
+	p, err := ParseScript([]byte(`
+turns:
+  - text: "no json here"
+  - text: "{\"summary\": 1, \"steps\": []}"
+  - text: "{\"summary\": \"x\", \"steps\": [{\"action\": \"delete\"}]}"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plan testPlan
+	err = ChatJSON(context.Background(), p, userRequest("plan it"), testPlanSchema, &plan)
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") || !strings.Contains(err.Error(), "delete is not one of") {
+		t.Errorf("expected failure after 3 attempts, got %v", err)
+	}
+}
+
+func TestChatJSON_NativeSchema(t *testing.T) {
+	var got ollamaChatRequest
+	srv, _ := newOllamaStub(t, []string{
+		`{"message":{"role":"assistant","content":"{\"summary\":\"s\",\"steps\":[]}"},"done":true}`,
+	}, &got)
+	defer srv.Close()
+
+	p := NewOllamaProvider(srv.URL, "qwen2.5-coder:32b", nil)
+	var plan testPlan
+	if err := ChatJSON(context.Background(), p, userRequest("plan it"), testPlanSchema, &plan); err != nil {
+		t.Fatalf("ChatJSON: %v", err)
+	}
+	if got.Format["type"] != "object" || len(got.Tools) != 0 {
+		t.Errorf("expected schema in format and no tools, got %+v", got)
+	}
+	for _, m := range got.Messages {
+		if strings.Contains(m.Content, "JSON Schema") {
+			t.Error("native providers should not get the schema in the prompt")
+		}
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		doc     string
+		wantErr string
+	}{
+		{`{"summary": "s", "steps": []}`, ""},
+		{`{"summary": "s", "steps": [{"action": "create", "extra": true}]}`, ""},
+		{`{"summary": "s"}`, `missing required field "steps"`},
+		{`{"summary": 3, "steps": []}`, "$.summary: expected string, got number"},
+		{`{"summary": "s", "steps": {}}`, "$.steps: expected array, got object"},
+		{`{"summary": "s", "steps": [{"action": "run"}]}`, `$.steps[0].action: run is not one of`},
+		{`[]`, "$: expected object, got array"},
+	}
+	for _, tt := range tests {
+		var v any
+		if err := json.Unmarshal([]byte(tt.doc), &v); err != nil {
+			t.Fatal(err)
+		}
+		err := validateSchema(v, testPlanSchema.Schema, "$")
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.doc, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.doc, tt.wantErr, err)
+		}
+	}
+}
+
+func TestAnthropicSchemaTool(t *testing.T) {
+	tool := anthropicSchemaTool(testPlanSchema)
+	data, err := json.Marshal(tool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Name        string         `json:"name"`
+		InputSchema map[string]any `json:"input_schema"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "plan" || got.InputSchema["type"] != "object" || got.InputSchema["properties"] == nil {
+		t.Errorf("unexpected tool %s", data)
+	}
+	if req, _ := got.InputSchema["required"].([]any); len(req) != 2 {
+		t.Errorf("required fields not carried over: %s", data)
+	}
+}
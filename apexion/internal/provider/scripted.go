@@ -0,0 +1,362 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScriptedProvider replays a hand-written scenario instead of calling a
+// model. Each Chat call consumes the next turn of the script: it first checks
+// the turn's expectations against the request (typically the tool results
+// the agent sent back), then streams the scripted text, reasoning and tool
+// calls. Failed expectations are returned as Chat errors, which ends the
+// agent turn, so a scenario fails loudly both under `go test` and
+// `apexion run --provider scripted`.
+//
+//	model: scripted
+//	turns:
+//	  - text: "Let me fix that."
+//	    tool_calls:
+//	      - name: edit_file
+//	        input: {file_path: main.go, old_string: "fmt.Println", new_string: "log.Println"}
+//	  - expect:
+//	      tool_results:
+//	        - tool: edit_file
+//	          is_error: true
+//	          contains: "not found"
+//	    error: "status 429 rate limited"   # injected; the agent retries
+//	  - delay: 200ms
+//	    text: "Done."
+//	    usage: {input: 1200, output: 40}
+//	    stop_reason: end_turn   # default; max_tokens simulates truncation
+type ScriptedProvider struct {
+	script Script
+
+	mu   sync.Mutex
+	next int
+	seq  int
+}
+
+// Script is the YAML document read by ScriptedProvider.
+type Script struct {
+	Model         string         `yaml:"model"`
+	ContextWindow int            `yaml:"context_window"`
+	Turns         []ScriptedTurn `yaml:"turns"`
+}
+
+// ScriptedTurn is one model response.
+type ScriptedTurn struct {
+	Expect    *ScriptedExpect    `yaml:"expect"`
+	Delay     time.Duration      `yaml:"delay"`    // wait before responding
+	Error     string             `yaml:"error"`    // fail the Chat call itself
+	Thinking  string             `yaml:"thinking"` // streamed as reasoning
+	Text      string             `yaml:"text"`     // streamed as one delta
+	Deltas    []string           `yaml:"deltas"`   // streamed as separate deltas (after text)
+	ToolCalls []ScriptedToolCall `yaml:"tool_calls"`
+	StreamErr string             `yaml:"stream_error"` // emitted after the content, instead of Done
+	Usage     *ScriptedUsage     `yaml:"usage"`
+	// StopReason is reported with Done; it defaults to tool_use when the
+	// turn has tool calls and end_turn otherwise. Set max_tokens to
+	// simulate truncated output.
+	StopReason StopReason `yaml:"stop_reason"`
+}
+
+// ScriptedToolCall is a tool call emitted by a turn. RawInput, if set, is
+// sent verbatim (e.g. malformed JSON to exercise tool repair).
+type ScriptedToolCall struct {
+	ID       string         `yaml:"id"`
+	Name     string         `yaml:"name"`
+	Input    map[string]any `yaml:"input"`
+	RawInput string         `yaml:"raw_input"`
+}
+
+// ScriptedUsage is the token usage reported when a turn completes.
+type ScriptedUsage struct {
+	Input     int `yaml:"input"`
+	Output    int `yaml:"output"`
+	CacheRead int `yaml:"cache_read"`
+}
+
+// ScriptedExpect asserts on the request that a turn answers.
+type ScriptedExpect struct {
+	// ToolResults must match, in order, the tool results of the latest user
+	// turn (the messages after the last assistant message).
+	ToolResults []ScriptedToolResult `yaml:"tool_results"`
+	// UserContains must appear in the text of the latest user turn.
+	UserContains string `yaml:"user_contains"`
+	// SystemContains must appear in the system prompt.
+	SystemContains string `yaml:"system_contains"`
+	// HistoryContains must appear in the text of some message of the
+	// request, e.g. the summary injected after compaction.
+	HistoryContains string `yaml:"history_contains"`
+	// MaxTokens, if set, must equal the request's output token budget.
+	MaxTokens int `yaml:"max_tokens"`
+	// Tools must all be offered in the request.
+	Tools []string `yaml:"tools"`
+}
+
+// ScriptedToolResult matches one tool result.
+type ScriptedToolResult struct {
+	Tool     string `yaml:"tool"`
+	Contains string `yaml:"contains"`
+	IsError  *bool  `yaml:"is_error"`
+	Image    *bool  `yaml:"image"` // whether the result carries an image
+}
+
+// NewScriptedProvider loads a scenario from a YAML file.
+func NewScriptedProvider(path string) (*ScriptedProvider, error) {
+	if path == "" {
+		return nil, errors.New("scripted provider needs a script: set providers.scripted.script or APEXION_SCRIPT")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read script: %w", err)
+	}
+	p, err := ParseScript(data)
+	if err != nil {
+		return nil, fmt.Errorf("script %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ParseScript builds a ScriptedProvider from YAML.
+func ParseScript(data []byte) (*ScriptedProvider, error) {
+	var s Script
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if len(s.Turns) == 0 {
+		return nil, errors.New("script has no turns")
+	}
+	if s.Model == "" {
+		s.Model = "scripted"
+	}
+	if s.ContextWindow <= 0 {
+		s.ContextWindow = 128000
+	}
+	return &ScriptedProvider{script: s}, nil
+}
+
+func (p *ScriptedProvider) Name() string         { return "scripted" }
+func (p *ScriptedProvider) Models() []string     { return []string{p.script.Model} }
+func (p *ScriptedProvider) DefaultModel() string { return p.script.Model }
+func (p *ScriptedProvider) ContextWindow() int   { return p.script.ContextWindow }
+
+// Remaining returns how many turns have not been played yet.
+func (p *ScriptedProvider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.script.Turns) - p.next
+}
+
+func (p *ScriptedProvider) Chat(ctx context.Context, req *ChatRequest) (<-chan Event, error) {
+	p.mu.Lock()
+	if p.next >= len(p.script.Turns) {
+		p.mu.Unlock()
+		return nil, permanentError{fmt.Errorf("scripted: script exhausted after %d turns", len(p.script.Turns))}
+	}
+	n := p.next
+	turn := p.script.Turns[n]
+	p.next++
+	p.mu.Unlock()
+
+	if turn.Expect != nil {
+		if err := turn.Expect.check(req); err != nil {
+			return nil, permanentError{fmt.Errorf("scripted: turn %d: %w", n+1, err)}
+		}
+	}
+	if turn.Delay > 0 {
+		t := time.NewTimer(turn.Delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+	if turn.Error != "" {
+		return nil, errors.New(turn.Error)
+	}
+
+	events := []Event{}
+	if turn.Thinking != "" {
+		events = append(events, Event{Type: EventThinkingDelta, ThinkingDelta: turn.Thinking})
+	}
+	if turn.Text != "" {
+		events = append(events, Event{Type: EventTextDelta, TextDelta: turn.Text})
+	}
+	for _, d := range turn.Deltas {
+		events = append(events, Event{Type: EventTextDelta, TextDelta: d})
+	}
+	for _, tc := range turn.ToolCalls {
+		call, err := p.toolCall(tc)
+		if err != nil {
+			return nil, fmt.Errorf("scripted: turn %d: %w", n+1, err)
+		}
+		events = append(events, Event{Type: EventToolCallDone, ToolCall: call})
+	}
+	if turn.StreamErr != "" {
+		events = append(events, Event{Type: EventError, Error: errors.New(turn.StreamErr)})
+	} else {
+		usage := &Usage{}
+		if turn.Usage != nil {
+			usage = &Usage{
+				InputTokens:     turn.Usage.Input,
+				OutputTokens:    turn.Usage.Output,
+				CacheReadTokens: turn.Usage.CacheRead,
+			}
+		}
+		stop := turn.StopReason
+		if stop == "" {
+			stop = StopEndTurn
+			if len(turn.ToolCalls) > 0 {
+				stop = StopToolUse
+			}
+		}
+		events = append(events, Event{Type: EventDone, Usage: usage, StopReason: stop})
+	}
+
+	ch := make(chan Event, len(events))
+	for _, ev := range events {
+		ch <- ev
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (p *ScriptedProvider) toolCall(tc ScriptedToolCall) (*ToolCallRequest, error) {
+	input := json.RawMessage(tc.RawInput)
+	if tc.RawInput == "" {
+		args := tc.Input
+		if args == nil {
+			args = map[string]any{}
+		}
+		data, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("tool call %s: %w", tc.Name, err)
+		}
+		input = data
+	}
+	id := tc.ID
+	if id == "" {
+		p.mu.Lock()
+		p.seq++
+		id = fmt.Sprintf("scripted_call_%d", p.seq)
+		p.mu.Unlock()
+	}
+	return &ToolCallRequest{ID: id, Name: tc.Name, Input: input}, nil
+}
+
+// check verifies the expectations against req.
+// historyContains reports whether the text of any message contains s.
+func historyContains(messages []Message, s string) bool {
+	for _, m := range messages {
+		for _, c := range m.Content {
+			if c.Type == ContentTypeText && strings.Contains(c.Text, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *ScriptedExpect) check(req *ChatRequest) error {
+	// The user side of the latest turn: everything after the last assistant
+	// message (tool results plus any injected hints).
+	start := len(req.Messages)
+	for start > 0 && req.Messages[start-1].Role != RoleAssistant {
+		start--
+	}
+	var turn []Content
+	for _, m := range req.Messages[start:] {
+		turn = append(turn, m.Content...)
+	}
+
+	if e.SystemContains != "" && !strings.Contains(req.SystemPrompt, e.SystemContains) {
+		return fmt.Errorf("system prompt does not contain %q", e.SystemContains)
+	}
+	if e.HistoryContains != "" && !historyContains(req.Messages, e.HistoryContains) {
+		return fmt.Errorf("no message contains %q", e.HistoryContains)
+	}
+	if e.MaxTokens > 0 && req.MaxTokens != e.MaxTokens {
+		return fmt.Errorf("expected max_tokens %d, got %d", e.MaxTokens, req.MaxTokens)
+	}
+	if e.UserContains != "" {
+		var text strings.Builder
+		for _, c := range turn {
+			if c.Type == ContentTypeText {
+				text.WriteString(c.Text)
+			}
+		}
+		if !strings.Contains(text.String(), e.UserContains) {
+			return fmt.Errorf("latest user turn does not contain %q (got %q)", e.UserContains, truncateScripted(text.String()))
+		}
+	}
+	for _, name := range e.Tools {
+		found := false
+		for _, t := range req.Tools {
+			if t.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("tool %q was not offered", name)
+		}
+	}
+
+	if len(e.ToolResults) == 0 {
+		return nil
+	}
+	// Resolve tool names from the tool_use blocks in history.
+	names := make(map[string]string)
+	for _, m := range req.Messages {
+		for _, c := range m.Content {
+			if c.Type == ContentTypeToolUse {
+				names[c.ToolUseID] = c.ToolName
+			}
+		}
+	}
+	var results []Content
+	for _, c := range turn {
+		if c.Type == ContentTypeToolResult {
+			results = append(results, c)
+		}
+	}
+	if len(results) != len(e.ToolResults) {
+		return fmt.Errorf("expected %d tool result(s), got %d", len(e.ToolResults), len(results))
+	}
+	for i, want := range e.ToolResults {
+		got := results[i]
+		if want.Tool != "" && names[got.ToolUseID] != want.Tool {
+			return fmt.Errorf("tool result %d: expected tool %q, got %q", i+1, want.Tool, names[got.ToolUseID])
+		}
+		if want.IsError != nil && got.IsError != *want.IsError {
+			return fmt.Errorf("tool result %d (%s): expected is_error=%v, got %v: %s",
+				i+1, names[got.ToolUseID], *want.IsError, got.IsError, truncateScripted(got.ToolResult))
+		}
+		if want.Image != nil && (got.ImageData != "") != *want.Image {
+			return fmt.Errorf("tool result %d (%s): expected image=%v", i+1, names[got.ToolUseID], *want.Image)
+		}
+		if want.Contains != "" && !strings.Contains(got.ToolResult, want.Contains) {
+			return fmt.Errorf("tool result %d (%s): expected to contain %q, got %q",
+				i+1, names[got.ToolUseID], want.Contains, truncateScripted(got.ToolResult))
+		}
+	}
+	return nil
+}
+
+func truncateScripted(s string) string {
+	if len(s) > 200 {
+		return s[:200] + "..."
+	}
+	return s
+}
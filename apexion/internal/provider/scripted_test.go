@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const testScript = `
+model: scripted-test
+turns:
+  - thinking: "plan"
+    text: "Editing. "
+    deltas: ["One ", "moment."]
+    tool_calls:
+      - name: edit_file
+        input: {file_path: main.go, old_string: a, new_string: b}
+      - name: glob
+        raw_input: '{"pattern": '
+    usage: {input: 120, output: 8}
+  - expect:
+      tool_results:
+        - tool: edit_file
+          is_error: true
+          contains: "not found"
+        - tool: glob
+    error: "status 503 unavailable"
+  - stream_error: "connection reset"
+`
+
+func TestScriptedProvider_PlaysTurns(t *testing.T) {
+	p, err := ParseScript([]byte(testScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.DefaultModel() != "scripted-test" || p.ContextWindow() != 128000 {
+		t.Errorf("unexpected metadata %s %d", p.DefaultModel(), p.ContextWindow())
+	}
+
+	var text, thinking strings.Builder
+	var calls []*ToolCallRequest
+	var usage *Usage
+	for ev := range mustChat(t, p, userRequest("fix it")) {
+		switch ev.Type {
+		case EventTextDelta:
+			text.WriteString(ev.TextDelta)
+		case EventThinkingDelta:
+			thinking.WriteString(ev.ThinkingDelta)
+		case EventToolCallDone:
+			calls = append(calls, ev.ToolCall)
+		case EventDone:
+			usage = ev.Usage
+		}
+	}
+	if text.String() != "Editing. One moment." || thinking.String() != "plan" {
+		t.Errorf("unexpected text %q / thinking %q", text.String(), thinking.String())
+	}
+	if len(calls) != 2 || string(calls[0].Input) != `{"file_path":"main.go","new_string":"b","old_string":"a"}` ||
+		string(calls[1].Input) != `{"pattern": ` || calls[0].ID == calls[1].ID {
+		t.Errorf("unexpected tool calls %+v", calls)
+	}
+	if usage == nil || usage.InputTokens != 120 || usage.OutputTokens != 8 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+
+	// Turn 2 checks the tool results, then injects a retryable error.
+	req := userRequest("fix it")
+	req.Messages = append(req.Messages,
+		Message{Role: RoleAssistant, Content: []Content{
+			{Type: ContentTypeToolUse, ToolUseID: calls[0].ID, ToolName: "edit_file"},
+			{Type: ContentTypeToolUse, ToolUseID: calls[1].ID, ToolName: "glob"},
+		}},
+		Message{Role: RoleUser, Content: []Content{{Type: ContentTypeText, Text: "[SYSTEM] hint"}}},
+		Message{Role: RoleUser, Content: []Content{
+			{Type: ContentTypeToolResult, ToolUseID: calls[0].ID, ToolResult: "text not found in file", IsError: true},
+			{Type: ContentTypeToolResult, ToolUseID: calls[1].ID, ToolResult: "invalid params"},
+		}},
+	)
+	_, err = p.Chat(context.Background(), req)
+	if err == nil || !IsRetryableError(err) {
+		t.Errorf("expected injected retryable error, got %v", err)
+	}
+
+	events := drain(mustChat(t, p, req))
+	if len(events) != 1 || events[0].Type != EventError || events[0].Error.Error() != "connection reset" {
+		t.Errorf("expected injected stream error, got %+v", events)
+	}
+
+	if p.Remaining() != 0 {
+		t.Errorf("expected script to be consumed, %d turns left", p.Remaining())
+	}
+	if _, err := p.Chat(context.Background(), req); err == nil || IsRetryableError(err) {
+		t.Errorf("expected permanent exhaustion error, got %v", err)
+	}
+}
+
+func TestScriptedProvider_ExpectationFailure(t *testing.T) {
+	p, err := ParseScript([]byte(`
+turns:
+  - expect:
+      tool_results:
+        - tool: bash
+          contains: "ok"
+    text: "never sent"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := userRequest("run")
+	req.Messages = append(req.Messages,
+		Message{Role: RoleAssistant, Content: []Content{{Type: ContentTypeToolUse, ToolUseID: "1", ToolName: "bash"}}},
+		Message{Role: RoleUser, Content: []Content{{Type: ContentTypeToolResult, ToolUseID: "1", ToolResult: "error: timeout after 500ms"}}},
+	)
+	_, err = p.Chat(context.Background(), req)
+	if err == nil || !strings.Contains(err.Error(), `expected to contain "ok"`) {
+		t.Fatalf("expected expectation failure, got %v", err)
+	}
+	if IsRetryableError(err) {
+		t.Error("expectation failures must not be retried even if they quote retryable text")
+	}
+}
+
+func TestParseScript_Invalid(t *testing.T) {
+	if _, err := ParseScript([]byte("turns: []")); err == nil {
+		t.Error("expected error for a script without turns")
+	}
+	if _, err := NewScriptedProvider(""); err == nil {
+		t.Error("expected error for a missing script path")
+	}
+}
@@ -1,5 +1,23 @@
 package session
 
+import "github.com/apexion-ai/apexion/internal/tokenizer"
+
+// TokenCounter counts the tokens a model sees for a piece of text.
+// tokenizer.ForModel returns the counter for a provider/model.
+type TokenCounter interface {
+	CountTokens(text string) int
+}
+
+// defaultCounter is used when no counter is given.
+var defaultCounter TokenCounter = tokenizer.ForModel("", "")
+
+func counterOrDefault(tc TokenCounter) TokenCounter {
+	if tc == nil {
+		return defaultCounter
+	}
+	return tc
+}
+
 // TokenBudget manages context window allocation for a model.
 type TokenBudget struct {
 	ContextWindow int // total context window of the model
@@ -3,6 +3,7 @@ package session
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/apexion-ai/apexion/internal/provider"
 )
@@ -23,6 +24,10 @@ type Turn struct {
 //   - All assistant↔tool_result chains are kept within the same turn.
 //   - An incomplete turn (ending with tool_use awaiting results) is preserved.
 func SplitTurns(messages []provider.Message) []Turn {
+	return splitTurns(messages, nil)
+}
+
+func splitTurns(messages []provider.Message, tc TokenCounter) []Turn {
 	if len(messages) == 0 {
 		return nil
 	}
@@ -43,7 +48,7 @@ func SplitTurns(messages []provider.Message) []Turn {
 		prev := messages[i-1]
 		if prev.Role == provider.RoleAssistant && !hasToolUse(prev) {
 			// Cut here: messages[currentStart:i] form one turn.
-			turn := makeTurn(messages[currentStart:i])
+			turn := makeTurn(messages[currentStart:i], tc)
 			turn.Complete = true
 			turns = append(turns, turn)
 			currentStart = i
@@ -52,7 +57,7 @@ func SplitTurns(messages []provider.Message) []Turn {
 
 	// Remaining messages form the last turn.
 	if currentStart < len(messages) {
-		turn := makeTurn(messages[currentStart:])
+		turn := makeTurn(messages[currentStart:], tc)
 		// Check if it's complete (ends with assistant without tool_use).
 		last := messages[len(messages)-1]
 		turn.Complete = last.Role == provider.RoleAssistant && !hasToolUse(last)
@@ -62,10 +67,10 @@ func SplitTurns(messages []provider.Message) []Turn {
 	return turns
 }
 
-func makeTurn(msgs []provider.Message) Turn {
+func makeTurn(msgs []provider.Message, tc TokenCounter) Turn {
 	return Turn{
 		Messages: msgs,
-		Tokens:   estimateMessagesTokens(msgs),
+		Tokens:   estimateMessagesTokens(msgs, tc),
 	}
 }
 
@@ -94,7 +99,9 @@ func hasToolUse(msg provider.Message) bool {
 //	Phase A: Summary injection (if summary is non-empty)
 //	Phase B: Observation masking (replace old tool_result content with placeholder)
 //	Phase C: Turn-level trimming (remove oldest turns if still over maxTokens)
-func CompactHistory(messages []provider.Message, maxTokens int, summary string) []provider.Message {
+//
+// Tokens are counted with tc (the default tokenizer if nil).
+func CompactHistory(messages []provider.Message, maxTokens int, summary string, tc TokenCounter) []provider.Message {
 	if len(messages) == 0 {
 		return messages
 	}
@@ -214,8 +221,8 @@ func CompactHistory(messages []provider.Message, maxTokens int, summary string)
 	}
 
 	// Phase C: Turn-level trimming (if still over maxTokens)
-	if estimateMessagesTokens(result) > maxTokens {
-		turns := SplitTurns(result)
+	if estimateMessagesTokens(result, tc) > maxTokens {
+		turns := splitTurns(result, tc)
 		const minKeepTurns = 5
 
 		// If summary was injected, the first "turn" contains the summary message.
@@ -273,12 +280,14 @@ func cloneMessages(msgs []provider.Message) []provider.Message {
 		}
 		for j, c := range msg.Content {
 			result[i].Content[j] = provider.Content{
-				Type:       c.Type,
-				Text:       string([]byte(c.Text)),
-				ToolUseID:  string([]byte(c.ToolUseID)),
-				ToolName:   string([]byte(c.ToolName)),
-				ToolResult: string([]byte(c.ToolResult)),
-				IsError:    c.IsError,
+				Type:        c.Type,
+				Text:        string([]byte(c.Text)),
+				ToolUseID:   string([]byte(c.ToolUseID)),
+				ToolName:    string([]byte(c.ToolName)),
+				ToolResult:  string([]byte(c.ToolResult)),
+				IsError:     c.IsError,
+				Signature:   string([]byte(c.Signature)),
+				ReasoningID: string([]byte(c.ReasoningID)),
 			}
 			if len(c.ToolInput) > 0 {
 				result[i].Content[j].ToolInput = append(json.RawMessage{}, c.ToolInput...)
@@ -293,11 +302,18 @@ func cloneMessages(msgs []provider.Message) []provider.Message {
 // StripImageData removes image data from messages to save tokens.
 // Replaces image content blocks with text placeholders.
 func StripImageData(messages []provider.Message) []provider.Message {
+	return ReplaceImages(messages, "[Image omitted during compaction]")
+}
+
+// ReplaceImages replaces every image in messages, attached or returned in
+// a tool result, with placeholder text. Messages without images are shared
+// with the input, the rest are copied.
+func ReplaceImages(messages []provider.Message, placeholder string) []provider.Message {
 	result := make([]provider.Message, 0, len(messages))
 	for _, msg := range messages {
 		needsStrip := false
 		for _, c := range msg.Content {
-			if c.Type == provider.ContentTypeImage && c.ImageData != "" {
+			if (c.Type == provider.ContentTypeImage || c.Type == provider.ContentTypeToolResult) && c.ImageData != "" {
 				needsStrip = true
 				break
 			}
@@ -314,13 +330,18 @@ func StripImageData(messages []provider.Message) []provider.Message {
 			Content: make([]provider.Content, 0, len(msg.Content)),
 		}
 		for _, c := range msg.Content {
-			if c.Type == provider.ContentTypeImage {
+			switch {
+			case c.Type == provider.ContentTypeImage:
 				// Replace image with text placeholder.
 				newMsg.Content = append(newMsg.Content, provider.Content{
 					Type: provider.ContentTypeText,
-					Text: "[Image omitted during compaction]",
+					Text: placeholder,
 				})
-			} else {
+			case c.Type == provider.ContentTypeToolResult && c.ImageData != "":
+				c.ImageData, c.ImageMediaType = "", ""
+				c.ToolResult = strings.TrimSpace(c.ToolResult + "\n" + placeholder)
+				newMsg.Content = append(newMsg.Content, c)
+			default:
 				newMsg.Content = append(newMsg.Content, c)
 			}
 		}
@@ -563,16 +584,17 @@ func countToolResults(msg provider.Message) int {
 	return n
 }
 
-func estimateMessagesTokens(messages []provider.Message) int {
+func estimateMessagesTokens(messages []provider.Message, tc TokenCounter) int {
+	tc = counterOrDefault(tc)
 	total := 0
 	for _, msg := range messages {
 		for _, c := range msg.Content {
-			total += len(c.Text)
-			total += len(c.ToolResult)
-			total += len(c.ToolInput)
+			total += tc.CountTokens(c.Text)
+			total += tc.CountTokens(c.ToolResult)
+			total += tc.CountTokens(string(c.ToolInput))
 		}
 	}
-	return total / 4
+	return total
 }
 
 func estimateTurnsTokens(turns []Turn) int {
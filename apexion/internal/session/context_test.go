@@ -193,7 +193,7 @@ func TestSplitTurns_ToolUseFollowedByNewUser(t *testing.T) {
 // --- CompactHistory tests ---
 
 func TestCompactHistory_Empty(t *testing.T) {
-	result := CompactHistory(nil, 100000, "")
+	result := CompactHistory(nil, 100000, "", nil)
 	if len(result) != 0 {
 		t.Errorf("expected empty, got %d messages", len(result))
 	}
@@ -204,7 +204,7 @@ func TestCompactHistory_SummaryInjection(t *testing.T) {
 		userText("hello"),
 		assistantText("hi"),
 	}
-	result := CompactHistory(msgs, 100000, "previous context summary")
+	result := CompactHistory(msgs, 100000, "previous context summary", nil)
 	if len(result) != 3 {
 		t.Fatalf("expected 3 messages (summary + 2 original), got %d", len(result))
 	}
@@ -224,7 +224,7 @@ func TestCompactHistory_NoSummary(t *testing.T) {
 		userText("hello"),
 		assistantText("hi"),
 	}
-	result := CompactHistory(msgs, 100000, "")
+	result := CompactHistory(msgs, 100000, "", nil)
 	if len(result) != 2 {
 		t.Fatalf("expected 2 messages (no summary), got %d", len(result))
 	}
@@ -245,7 +245,7 @@ func TestCompactHistory_ObservationMasking(t *testing.T) {
 	// Add a final user message for context.
 	msgs = append(msgs, userText("what now?"))
 
-	result := CompactHistory(msgs, 1000000, "") // large maxTokens to avoid trimming
+	result := CompactHistory(msgs, 1000000, "", nil) // large maxTokens to avoid trimming
 
 	// Count masked vs unmasked tool results.
 	masked := 0
@@ -290,7 +290,7 @@ func TestCompactHistory_DoesNotMutateOriginal(t *testing.T) {
 		)
 	}
 
-	_ = CompactHistory(msgs, 1000000, "")
+	_ = CompactHistory(msgs, 1000000, "", nil)
 
 	// Original messages should not be modified.
 	if msgs[2].Content[0].ToolResult != originalResult {
@@ -309,7 +309,7 @@ func TestCompactHistory_TurnTrimming(t *testing.T) {
 	}
 
 	// Set a small maxTokens to force trimming.
-	result := CompactHistory(msgs, 5000, "")
+	result := CompactHistory(msgs, 5000, "", nil)
 
 	// Should have fewer messages than original.
 	if len(result) >= len(msgs) {
@@ -322,6 +322,30 @@ func TestCompactHistory_TurnTrimming(t *testing.T) {
 	}
 }
 
+// quarterCounter is the old chars/4 estimate.
+type quarterCounter struct{}
+
+func (quarterCounter) CountTokens(s string) int { return len(s) / 4 }
+
+func TestCompactHistory_UsesTokenCounter(t *testing.T) {
+	// 40 messages of 138 Han characters: 5520 characters, which chars/4
+	// counts as 4140 tokens (each character is 3 bytes).
+	sentence := "请帮我检查这个函数为什么会返回空指针然后修复它" // 23 characters
+	var msgs []provider.Message
+	for i := 0; i < 20; i++ {
+		msgs = append(msgs,
+			userText(strings.Repeat(sentence, 6)),
+			assistantText(strings.Repeat(sentence, 6)),
+		)
+	}
+	if got := len(CompactHistory(msgs, 5000, "", quarterCounter{})); got != len(msgs) {
+		t.Errorf("chars/4: expected no trimming, got %d of %d messages", got, len(msgs))
+	}
+	if got := len(CompactHistory(msgs, 5000, "", nil)); got >= len(msgs) {
+		t.Errorf("default tokenizer: expected CJK history over budget to be trimmed, got %d of %d messages", got, len(msgs))
+	}
+}
+
 func TestCompactHistory_PreservesToolUsePairs(t *testing.T) {
 	// After compaction, every tool_use should have a matching tool_result.
 	var msgs []provider.Message
@@ -335,7 +359,7 @@ func TestCompactHistory_PreservesToolUsePairs(t *testing.T) {
 		)
 	}
 
-	result := CompactHistory(msgs, 5000, "")
+	result := CompactHistory(msgs, 5000, "", nil)
 
 	// Collect tool_use IDs and tool_result IDs.
 	useIDs := map[string]bool{}
@@ -492,6 +516,30 @@ func TestNewTokenBudget(t *testing.T) {
 	}
 }
 
+// --- ReplaceImages tests ---
+
+func TestReplaceImages_ToolResultsAndAttachments(t *testing.T) {
+	shot := toolResult("t1", "[Image: image/png]")
+	shot.Content[0].ImageData = "aGVsbG8="
+	shot.Content[0].ImageMediaType = "image/png"
+	attached := userText("look")
+	attached.Content = append(attached.Content, provider.Content{Type: provider.ContentTypeImage, ImageData: "aGk=", ImageMediaType: "image/jpeg"})
+	msgs := []provider.Message{attached, assistantWithToolUse("", "t1", "screenshot"), shot}
+
+	result := ReplaceImages(msgs, "[no images]")
+
+	if got := result[0].Content[1]; got.Type != provider.ContentTypeText || got.Text != "[no images]" {
+		t.Errorf("expected attachment replaced by text, got %+v", got)
+	}
+	got := result[2].Content[0]
+	if got.ImageData != "" || got.ImageMediaType != "" || got.ToolResult != "[Image: image/png]\n[no images]" {
+		t.Errorf("expected image dropped from tool result, got %+v", got)
+	}
+	if msgs[2].Content[0].ImageData == "" {
+		t.Error("input messages must not be modified")
+	}
+}
+
 // --- MaskOldToolOutputs tests ---
 
 func TestMaskOldToolOutputs_MasksOldKeepsRecent(t *testing.T) {
@@ -0,0 +1,174 @@
+package session
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Plan step and check statuses.
+const (
+	PlanPending    = "pending"
+	PlanInProgress = "in_progress"
+	PlanDone       = "done"
+	PlanFailed     = "failed"
+	PlanSkipped    = "skipped"
+)
+
+// Plan is a structured implementation plan produced in plan mode. Each
+// session has at most one; a new plan replaces the previous one.
+type Plan struct {
+	SessionID    string      `json:"-" yaml:"-"`
+	Summary      string      `json:"summary" yaml:"summary"`
+	Steps        []PlanStep  `json:"steps" yaml:"steps"`
+	Verification []PlanCheck `json:"verification,omitempty" yaml:"verification,omitempty"`
+	UpdatedAt    time.Time   `json:"-" yaml:"-"`
+}
+
+// PlanStep is one step of a plan.
+type PlanStep struct {
+	Description string   `json:"description" yaml:"description"`
+	Files       []string `json:"files,omitempty" yaml:"files,omitempty"`
+	Details     string   `json:"details,omitempty" yaml:"details,omitempty"`
+	Verify      string   `json:"verify,omitempty" yaml:"verify,omitempty"` // shell command that checks the step
+	Status      string   `json:"status" yaml:"status"`
+	Note        string   `json:"note,omitempty" yaml:"note,omitempty"` // why it failed, or what was done
+}
+
+// PlanCheck is a shell command that verifies the plan as a whole once all
+// steps are done.
+type PlanCheck struct {
+	Run    string `json:"run" yaml:"run"`
+	Status string `json:"status" yaml:"status"`
+}
+
+// Status summarizes the plan: failed if any step failed, done when every
+// step is done or skipped, in_progress once any step has started.
+func (p *Plan) Status() string {
+	started, finished := false, true
+	for _, s := range p.Steps {
+		switch s.Status {
+		case PlanFailed:
+			return PlanFailed
+		case PlanDone, PlanSkipped:
+			started = true
+		case PlanInProgress:
+			started, finished = true, false
+		default:
+			finished = false
+		}
+	}
+	switch {
+	case finished:
+		return PlanDone
+	case started:
+		return PlanInProgress
+	}
+	return PlanPending
+}
+
+// Validate checks a plan after it was edited by hand.
+func (p *Plan) Validate() error {
+	var errs []error
+	if len(p.Steps) == 0 {
+		errs = append(errs, errors.New("plan has no steps"))
+	}
+	valid := func(status string) bool {
+		switch status {
+		case PlanPending, PlanInProgress, PlanDone, PlanFailed, PlanSkipped:
+			return true
+		}
+		return false
+	}
+	for i, s := range p.Steps {
+		if strings.TrimSpace(s.Description) == "" {
+			errs = append(errs, fmt.Errorf("step %d: description is empty", i+1))
+		}
+		if !valid(s.Status) {
+			errs = append(errs, fmt.Errorf("step %d: unknown status %q", i+1, s.Status))
+		}
+	}
+	for i, c := range p.Verification {
+		if strings.TrimSpace(c.Run) == "" {
+			errs = append(errs, fmt.Errorf("verification %d: run is empty", i+1))
+		}
+		if !valid(c.Status) {
+			errs = append(errs, fmt.Errorf("verification %d: unknown status %q", i+1, c.Status))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PlanStore persists the plan of each session.
+type PlanStore interface {
+	SavePlan(p *Plan) error
+	// LoadPlan returns the session's plan, or nil if it has none.
+	LoadPlan(sessionID string) (*Plan, error)
+}
+
+// NullPlanStore is a no-op implementation.
+type NullPlanStore struct{}
+
+func (NullPlanStore) SavePlan(*Plan) error           { return nil }
+func (NullPlanStore) LoadPlan(string) (*Plan, error) { return nil, nil }
+
+const createPlansTableSQL = `
+CREATE TABLE IF NOT EXISTS plans (
+    session_id TEXT PRIMARY KEY,
+    data       TEXT NOT NULL,
+    updated_ms INTEGER NOT NULL
+);
+`
+
+// SQLitePlanStore implements PlanStore backed by SQLite.
+type SQLitePlanStore struct {
+	db *sql.DB
+}
+
+// NewSQLitePlanStore creates a plan store using an existing SQLite DB connection.
+// The plans table is created if it doesn't exist.
+func NewSQLitePlanStore(db *sql.DB) (*SQLitePlanStore, error) {
+	if _, err := db.Exec(createPlansTableSQL); err != nil {
+		return nil, fmt.Errorf("create plans table: %w", err)
+	}
+	return &SQLitePlanStore{db: db}, nil
+}
+
+func (s *SQLitePlanStore) SavePlan(p *Plan) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal plan: %w", err)
+	}
+	p.UpdatedAt = time.Now()
+	_, err = s.db.Exec(`
+		INSERT INTO plans (session_id, data, updated_ms) VALUES (?, ?, ?)
+		ON CONFLICT(session_id) DO UPDATE SET data = excluded.data, updated_ms = excluded.updated_ms`,
+		p.SessionID, string(data), p.UpdatedAt.UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("save plan: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLitePlanStore) LoadPlan(sessionID string) (*Plan, error) {
+	var data string
+	var updatedMS int64
+	err := s.db.QueryRow(`SELECT data, updated_ms FROM plans WHERE session_id = ?`, sessionID).Scan(&data, &updatedMS)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load plan: %w", err)
+	}
+	var p Plan
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		return nil, fmt.Errorf("decode plan: %w", err)
+	}
+	p.SessionID = sessionID
+	p.UpdatedAt = time.UnixMilli(updatedMS)
+	return &p, nil
+}
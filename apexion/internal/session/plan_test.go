@@ -0,0 +1,79 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSQLitePlanStore_RoundTrip(t *testing.T) {
+	ps, err := NewSQLitePlanStore(openTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err := ps.LoadPlan("sess-1"); err != nil || p != nil {
+		t.Fatalf("expected no plan, got %+v, %v", p, err)
+	}
+
+	plan := &Plan{
+		SessionID: "sess-1",
+		Summary:   "add a flag",
+		Steps: []PlanStep{
+			{Description: "parse the flag", Files: []string{"cmd/root.go"}, Status: PlanDone},
+			{Description: "use it", Verify: "go build ./...", Status: PlanPending},
+		},
+		Verification: []PlanCheck{{Run: "go test ./...", Status: PlanPending}},
+	}
+	if err := ps.SavePlan(plan); err != nil {
+		t.Fatal(err)
+	}
+	plan.Steps[1].Status = PlanFailed
+	plan.Steps[1].Note = "build failed"
+	if err := ps.SavePlan(plan); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ps.LoadPlan("sess-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SessionID != "sess-1" || got.Summary != "add a flag" || len(got.Steps) != 2 ||
+		got.Steps[0].Files[0] != "cmd/root.go" || got.Steps[1].Note != "build failed" ||
+		got.Verification[0].Run != "go test ./..." {
+		t.Errorf("plan did not round-trip: %+v", got)
+	}
+	if got.Status() != PlanFailed {
+		t.Errorf("Status = %q, want failed", got.Status())
+	}
+}
+
+func TestPlan_StatusAndValidate(t *testing.T) {
+	steps := func(statuses ...string) *Plan {
+		p := &Plan{}
+		for _, s := range statuses {
+			p.Steps = append(p.Steps, PlanStep{Description: "x", Status: s})
+		}
+		return p
+	}
+	tests := []struct {
+		plan *Plan
+		want string
+	}{
+		{steps(PlanPending, PlanPending), PlanPending},
+		{steps(PlanDone, PlanPending), PlanInProgress},
+		{steps(PlanDone, PlanSkipped), PlanDone},
+		{steps(PlanDone, PlanFailed, PlanPending), PlanFailed},
+	}
+	for _, tt := range tests {
+		if got := tt.plan.Status(); got != tt.want {
+			t.Errorf("Status(%+v) = %q, want %q", tt.plan.Steps, got, tt.want)
+		}
+	}
+
+	bad := steps("finished")
+	bad.Steps = append(bad.Steps, PlanStep{Status: PlanPending})
+	err := bad.Validate()
+	if err == nil || !strings.Contains(err.Error(), `step 1: unknown status "finished"`) ||
+		!strings.Contains(err.Error(), "step 2: description is empty") {
+		t.Errorf("unexpected validation result: %v", err)
+	}
+}
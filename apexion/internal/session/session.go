@@ -50,15 +50,8 @@ func (s *Session) Clear() {
 	s.TokensUsed = 0
 }
 
-// EstimateTokens returns a rough token estimate (total chars / 4).
-func (s *Session) EstimateTokens() int {
-	total := 0
-	for _, msg := range s.Messages {
-		for _, c := range msg.Content {
-			total += len(c.Text)
-			total += len(c.ToolResult)
-			total += len(c.ToolInput)
-		}
-	}
-	return total / 4
+// EstimateTokens returns the token count of the message history as seen by
+// tc (the default tokenizer if nil).
+func (s *Session) EstimateTokens(tc TokenCounter) int {
+	return estimateMessagesTokens(s.Messages, tc)
 }
@@ -0,0 +1,90 @@
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Spend is an amount of provider usage.
+type Spend struct {
+	Cost   float64 // dollars
+	Tokens int     // prompt + output tokens
+}
+
+// Add returns the sum of s and o.
+func (s Spend) Add(o Spend) Spend {
+	return Spend{Cost: s.Cost + o.Cost, Tokens: s.Tokens + o.Tokens}
+}
+
+// SpendStore persists provider spend so budgets can cover resumed sessions
+// and a calendar day across sessions.
+type SpendStore interface {
+	// Record stores the spend of one provider call.
+	Record(sessionID, model string, spend Spend) error
+	// Total sums the spend recorded for sessionID ("" = all sessions) at or
+	// after since (zero = all time).
+	Total(sessionID string, since time.Time) (Spend, error)
+}
+
+// NullSpendStore is a no-op implementation.
+type NullSpendStore struct{}
+
+func (NullSpendStore) Record(string, string, Spend) error     { return nil }
+func (NullSpendStore) Total(string, time.Time) (Spend, error) { return Spend{}, nil }
+
+const createSpendTableSQL = `
+CREATE TABLE IF NOT EXISTS spend (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id TEXT NOT NULL,
+    model      TEXT DEFAULT '',
+    cost       REAL NOT NULL DEFAULT 0,
+    tokens     INTEGER NOT NULL DEFAULT 0,
+    created_ms INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_spend_created_ms ON spend(created_ms);
+CREATE INDEX IF NOT EXISTS idx_spend_session_id ON spend(session_id);
+`
+
+// SQLiteSpendStore implements SpendStore backed by SQLite.
+type SQLiteSpendStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteSpendStore creates a spend store using an existing SQLite DB connection.
+// The spend table is created if it doesn't exist.
+func NewSQLiteSpendStore(db *sql.DB) (*SQLiteSpendStore, error) {
+	if _, err := db.Exec(createSpendTableSQL); err != nil {
+		return nil, fmt.Errorf("create spend table: %w", err)
+	}
+	return &SQLiteSpendStore{db: db}, nil
+}
+
+func (s *SQLiteSpendStore) Record(sessionID, model string, spend Spend) error {
+	_, err := s.db.Exec(`
+		INSERT INTO spend (session_id, model, cost, tokens, created_ms)
+		VALUES (?, ?, ?, ?, ?)`,
+		sessionID, model, spend.Cost, spend.Tokens, time.Now().UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("insert spend: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteSpendStore) Total(sessionID string, since time.Time) (Spend, error) {
+	var sinceMS int64
+	if !since.IsZero() {
+		sinceMS = since.UnixMilli()
+	}
+	var total Spend
+	err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(cost), 0), COALESCE(SUM(tokens), 0) FROM spend
+		WHERE (? = '' OR session_id = ?) AND created_ms >= ?`,
+		sessionID, sessionID, sinceMS,
+	).Scan(&total.Cost, &total.Tokens)
+	if err != nil {
+		return Spend{}, fmt.Errorf("sum spend: %w", err)
+	}
+	return total, nil
+}
@@ -0,0 +1,44 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSQLiteSpendStore_Total(t *testing.T) {
+	db := openTestDB(t)
+	ss, err := NewSQLiteSpendStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Yesterday's spend only counts toward all-time and session totals.
+	if _, err := db.Exec(`INSERT INTO spend (session_id, model, cost, tokens, created_ms) VALUES (?, ?, ?, ?, ?)`,
+		"sess-1", "gpt-4o", 1.0, 1000, time.Now().Add(-24*time.Hour).UnixMilli()); err != nil {
+		t.Fatal(err)
+	}
+	ss.Record("sess-1", "gpt-4o", Spend{Cost: 0.25, Tokens: 300})
+	ss.Record("sess-2", "gpt-4o", Spend{Cost: 0.5, Tokens: 700})
+
+	today := time.Now().Add(-time.Hour)
+	tests := []struct {
+		session string
+		since   time.Time
+		want    Spend
+	}{
+		{"sess-1", time.Time{}, Spend{Cost: 1.25, Tokens: 1300}},
+		{"sess-1", today, Spend{Cost: 0.25, Tokens: 300}},
+		{"", today, Spend{Cost: 0.75, Tokens: 1000}},
+		{"", time.Time{}, Spend{Cost: 1.75, Tokens: 2000}},
+		{"sess-3", time.Time{}, Spend{}},
+	}
+	for _, tt := range tests {
+		got, err := ss.Total(tt.session, tt.since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Total(%q, %v) = %+v, want %+v", tt.session, tt.since, got, tt.want)
+		}
+	}
+}
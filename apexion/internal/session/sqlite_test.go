@@ -180,3 +180,33 @@ func TestSaveUpdatesExisting(t *testing.T) {
 		t.Errorf("List messages = %d, want 2", infos[0].Messages)
 	}
 }
+
+func TestSaveAndLoad_ReasoningItems(t *testing.T) {
+	store := newTestStore(t)
+
+	reasoning := provider.Content{
+		Type:        provider.ContentTypeReasoning,
+		ReasoningID: "rs_1",
+		Text:        "Need the file first.",
+		Signature:   "enc-abc",
+	}
+	s := &Session{
+		ID:        "reasoning",
+		CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Messages: []provider.Message{
+			{Role: provider.RoleUser, Content: []provider.Content{{Type: provider.ContentTypeText, Text: "fix main.go"}}},
+			{Role: provider.RoleAssistant, Content: []provider.Content{reasoning, {Type: provider.ContentTypeText, Text: "Reading it."}}},
+		},
+	}
+	if err := store.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := store.Load("reasoning")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := loaded.Messages[1].Content[0]; got.Type != reasoning.Type || got.ReasoningID != reasoning.ReasoningID ||
+		got.Text != reasoning.Text || got.Signature != reasoning.Signature {
+		t.Errorf("reasoning item not restored: %+v", got)
+	}
+}
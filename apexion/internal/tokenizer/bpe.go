@@ -0,0 +1,81 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// maxMergePiece bounds the length of a piece handed to the quadratic merge
+// loop. Longer pieces (minified code, base64 blobs) are merged in chunks,
+// which can only over-count slightly at the chunk seams.
+const maxMergePiece = 512
+
+// parseRanks reads a .tiktoken vocabulary: one "<base64 token> <rank>" per line.
+func parseRanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int, 200000)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		tok, rank, ok := bytes.Cut(line, []byte(" "))
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"<token> <rank>\"", n)
+		}
+		raw, err := base64.StdEncoding.DecodeString(string(tok))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		id, err := strconv.Atoi(string(rank))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		ranks[string(raw)] = id
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranks) < 256 {
+		return nil, fmt.Errorf("vocabulary has %d tokens, expected at least the 256 single bytes", len(ranks))
+	}
+	return ranks, nil
+}
+
+// bpeCount returns how many tokens byte-pair encoding splits piece into.
+func bpeCount(ranks map[string]int, piece string) int {
+	if len(piece) == 0 {
+		return 0
+	}
+	if _, ok := ranks[piece]; ok {
+		return 1
+	}
+	if len(piece) > maxMergePiece {
+		return bpeCount(ranks, piece[:maxMergePiece]) + bpeCount(ranks, piece[maxMergePiece:])
+	}
+
+	// parts holds the boundaries of the current tokens. Repeatedly merge the
+	// adjacent pair whose concatenation has the lowest rank, as tiktoken does.
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+	for len(parts) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i+2 < len(parts); i++ {
+			if rank, ok := ranks[piece[parts[i]:parts[i+2]]]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+	return len(parts) - 1
+}
@@ -0,0 +1,52 @@
+package tokenizer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// estimatePiece approximates the BPE token count of one pre-token when the
+// vocabulary is not installed. The rates follow how cl100k_base and
+// o200k_base typically split English prose, source code and CJK text; unlike
+// bytes/4 they do not count a Han character (3 bytes) as 0.75 tokens or a
+// run of punctuation as a fraction of one.
+func estimatePiece(piece string, cjkRate float64) float64 {
+	var ascii, other, cjk, symbols, otherSymbols int
+	for _, r := range piece {
+		switch {
+		case unicode.IsSpace(r):
+		case isCJK(r):
+			cjk++
+		case unicode.IsLetter(r) || unicode.Is(unicode.M, r):
+			if r < utf8.RuneSelf {
+				ascii++
+			} else {
+				other++
+			}
+		case unicode.IsNumber(r):
+			ascii++
+		case r < utf8.RuneSelf:
+			symbols++
+		default:
+			otherSymbols++
+		}
+	}
+	tokens := float64(cjk)*cjkRate +
+		float64(ceilDiv(ascii, 6)) + // common words and identifier chunks are single tokens
+		float64(ceilDiv(other, 2)) + // accented Latin, Cyrillic, Greek, ...
+		float64(ceilDiv(symbols, 2)) + // "()", "{\n", "//" and friends merge in pairs
+		float64(otherSymbols) // CJK punctuation, emoji, box drawing
+	if tokens < 1 {
+		return 1 // whitespace-only pieces are one token
+	}
+	return tokens
+}
+
+// isCJK reports whether r is a Han, kana or Hangul character.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
@@ -0,0 +1,72 @@
+//go:build ignore
+
+// gen_vocab downloads the cl100k_base and o200k_base vocabularies and writes
+// them gzipped to vocab/, where they are embedded into the binary.
+//
+//	go generate ./internal/tokenizer
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const baseURL = "https://openaipublic.blob.core.windows.net/encodings/"
+
+// minRanks guards against saving an error page instead of a vocabulary.
+const minRanks = 100000
+
+func main() {
+	for _, name := range []string{"cl100k_base", "o200k_base"} {
+		if err := fetch(name); err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+func fetch(name string) error {
+	resp, err := http.Get(baseURL + name + ".tiktoken")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", resp.Request.URL, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	lines := 0
+	for sc := bufio.NewScanner(bytes.NewReader(data)); sc.Scan(); {
+		lines++
+	}
+	if lines < minRanks {
+		return fmt.Errorf("only %d ranks, want at least %d", lines, minRanks)
+	}
+
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	path := filepath.Join("vocab", name+".tiktoken.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d ranks, %d bytes\n", path, lines, buf.Len())
+	return nil
+}
@@ -0,0 +1,177 @@
+package tokenizer
+
+import "unicode"
+
+// splitPieces breaks text into the pre-tokens that BPE runs on, following
+// the cl100k_base and o200k_base split patterns:
+//
+//	cl100k: (?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	        ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//	o200k:  [^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|...)?|
+//	        [^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|...)?|
+//	        \p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// Go's regexp has no lookahead, so the patterns are matched by hand.
+func splitPieces(text string, casedWords bool, fn func(piece string)) {
+	rs := []rune(text)
+	// Byte offsets of each rune, plus the end.
+	offs := make([]int, len(rs)+1)
+	o := 0
+	for i, r := range rs {
+		offs[i] = o
+		o += len(string(r))
+	}
+	offs[len(rs)] = o
+
+	for i := 0; i < len(rs); {
+		n := matchPiece(rs, i, casedWords)
+		if n <= 0 {
+			n = 1
+		}
+		fn(text[offs[i]:offs[i+n]])
+		i += n
+	}
+}
+
+// matchPiece returns the length in runes of the piece starting at rs[i].
+func matchPiece(rs []rune, i int, casedWords bool) int {
+	r := rs[i]
+
+	if !casedWords {
+		if n := matchContraction(rs, i); n > 0 {
+			return n
+		}
+		// [^\r\n\p{L}\p{N}]?\p{L}+
+		j := i
+		if isPrefix(r) && i+1 < len(rs) && unicode.IsLetter(rs[i+1]) {
+			j++
+		}
+		if j < len(rs) && unicode.IsLetter(rs[j]) {
+			for j < len(rs) && unicode.IsLetter(rs[j]) {
+				j++
+			}
+			return j - i
+		}
+	} else if n := matchCasedWord(rs, i); n > 0 {
+		return n
+	}
+
+	// \p{N}{1,3}
+	if unicode.IsNumber(r) {
+		j := i
+		for j < len(rs) && j-i < 3 && unicode.IsNumber(rs[j]) {
+			j++
+		}
+		return j - i
+	}
+
+	// ` ?[^\s\p{L}\p{N}]+[\r\n]*` (o200k also absorbs trailing slashes)
+	j := i
+	if r == ' ' {
+		j++
+	}
+	if j < len(rs) && isSymbol(rs[j]) {
+		for j < len(rs) && isSymbol(rs[j]) {
+			j++
+		}
+		for j < len(rs) && (isNewline(rs[j]) || (casedWords && rs[j] == '/')) {
+			j++
+		}
+		return j - i
+	}
+
+	if !unicode.IsSpace(r) {
+		return 1
+	}
+	end := i
+	for end < len(rs) && unicode.IsSpace(rs[end]) {
+		end++
+	}
+	// \s*[\r\n]+ — up to and including the last newline of the run.
+	for k := end - 1; k >= i; k-- {
+		if isNewline(rs[k]) {
+			return k + 1 - i
+		}
+	}
+	// \s+(?!\S) — leave the last space to prefix the following word.
+	if end == len(rs) || end-i == 1 {
+		return end - i
+	}
+	return end - 1 - i
+}
+
+// matchContraction matches (?i:'s|'t|'re|'ve|'m|'ll|'d) at rs[i].
+func matchContraction(rs []rune, i int) int {
+	if rs[i] != '\'' || i+1 >= len(rs) {
+		return 0
+	}
+	a := unicode.ToLower(rs[i+1])
+	switch a {
+	case 's', 't', 'm', 'd':
+		return 2
+	}
+	if i+2 < len(rs) {
+		b := unicode.ToLower(rs[i+2])
+		if (a == 'r' && b == 'e') || (a == 'v' && b == 'e') || (a == 'l' && b == 'l') {
+			return 3
+		}
+	}
+	return 0
+}
+
+// matchCasedWord matches the two o200k word alternatives: an optional
+// prefix, then upper* lower+ or upper+ lower*, then an optional contraction.
+func matchCasedWord(rs []rune, i int) int {
+	start := i
+	if isPrefix(rs[i]) && i+1 < len(rs) && unicode.IsLetter(rs[i+1]) {
+		start++
+	}
+	if start >= len(rs) || !unicode.IsLetter(rs[start]) && !unicode.Is(unicode.M, rs[start]) {
+		return 0
+	}
+
+	j := start
+	for j < len(rs) && isUpperish(rs[j]) {
+		j++
+	}
+	upperEnd := j
+	for j < len(rs) && isLowerish(rs[j]) {
+		j++
+	}
+	switch {
+	case j > upperEnd:
+		// upper* lower+
+	case upperEnd > start && isLowerish(rs[upperEnd-1]):
+		// upper* backtracks so that lower+ takes the trailing Lm/Lo/M rune.
+	case upperEnd > start:
+		// upper+ lower*
+	default:
+		return 0
+	}
+	if j < len(rs) {
+		j += matchContraction(rs, j)
+	}
+	return j - i
+}
+
+func isNewline(r rune) bool { return r == '\r' || r == '\n' }
+
+// isPrefix reports whether r may lead a word: [^\r\n\p{L}\p{N}].
+func isPrefix(r rune) bool {
+	return !isNewline(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// isSymbol matches [^\s\p{L}\p{N}].
+func isSymbol(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// isUpperish matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}].
+func isUpperish(r rune) bool {
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// isLowerish matches [\p{Ll}\p{Lm}\p{Lo}\p{M}].
+func isLowerish(r rune) bool {
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
@@ -0,0 +1,267 @@
+// Package tokenizer counts tokens the way the model will, so context budgets
+// do not drift on CJK text and code-heavy tool output.
+//
+// Counting uses the cl100k_base and o200k_base byte-pair encodings. The
+// vocabularies, in the format published by OpenAI, are embedded gzipped
+// from vocab/ (see gen_vocab.go):
+//
+//	https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken
+//	https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken
+//
+// A <VocabDir>/<encoding>.tiktoken file overrides the embedded copy. Without
+// either, the same pre-tokenization runs and each piece is estimated from its
+// script (Latin, CJK, symbols) instead.
+// Providers with their own tokenizers are mapped to the closest encoding and
+// scaled by a per-provider calibration factor.
+package tokenizer
+
+//go:generate go run gen_vocab.go
+
+import (
+	"compress/gzip"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// maxCachedPieces bounds the per-encoding piece cache.
+const maxCachedPieces = 1 << 16
+
+// Encoding is a BPE vocabulary plus its pre-tokenization rules.
+type Encoding struct {
+	name       string
+	casedWords bool    // o200k splits words on case changes
+	cjkRate    float64 // estimated tokens per CJK character without a vocabulary
+
+	once   sync.Once
+	ranks  map[string]int // nil when no vocabulary is available
+	source string         // where ranks came from
+	err    error
+
+	mu    sync.Mutex
+	cache map[string]int
+}
+
+var (
+	cl100k = &Encoding{name: "cl100k_base", cjkRate: 1.25}
+	o200k  = &Encoding{name: "o200k_base", casedWords: true, cjkRate: 0.85}
+)
+
+//go:embed vocab
+var vocabFS embed.FS
+
+// bundled holds the embedded vocabularies as vocab/<encoding>.tiktoken.gz.
+// Tests replace it.
+var bundled fs.FS = vocabFS
+
+// VocabDir returns the directory whose vocabularies override the embedded
+// ones: $APEXION_TOKENIZER_DIR, or ~/.config/apexion/tokenizers.
+func VocabDir() string {
+	if dir := os.Getenv("APEXION_TOKENIZER_DIR"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "apexion", "tokenizers")
+}
+
+// Name returns the encoding name, e.g. "cl100k_base".
+func (e *Encoding) Name() string { return e.name }
+
+// Loaded reports whether the vocabulary is available, loading it on first
+// use. The error explains why counts fall back to estimates.
+func (e *Encoding) Loaded() (bool, error) {
+	e.load()
+	return e.ranks != nil, e.err
+}
+
+// Source returns the override file the vocabulary was read from, or
+// "embedded". It is empty until the vocabulary has loaded.
+func (e *Encoding) Source() string {
+	e.load()
+	return e.source
+}
+
+func (e *Encoding) load() {
+	e.once.Do(func() {
+		r, source, err := e.open()
+		if err != nil {
+			e.err = err
+			return
+		}
+		defer r.Close()
+		ranks, err := parseRanks(r)
+		if err != nil {
+			e.err = fmt.Errorf("%s: %w", source, err)
+			return
+		}
+		e.ranks = ranks
+		e.source = source
+		e.cache = make(map[string]int)
+	})
+}
+
+// open returns the override file if there is one, else the embedded copy.
+func (e *Encoding) open() (io.ReadCloser, string, error) {
+	if dir := VocabDir(); dir != "" {
+		path := filepath.Join(dir, e.name+".tiktoken")
+		f, err := os.Open(path)
+		if err == nil {
+			return f, path, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, "", err
+		}
+	}
+	f, err := bundled.Open("vocab/" + e.name + ".tiktoken.gz")
+	if err != nil {
+		return nil, "", err
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, "", fmt.Errorf("embedded %s: %w", e.name, err)
+	}
+	return gzipFile{zr, f}, "embedded", nil
+}
+
+// gzipFile closes both the decompressor and the file under it.
+type gzipFile struct {
+	*gzip.Reader
+	f fs.File
+}
+
+func (g gzipFile) Close() error {
+	g.Reader.Close()
+	return g.f.Close()
+}
+
+// count returns the (possibly estimated) token count of text.
+func (e *Encoding) count(text string) float64 {
+	e.load()
+	total := 0.0
+	splitPieces(text, e.casedWords, func(piece string) {
+		if e.ranks == nil {
+			total += estimatePiece(piece, e.cjkRate)
+			return
+		}
+		total += float64(e.countPiece(piece))
+	})
+	return total
+}
+
+func (e *Encoding) countPiece(piece string) int {
+	e.mu.Lock()
+	n, ok := e.cache[piece]
+	e.mu.Unlock()
+	if ok {
+		return n
+	}
+	n = bpeCount(e.ranks, piece)
+	e.mu.Lock()
+	if len(e.cache) >= maxCachedPieces {
+		e.cache = make(map[string]int)
+	}
+	e.cache[piece] = n
+	e.mu.Unlock()
+	return n
+}
+
+// Counter counts tokens for one provider/model. It implements
+// session.TokenCounter.
+type Counter struct {
+	enc   *Encoding
+	scale float64
+}
+
+// CountTokens returns the number of tokens text is expected to occupy.
+func (c *Counter) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return int(math.Ceil(c.enc.count(text) * c.scale))
+}
+
+// Encoding returns the reference encoding.
+func (c *Counter) Encoding() *Encoding { return c.enc }
+
+// Scale returns the calibration factor applied on top of the encoding.
+func (c *Counter) Scale() float64 { return c.scale }
+
+// calibration maps a model family to its reference encoding and the rough
+// ratio of its native tokenizer's counts to that encoding's.
+type calibration struct {
+	prefix string
+	enc    *Encoding
+	scale  float64
+}
+
+// modelCalibrations is matched in order against the lower-cased model name
+// (without any "vendor/" prefix), so gateways serving e.g. Claude through an
+// OpenAI-compatible endpoint still get the right tokenizer.
+var modelCalibrations = []calibration{
+	{"gpt-4o", o200k, 1.0},
+	{"gpt-4.1", o200k, 1.0},
+	{"gpt-4.5", o200k, 1.0},
+	{"gpt-5", o200k, 1.0},
+	{"chatgpt", o200k, 1.0},
+	{"o1", o200k, 1.0},
+	{"o3", o200k, 1.0},
+	{"o4", o200k, 1.0},
+	{"gpt-4", cl100k, 1.0},
+	{"gpt-3.5", cl100k, 1.0},
+	{"claude", cl100k, 1.10},
+	{"gemini", o200k, 1.05},
+	{"gemma", o200k, 1.05},
+	// Chinese model vocabularies are closer to o200k on CJK text.
+	{"deepseek", o200k, 1.0},
+	{"qwen", o200k, 1.0},
+	{"kimi", o200k, 1.0},
+	{"moonshot", o200k, 1.0},
+	{"glm", o200k, 1.0},
+	{"doubao", o200k, 1.0},
+	{"minimax", o200k, 1.0},
+	{"llama", cl100k, 1.0},
+}
+
+// providerCalibrations is the fallback when the model name is not recognized.
+var providerCalibrations = map[string]calibration{
+	"openai":    {enc: o200k, scale: 1.0},
+	"anthropic": {enc: cl100k, scale: 1.10},
+	"gemini":    {enc: o200k, scale: 1.05},
+	"deepseek":  {enc: o200k, scale: 1.0},
+	"qwen":      {enc: o200k, scale: 1.0},
+	"kimi":      {enc: o200k, scale: 1.0},
+	"glm":       {enc: o200k, scale: 1.0},
+	"doubao":    {enc: o200k, scale: 1.0},
+	"minimax":   {enc: o200k, scale: 1.0},
+}
+
+// ForModel returns the counter for a provider/model pair. Unknown
+// combinations use cl100k_base unscaled.
+func ForModel(providerName, model string) *Counter {
+	m := strings.ToLower(model)
+	if i := strings.LastIndex(m, "/"); i >= 0 {
+		m = m[i+1:]
+	}
+	if m != "" {
+		for _, c := range modelCalibrations {
+			if strings.HasPrefix(m, c.prefix) {
+				return &Counter{enc: c.enc, scale: c.scale}
+			}
+		}
+	}
+	if c, ok := providerCalibrations[strings.ToLower(providerName)]; ok {
+		return &Counter{enc: c.enc, scale: c.scale}
+	}
+	return &Counter{enc: cl100k, scale: 1.0}
+}
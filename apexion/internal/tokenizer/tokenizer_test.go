@@ -0,0 +1,210 @@
+package tokenizer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func pieces(text string, cased bool) []string {
+	var out []string
+	splitPieces(text, cased, func(p string) { out = append(out, p) })
+	return out
+}
+
+func TestSplitPieces(t *testing.T) {
+	tests := []struct {
+		text  string
+		cased bool
+		want  []string
+	}{
+		{"Hello world", false, []string{"Hello", " world"}},
+		{"it's 12345 ok", false, []string{"it", "'s", " ", "123", "45", " ok"}},
+		{"  foo", false, []string{" ", " foo"}},
+		{"a\n\n  b", false, []string{"a", "\n\n", " ", " b"}},
+		{"x := f(y);\n", false, []string{"x", " :=", " f", "(y", ");\n"}},
+		{"trailing   ", false, []string{"trailing", "   "}},
+		{"你好，世界", false, []string{"你好", "，世界"}},
+		{"HTTPServer isOK", true, []string{"HTTPServer", " is", "OK"}},
+		{"DON'T stop", true, []string{"DON'T", " stop"}},
+		{"a/b//\n", true, []string{"a", "/b", "//\n"}},
+	}
+	for _, tt := range tests {
+		got := pieces(tt.text, tt.cased)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("splitPieces(%q, cased=%v) = %q, want %q", tt.text, tt.cased, got, tt.want)
+		}
+		if strings.Join(got, "") != tt.text {
+			t.Errorf("pieces of %q do not reassemble", tt.text)
+		}
+	}
+}
+
+// testVocab builds a tiny vocabulary: all single bytes, then the merges in order.
+func testVocab(merges ...string) string {
+	var b strings.Builder
+	rank := 0
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), rank)
+		rank++
+	}
+	for _, m := range merges {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(m)), rank)
+		rank++
+	}
+	return b.String()
+}
+
+func TestBPECount(t *testing.T) {
+	ranks, err := parseRanks(strings.NewReader(testVocab("he", "ll", "hell", "hello", " w", "or")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		piece string
+		want  int
+	}{
+		{"", 0},
+		{"hello", 1},
+		{"hellohello", 2},
+		{" world", 4}, // " w" "or" "l" "d"
+		{"xyz", 3},
+		{strings.Repeat("ab", maxMergePiece), 2 * maxMergePiece},
+	}
+	for _, tt := range tests {
+		if got := bpeCount(ranks, tt.piece); got != tt.want {
+			t.Errorf("bpeCount(%q) = %d, want %d", tt.piece, got, tt.want)
+		}
+	}
+
+	if _, err := parseRanks(strings.NewReader("aGk= 0\n")); err == nil {
+		t.Error("expected error for a vocabulary without the byte tokens")
+	}
+	if _, err := parseRanks(strings.NewReader("not base64!\n")); err == nil {
+		t.Error("expected error for a malformed line")
+	}
+}
+
+func TestEncoding_LoadsVocabulary(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("APEXION_TOKENIZER_DIR", dir)
+	if err := os.WriteFile(filepath.Join(dir, "test_base.tiktoken"), []byte(testVocab("he", "ll", "hell", "hello", " w", "or")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	enc := &Encoding{name: "test_base", cjkRate: 1}
+	if ok, err := enc.Loaded(); !ok {
+		t.Fatalf("vocabulary not loaded: %v", err)
+	}
+	c := &Counter{enc: enc, scale: 1}
+	if got := c.CountTokens("hello world"); got != 5 {
+		t.Errorf("CountTokens = %d, want 5", got)
+	}
+	c.scale = 1.5
+	if got := c.CountTokens("hello world"); got != 8 {
+		t.Errorf("scaled CountTokens = %d, want 8", got)
+	}
+
+	missing := &Encoding{name: "missing_base", cjkRate: 1}
+	if ok, err := missing.Loaded(); ok || err == nil {
+		t.Errorf("expected missing vocabulary to report an error, got %v %v", ok, err)
+	}
+}
+
+// setBundled replaces the embedded vocabularies for the rest of the test.
+func setBundled(t *testing.T, files map[string]string) {
+	fsys := fstest.MapFS{}
+	for name, vocab := range files {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(vocab))
+		zw.Close()
+		fsys["vocab/"+name+".tiktoken.gz"] = &fstest.MapFile{Data: buf.Bytes()}
+	}
+	old := bundled
+	bundled = fsys
+	t.Cleanup(func() { bundled = old })
+}
+
+func TestEncoding_LoadsEmbeddedVocabulary(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("APEXION_TOKENIZER_DIR", dir)
+	setBundled(t, map[string]string{
+		"test_base":  testVocab("he", "ll", "hell", "hello", " w", "or"),
+		"other_base": testVocab("he", "ll", "hell", "hello", " w", "or"),
+	})
+
+	enc := &Encoding{name: "test_base", cjkRate: 1}
+	if ok, err := enc.Loaded(); !ok {
+		t.Fatalf("embedded vocabulary not loaded: %v", err)
+	}
+	if got := enc.Source(); got != "embedded" {
+		t.Errorf("Source = %q, want embedded", got)
+	}
+	if got := (&Counter{enc: enc, scale: 1}).CountTokens("hello world"); got != 5 {
+		t.Errorf("CountTokens = %d, want 5", got)
+	}
+
+	// A file in the vocabulary directory wins over the embedded copy.
+	path := filepath.Join(dir, "other_base.tiktoken")
+	if err := os.WriteFile(path, []byte(testVocab()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	override := &Encoding{name: "other_base", cjkRate: 1}
+	if got := override.Source(); got != path {
+		t.Errorf("Source = %q, want %q", got, path)
+	}
+	if got := (&Counter{enc: override, scale: 1}).CountTokens("hello"); got != 5 {
+		t.Errorf("override CountTokens = %d, want 5 (bytes only)", got)
+	}
+}
+
+func TestEstimate_BeatsCharsOverFour(t *testing.T) {
+	t.Setenv("APEXION_TOKENIZER_DIR", t.TempDir()) // no vocabularies
+	setBundled(t, nil)
+	c := &Counter{enc: &Encoding{name: "cl100k_base", cjkRate: 1.25}, scale: 1}
+
+	// cl100k spends at least one token per common Han character; chars/4
+	// credits each with 0.75.
+	zh := "请帮我检查这个函数为什么会返回空指针"
+	if got, chars := c.CountTokens(zh), len([]rune(zh)); got < chars || got <= len(zh)/4 {
+		t.Errorf("CJK estimate = %d, want at least %d (chars/4 = %d)", got, chars, len(zh)/4)
+	}
+	// One token per word in cl100k.
+	if got := c.CountTokens("The quick brown fox jumps over the lazy dog"); got < 8 || got > 11 {
+		t.Errorf("English estimate = %d, want about 9", got)
+	}
+	if got := c.CountTokens(""); got != 0 {
+		t.Errorf("empty text = %d tokens", got)
+	}
+}
+
+func TestForModel(t *testing.T) {
+	tests := []struct {
+		provider, model string
+		enc             string
+		scale           float64
+	}{
+		{"openai", "gpt-4o-mini", "o200k_base", 1.0},
+		{"openai", "gpt-4-turbo", "cl100k_base", 1.0},
+		{"openai", "o3-mini", "o200k_base", 1.0},
+		{"anthropic", "claude-sonnet-4-20250514", "cl100k_base", 1.10},
+		{"openai", "anthropic/claude-3.5-sonnet", "cl100k_base", 1.10},
+		{"qwen", "qwen3-max", "o200k_base", 1.0},
+		{"deepseek", "", "o200k_base", 1.0},
+		{"ollama", "mystery-model", "cl100k_base", 1.0},
+	}
+	for _, tt := range tests {
+		c := ForModel(tt.provider, tt.model)
+		if c.Encoding().Name() != tt.enc || c.Scale() != tt.scale {
+			t.Errorf("ForModel(%q, %q) = %s x%.2f, want %s x%.2f",
+				tt.provider, tt.model, c.Encoding().Name(), c.Scale(), tt.enc, tt.scale)
+		}
+	}
+}
@@ -46,6 +46,7 @@ type Executor struct {
 	autoCommitter  *AutoCommitter // auto-commit after file edits (nil = disabled)
 	linter         *Linter        // lint after file edits (nil = disabled)
 	testRunner     *TestRunner    // test after file edits (nil = disabled)
+	readSlots      chan struct{}  // limits concurrently running read-only tools
 }
 
 // NewExecutor creates a tool executor.
@@ -55,6 +56,7 @@ func NewExecutor(registry *Registry, policy permission.Policy) *Executor {
 		policy:         policy,
 		defaultTimeout: 300 * time.Second,
 		tracker:        NewFileTracker(),
+		readSlots:      make(chan struct{}, DefaultMaxParallelReads),
 	}
 }
 
@@ -180,6 +182,17 @@ func (e *Executor) Execute(ctx context.Context, name string, params json.RawMess
 		}
 	}
 
+	if tool.IsReadOnly() && !unslottedReads[name] {
+		if !e.acquireRead(ctx) {
+			return ToolResult{
+				Content:       "[User cancelled — tool was not executed]",
+				IsError:       false,
+				UserCancelled: true,
+			}
+		}
+		defer e.releaseRead()
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, e.defaultTimeout)
 	defer cancel()
 
@@ -59,14 +59,7 @@ type HookManager struct {
 func LoadHooks(cwd string) *HookManager {
 	hm := &HookManager{}
 
-	paths := []string{
-		filepath.Join(cwd, ".apexion", "hooks.yaml"),
-	}
-	if home, err := os.UserHomeDir(); err == nil {
-		paths = append(paths, filepath.Join(home, ".config", "apexion", "hooks.yaml"))
-	}
-
-	for _, path := range paths {
+	for _, path := range hookPaths(cwd) {
 		data, err := os.ReadFile(path)
 		if err != nil {
 			continue
@@ -124,6 +117,61 @@ func LoadHooks(cwd string) *HookManager {
 	return hm
 }
 
+// hookPaths returns the hook files LoadHooks reads, project first.
+func hookPaths(cwd string) []string {
+	paths := []string{
+		filepath.Join(cwd, ".apexion", "hooks.yaml"),
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".config", "apexion", "hooks.yaml"))
+	}
+	return paths
+}
+
+// CheckHooks validates the hook files LoadHooks would read and returns the
+// number of hooks found plus every problem LoadHooks would silently skip:
+// unparsable YAML, invalid matcher regexes and empty commands.
+func CheckHooks(cwd string) (int, []error) {
+	var count int
+	var errs []error
+	for _, path := range hookPaths(cwd) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var cfg HooksConfig
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		groups := []struct {
+			event   HookEvent
+			entries []HookEntry
+		}{
+			{HookPreTool, cfg.Hooks.PreTool},
+			{HookPostTool, cfg.Hooks.PostTool},
+			{HookSessionStart, cfg.Hooks.SessionStart},
+			{HookSessionStop, cfg.Hooks.SessionStop},
+			{HookNotification, cfg.Hooks.Notification},
+		}
+		for _, g := range groups {
+			for i, h := range g.entries {
+				count++
+				if strings.TrimSpace(h.Command) == "" {
+					errs = append(errs, fmt.Errorf("%s: %s[%d]: empty command", path, g.event, i))
+				}
+				if g.event != HookPreTool && g.event != HookPostTool {
+					continue
+				}
+				if _, err := regexp.Compile(h.Matcher); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %s[%d]: invalid matcher %q: %v", path, g.event, i, h.Matcher, err))
+				}
+			}
+		}
+	}
+	return count, errs
+}
+
 // HasHooks returns true if any hooks are configured.
 func (hm *HookManager) HasHooks() bool {
 	return len(hm.preHooks) > 0 || len(hm.postHooks) > 0 ||
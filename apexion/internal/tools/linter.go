@@ -3,8 +3,11 @@ package tools
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -74,3 +77,39 @@ func (l *Linter) Run(ctx context.Context, filePath string) (output string, hasEr
 
 	return "", false, nil
 }
+
+// placeholderRe matches template placeholders in lint and test commands.
+var placeholderRe = regexp.MustCompile(`\{\{[^}]*\}\}`)
+
+// CheckCommandTemplates validates lint or test commands keyed by file
+// extension: keys must look like ".go", the only placeholder is {{.file}},
+// and the program the command starts with must be on PATH.
+func CheckCommandTemplates(commands map[string]string) []error {
+	exts := make([]string, 0, len(commands))
+	for ext := range commands {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+
+	var errs []error
+	for _, ext := range exts {
+		cmd := commands[ext]
+		if !strings.HasPrefix(ext, ".") {
+			errs = append(errs, fmt.Errorf("%q: key must be a file extension such as \".go\"", ext))
+		}
+		for _, ph := range placeholderRe.FindAllString(cmd, -1) {
+			if ph != "{{.file}}" {
+				errs = append(errs, fmt.Errorf("%s: unknown placeholder %s (only {{.file}} is replaced)", ext, ph))
+			}
+		}
+		fields := strings.Fields(cmd)
+		if len(fields) == 0 {
+			errs = append(errs, fmt.Errorf("%s: empty command", ext))
+			continue
+		}
+		if _, err := exec.LookPath(fields[0]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s not found on PATH", ext, fields[0]))
+		}
+	}
+	return errs
+}
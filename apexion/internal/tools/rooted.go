@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// rootedParams are the parameters that name a file or directory.
+var rootedParams = []string{"file_path", "path", "dir"}
+
+// RootedRegistry returns a copy of reg whose tools work in root instead of
+// the process working directory: relative file_path, path and dir
+// parameters resolve against root, empty path and dir parameters default
+// to it, absolute paths under orig map to the same place under root, and
+// bash runs there. It keeps a sub-agent inside a git worktree; it is not a
+// sandbox.
+func RootedRegistry(reg *Registry, root, orig string) *Registry {
+	r := NewRegistry()
+	for _, t := range reg.All() {
+		if bt, ok := t.(*BashTool); ok {
+			rooted := *bt
+			rooted.WorkDir = root
+			r.Register(&rooted)
+			continue
+		}
+		r.Register(&rootedTool{Tool: t, root: root, orig: orig})
+	}
+	return r
+}
+
+// rootedTool rewrites the path parameters of a tool call; see RootedRegistry.
+type rootedTool struct {
+	Tool
+	root string
+	orig string
+}
+
+func (t *rootedTool) Execute(ctx context.Context, params json.RawMessage) (ToolResult, error) {
+	var p map[string]json.RawMessage
+	if err := json.Unmarshal(params, &p); err != nil || p == nil {
+		return t.Tool.Execute(ctx, params)
+	}
+	declared := t.Tool.Parameters()
+	for _, key := range rootedParams {
+		if _, ok := declared[key]; !ok {
+			continue
+		}
+		var v string
+		if raw, ok := p[key]; ok && json.Unmarshal(raw, &v) != nil {
+			continue // not a string; let the tool report it
+		}
+		switch {
+		case v != "":
+			v = t.resolve(v)
+		case key == "file_path":
+			continue
+		default:
+			v = t.root
+		}
+		p[key], _ = json.Marshal(v)
+	}
+	rewritten, err := json.Marshal(p)
+	if err != nil {
+		return t.Tool.Execute(ctx, params)
+	}
+	return t.Tool.Execute(ctx, rewritten)
+}
+
+// resolve maps a path the model passed to its place under root.
+func (t *rootedTool) resolve(p string) string {
+	if !filepath.IsAbs(p) {
+		return filepath.Join(t.root, p)
+	}
+	rel, err := filepath.Rel(t.orig, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return p
+	}
+	return filepath.Join(t.root, rel)
+}
@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRootedRegistry(t *testing.T) {
+	orig := t.TempDir()
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(orig, "a.txt"), []byte("main tree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("worktree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(orig)
+	reg := RootedRegistry(CodeRegistry(), root, orig)
+
+	tests := []struct {
+		tool   string
+		params string
+		want   string
+	}{
+		{"read_file", `{"file_path":"a.txt"}`, "worktree"},
+		{"read_file", `{"file_path":"` + filepath.Join(orig, "a.txt") + `"}`, "worktree"},
+		{"list_dir", `{}`, "a.txt"},
+		{"bash", `{"command":"cat a.txt"}`, "worktree"},
+	}
+	for _, tt := range tests {
+		tool, _ := reg.Get(tt.tool)
+		res, err := tool.Execute(context.Background(), json.RawMessage(tt.params))
+		if err != nil || res.IsError || !strings.Contains(res.Content, tt.want) {
+			t.Errorf("%s %s = %q, %v; want %q", tt.tool, tt.params, res.Content, err, tt.want)
+		}
+	}
+
+	write, _ := reg.Get("write_file")
+	if _, err := write.Execute(context.Background(), json.RawMessage(`{"file_path":"b.txt","content":"new\n"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "b.txt")); err != nil {
+		t.Errorf("write_file did not write under the root: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(orig, "b.txt")); err == nil {
+		t.Error("write_file wrote to the process working directory")
+	}
+}
@@ -0,0 +1,190 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxParallelReads caps how many read-only tools run at once when
+// no limit is configured.
+const DefaultMaxParallelReads = 8
+
+// unslottedReads lists read-only tools that do not take a read slot: task
+// holds one for a whole sub-agent run and question while the user answers,
+// which would starve ordinary reads.
+var unslottedReads = map[string]bool{
+	"task":     true,
+	"question": true,
+}
+
+// Call is one tool call to schedule.
+type Call struct {
+	Name   string
+	Params json.RawMessage
+}
+
+// resource is something a call reads or writes: a file or directory, or a
+// named resource such as the todo list.
+type resource struct {
+	key   string // absolute path when path is true
+	path  bool
+	write bool
+}
+
+// SetMaxParallelReads sets how many read-only tools may run at once.
+// n <= 0 restores DefaultMaxParallelReads. Call before executing tools.
+func (e *Executor) SetMaxParallelReads(n int) {
+	if n <= 0 {
+		n = DefaultMaxParallelReads
+	}
+	e.readSlots = make(chan struct{}, n)
+}
+
+// acquireRead takes a read slot, or returns false if ctx ends first.
+func (e *Executor) acquireRead(ctx context.Context) bool {
+	select {
+	case e.readSlots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (e *Executor) releaseRead() {
+	<-e.readSlots
+}
+
+// Schedule calls run(i) for each call and returns when all have finished.
+// Calls run in parallel unless they conflict: a call waits for every
+// earlier call that writes something it touches, or touches something it
+// writes. Callers keep results by index, so ordering is unaffected.
+func (e *Executor) Schedule(calls []Call, run func(i int)) {
+	res := make([][]resource, len(calls))
+	done := make([]chan struct{}, len(calls))
+	for i, c := range calls {
+		res[i] = e.resources(c)
+		done[i] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	for i := range calls {
+		var deps []chan struct{}
+		for j := range i {
+			if conflicts(res[j], res[i]) {
+				deps = append(deps, done[j])
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[i])
+			for _, d := range deps {
+				<-d
+			}
+			run(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// Conflicts reports whether next conflicts with any of prior, i.e. whether
+// Schedule would make it wait for one of them.
+func (e *Executor) Conflicts(prior []Call, next Call) bool {
+	r := e.resources(next)
+	for _, c := range prior {
+		if conflicts(e.resources(c), r) {
+			return true
+		}
+	}
+	return false
+}
+
+// resources derives what a call touches from its name and params. Calls
+// to tools without a known footprint touch nothing if they are read-only,
+// and the whole working tree otherwise.
+func (e *Executor) resources(c Call) []resource {
+	var p struct {
+		FilePath string `json:"file_path"`
+		Path     string `json:"path"`
+		Mode     string `json:"mode"`
+	}
+	_ = json.Unmarshal(c.Params, &p)
+	cwd, _ := os.Getwd()
+	tree := resource{key: cwd, path: true}
+	treeWrite := resource{key: cwd, path: true, write: true}
+
+	switch c.Name {
+	case "read_file":
+		return []resource{{key: absPath(cwd, p.FilePath), path: true}}
+	case "edit_file", "write_file":
+		return []resource{{key: absPath(cwd, p.FilePath), path: true, write: true}}
+	case "glob", "grep", "list_dir", "repo_map", "symbol_nav":
+		return []resource{{key: absPath(cwd, p.Path), path: true}}
+	case "git_status", "git_diff", "git_log":
+		return []resource{tree}
+	case "bash", "git_commit", "git_push":
+		return []resource{treeWrite}
+	case "todo_read":
+		return []resource{{key: "todo"}}
+	case "todo_write":
+		return []resource{{key: "todo", write: true}}
+	case "task":
+		// Code-mode sub-agents and custom types with write tools edit
+		// files, unless they run in a worktree; the others only read.
+		if t, ok := e.registry.Get(c.Name); ok {
+			if tt, ok := t.(*TaskTool); ok && tt.writesTree(p.Mode) {
+				return []resource{treeWrite}
+			}
+		}
+		return []resource{tree}
+	}
+	if t, ok := e.registry.Get(c.Name); ok && t.IsReadOnly() {
+		return nil
+	}
+	return []resource{treeWrite}
+}
+
+// absPath resolves p against cwd; an empty p means cwd itself.
+func absPath(cwd, p string) string {
+	if p == "" {
+		return cwd
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(cwd, p)
+	}
+	return filepath.Clean(p)
+}
+
+// conflicts reports whether two calls' resources overlap with at least one
+// side writing.
+func conflicts(a, b []resource) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if (x.write || y.write) && overlaps(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// overlaps reports whether x and y are the same resource, or for paths,
+// whether one contains the other.
+func overlaps(x, y resource) bool {
+	if x.path != y.path {
+		return false
+	}
+	if x.key == y.key || !x.path {
+		return x.key == y.key
+	}
+	return within(x.key, y.key) || within(y.key, x.key)
+}
+
+// within reports whether path is inside dir.
+func within(path, dir string) bool {
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
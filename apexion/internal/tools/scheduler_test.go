@@ -0,0 +1,166 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExecutorConflicts(t *testing.T) {
+	exec := NewExecutor(DefaultRegistry(nil, nil), &allowAllPolicy{})
+	task, _ := exec.Registry().Get("task")
+	task.(*TaskTool).SetTypes([]SubAgentType{{Name: "migrator"}, {Name: "reviewer", ReadOnly: true}})
+	call := func(name, params string) Call {
+		return Call{Name: name, Params: json.RawMessage(params)}
+	}
+	tests := []struct {
+		name  string
+		prior Call
+		next  Call
+		want  bool
+	}{
+		{"two edits of one file", call("edit_file", `{"file_path":"a.go"}`), call("edit_file", `{"file_path":"./a.go"}`), true},
+		{"edits of different files", call("edit_file", `{"file_path":"a.go"}`), call("write_file", `{"file_path":"b.go"}`), false},
+		{"read after write", call("write_file", `{"file_path":"a.go"}`), call("read_file", `{"file_path":"a.go"}`), true},
+		{"two reads", call("read_file", `{"file_path":"a.go"}`), call("read_file", `{"file_path":"a.go"}`), false},
+		{"grep over an edited dir", call("edit_file", `{"file_path":"pkg/a.go"}`), call("grep", `{"pattern":"x","path":"pkg"}`), true},
+		{"grep elsewhere", call("edit_file", `{"file_path":"pkg/a.go"}`), call("grep", `{"pattern":"x","path":"cmd"}`), false},
+		{"prefix is not a parent", call("edit_file", `{"file_path":"pkg2/a.go"}`), call("list_dir", `{"path":"pkg"}`), false},
+		{"bash and a write", call("bash", `{"command":"go build ./..."}`), call("write_file", `{"file_path":"a.go"}`), true},
+		{"git status after bash", call("bash", `{"command":"make"}`), call("git_status", `{}`), true},
+		{"git status and a read", call("git_status", `{}`), call("read_file", `{"file_path":"a.go"}`), false},
+		{"todo list", call("todo_write", `{}`), call("todo_read", `{}`), true},
+		{"web and bash", call("bash", `{"command":"make"}`), call("web_fetch", `{"url":"https://example.com"}`), false},
+		{"code sub-agent and a read", call("task", `{"mode":"code"}`), call("read_file", `{"file_path":"a.go"}`), true},
+		{"custom writer and a read", call("task", `{"mode":"migrator"}`), call("read_file", `{"file_path":"a.go"}`), true},
+		{"read-only custom type and a read", call("task", `{"mode":"reviewer"}`), call("read_file", `{"file_path":"a.go"}`), false},
+		{"unknown tool writes the tree", call("mcp__fs__write", `{}`), call("read_file", `{"file_path":"a.go"}`), true},
+	}
+	for _, tt := range tests {
+		if got := exec.Conflicts([]Call{tt.prior}, tt.next); got != tt.want {
+			t.Errorf("%s: Conflicts = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Code sub-agents in worktrees do not touch the working tree.
+	task.(*TaskTool).SetIsolated(true)
+	if exec.Conflicts([]Call{call("task", `{"mode":"code"}`)}, call("task", `{"mode":"migrator"}`)) {
+		t.Error("isolated code sub-agents conflict")
+	}
+}
+
+func TestExecutorSchedule(t *testing.T) {
+	exec := NewExecutor(DefaultRegistry(nil, nil), &allowAllPolicy{})
+	calls := []Call{
+		{Name: "edit_file", Params: json.RawMessage(`{"file_path":"a.go"}`)},
+		{Name: "read_file", Params: json.RawMessage(`{"file_path":"b.go"}`)},
+		{Name: "edit_file", Params: json.RawMessage(`{"file_path":"a.go"}`)},
+	}
+
+	var mu sync.Mutex
+	var order []int
+	bStarted := make(chan struct{})
+	exec.Schedule(calls, func(i int) {
+		switch i {
+		case 0:
+			// The unrelated read must be able to start while this runs.
+			select {
+			case <-bStarted:
+			case <-time.After(5 * time.Second):
+				t.Error("independent call did not run in parallel")
+			}
+		case 1:
+			close(bStarted)
+		}
+		mu.Lock()
+		order = append(order, i)
+		mu.Unlock()
+	})
+
+	pos := make(map[int]int)
+	for p, i := range order {
+		pos[i] = p
+	}
+	if len(order) != 3 || pos[0] > pos[2] {
+		t.Errorf("conflicting edits ran out of order: %v", order)
+	}
+}
+
+// slowReadTool runs until released and tracks peak concurrency.
+type slowReadTool struct {
+	name          string // default "slow_read"
+	running, peak atomic.Int32
+	release       chan struct{}
+}
+
+func (t *slowReadTool) Name() string {
+	if t.name == "" {
+		return "slow_read"
+	}
+	return t.name
+}
+func (t *slowReadTool) Description() string              { return "slow read" }
+func (t *slowReadTool) Parameters() map[string]any       { return map[string]any{} }
+func (t *slowReadTool) IsReadOnly() bool                 { return true }
+func (t *slowReadTool) PermissionLevel() PermissionLevel { return PermissionRead }
+func (t *slowReadTool) Execute(ctx context.Context, _ json.RawMessage) (ToolResult, error) {
+	n := t.running.Add(1)
+	defer t.running.Add(-1)
+	for {
+		p := t.peak.Load()
+		if n <= p || t.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	<-t.release
+	return ToolResult{Content: "ok"}, nil
+}
+
+func TestExecutorMaxParallelReads(t *testing.T) {
+	tool := &slowReadTool{release: make(chan struct{})}
+	reg := NewRegistry()
+	reg.Register(tool)
+	exec := NewExecutor(reg, &allowAllPolicy{})
+	exec.SetMaxParallelReads(2)
+
+	calls := make([]Call, 5)
+	for i := range calls {
+		calls[i] = Call{Name: "slow_read", Params: json.RawMessage(`{}`)}
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(tool.release)
+	}()
+	exec.Schedule(calls, func(i int) {
+		exec.Execute(context.Background(), "slow_read", calls[i].Params)
+	})
+	if p := tool.peak.Load(); p != 2 {
+		t.Errorf("peak concurrent reads = %d, want 2", p)
+	}
+}
+
+func TestExecutorMaxParallelReads_ExemptsTaskAndQuestion(t *testing.T) {
+	question := &slowReadTool{name: "question", release: make(chan struct{})}
+	defer close(question.release)
+	read := &slowReadTool{release: make(chan struct{})}
+	close(read.release)
+	reg := NewRegistry()
+	reg.Register(question)
+	reg.Register(read)
+	exec := NewExecutor(reg, &allowAllPolicy{})
+	exec.SetMaxParallelReads(1)
+
+	// A question waiting for the user must not hold the only read slot.
+	go exec.Execute(context.Background(), "question", json.RawMessage(`{}`))
+	for question.running.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if r := exec.Execute(ctx, "slow_read", json.RawMessage(`{}`)); r.UserCancelled || r.Content != "ok" {
+		t.Errorf("read blocked behind a pending question: %+v", r)
+	}
+}
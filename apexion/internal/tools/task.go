@@ -19,6 +19,15 @@ const (
 // mode is "explore" (default) or "plan".
 type SubAgentRunner func(ctx context.Context, prompt string, mode string) (string, error)
 
+// SubAgentType is a user-defined sub-agent type the task tool can delegate
+// to in addition to the built-in modes. The agent package resolves its tools.
+type SubAgentType struct {
+	Name        string
+	Description string
+	ReadOnly    bool          // false if any of its tools can modify files or reach MCP servers
+	Timeout     time.Duration // 0 = taskTimeout
+}
+
 // BackgroundLauncher launches sub-agents as background tasks.
 type BackgroundLauncher interface {
 	Launch(ctx context.Context, prompt, mode string, runner SubAgentRunner) (string, error)
@@ -30,6 +39,8 @@ type TaskTool struct {
 	runner     SubAgentRunner
 	confirmer  Confirmer          // injected for code mode confirmation
 	bgLauncher BackgroundLauncher // injected for background mode
+	types      []SubAgentType     // user-defined sub-agent types
+	isolated   bool               // writing sub-agents run in their own git worktree
 }
 
 func (t *TaskTool) Name() string     { return "task" }
@@ -50,10 +61,34 @@ Use this tool when:
 - You need a focused coding task done independently (use mode="code")
 
 The sub-agent receives your prompt, works autonomously, and returns a text summary.
-Keep prompts specific and focused for best results.`
+Keep prompts specific and focused for best results.` + t.typesDescription()
+}
+
+// typesDescription lists the user-defined sub-agent types for the tool description.
+func (t *TaskTool) typesDescription() string {
+	if len(t.types) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n\nCustom sub-agent types (pass the name as mode):")
+	for _, st := range t.types {
+		fmt.Fprintf(&b, "\n- '%s': %s", st.Name, st.Description)
+		if !st.ReadOnly {
+			b.WriteString(" (can modify files or call MCP tools; asks for confirmation)")
+		}
+	}
+	return b.String()
 }
 
 func (t *TaskTool) Parameters() map[string]any {
+	modes := []string{"explore", "plan", "code"}
+	modeDesc := "Sub-agent mode: 'explore' (read-only research, default), 'plan' (read-only, structured plan), 'code' (can modify files and run commands)."
+	for _, st := range t.types {
+		modes = append(modes, st.Name)
+	}
+	if len(t.types) > 0 {
+		modeDesc += " Custom types are described in the tool description."
+	}
 	return map[string]any{
 		"prompt": map[string]any{
 			"type":        "string",
@@ -61,8 +96,8 @@ func (t *TaskTool) Parameters() map[string]any {
 		},
 		"mode": map[string]any{
 			"type":        "string",
-			"description": "Sub-agent mode: 'explore' (read-only research, default), 'plan' (read-only, structured plan), 'code' (can modify files and run commands).",
-			"enum":        []string{"explore", "plan", "code"},
+			"description": modeDesc,
+			"enum":        modes,
 		},
 		"run_in_background": map[string]any{
 			"type":        "boolean",
@@ -82,6 +117,51 @@ func (t *TaskTool) SetConfirmer(c Confirmer) {
 	t.confirmer = c
 }
 
+// SetTypes registers user-defined sub-agent types. Their names become valid
+// modes and are passed to the runner unchanged.
+func (t *TaskTool) SetTypes(types []SubAgentType) {
+	t.types = types
+}
+
+// subAgentType returns the user-defined type named mode, if any.
+func (t *TaskTool) subAgentType(mode string) (SubAgentType, bool) {
+	for _, st := range t.types {
+		if st.Name == mode {
+			return st, true
+		}
+	}
+	return SubAgentType{}, false
+}
+
+// canWrite reports whether a sub-agent of the given mode may modify files.
+func (t *TaskTool) canWrite(mode string) bool {
+	if mode == "code" {
+		return true
+	}
+	st, ok := t.subAgentType(mode)
+	return ok && !st.ReadOnly
+}
+
+// SetIsolated records that sub-agents which can modify files run in
+// their own git worktree, so they do not touch the working tree.
+func (t *TaskTool) SetIsolated(isolated bool) {
+	t.isolated = isolated
+}
+
+// writesTree reports whether a sub-agent of the given mode modifies the
+// working tree directly.
+func (t *TaskTool) writesTree(mode string) bool {
+	return t.canWrite(mode) && !t.isolated
+}
+
+// timeout returns the run time limit for the given mode.
+func (t *TaskTool) timeout(mode string) time.Duration {
+	if st, ok := t.subAgentType(mode); ok && st.Timeout > 0 {
+		return st.Timeout
+	}
+	return taskTimeout
+}
+
 // SetBGLauncher injects the background launcher for async execution.
 func (t *TaskTool) SetBGLauncher(bl BackgroundLauncher) {
 	t.bgLauncher = bl
@@ -113,9 +193,13 @@ func (t *TaskTool) Execute(ctx context.Context, params json.RawMessage) (ToolRes
 	}
 
 	// Code mode requires user confirmation since the sub-agent can modify files.
-	if p.Mode == "code" && t.confirmer != nil {
-		if !t.confirmer.Confirm("task (code mode)",
-			fmt.Sprintf("Code sub-agent will be launched with write permissions.\nTask: %s", truncateTaskOutput(p.Prompt)),
+	if t.canWrite(p.Mode) && t.confirmer != nil {
+		title, what := "task (code mode)", "Code sub-agent"
+		if p.Mode != "code" {
+			title, what = fmt.Sprintf("task (%s)", p.Mode), fmt.Sprintf("Sub-agent %q", p.Mode)
+		}
+		if !t.confirmer.Confirm(title,
+			fmt.Sprintf("%s will be launched with write permissions.\nTask: %s", what, truncateTaskOutput(p.Prompt)),
 			PermissionWrite) {
 			return ToolResult{
 				Content:       "[User cancelled code sub-agent]",
@@ -133,7 +217,7 @@ func (t *TaskTool) Execute(ctx context.Context, params json.RawMessage) (ToolRes
 	}
 
 	// Run with a dedicated timeout.
-	taskCtx, cancel := context.WithTimeout(ctx, taskTimeout)
+	taskCtx, cancel := context.WithTimeout(ctx, t.timeout(p.Mode))
 	defer cancel()
 
 	output, err := t.runner(taskCtx, p.Prompt, p.Mode)
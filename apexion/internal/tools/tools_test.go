@@ -386,3 +386,54 @@ func TestTruncateHeadTail_Truncates(t *testing.T) {
 		t.Error("result should end with tail content")
 	}
 }
+
+// --- Doctor check tests ---
+
+func TestCheckHooks(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	os.MkdirAll(filepath.Join(dir, ".apexion"), 0755)
+	hooks := `hooks:
+  pre_tool:
+    - matcher: "bash|edit_file"
+      command: "./check.sh"
+    - matcher: "write_(file"
+      command: "./check.sh"
+  session_start:
+    - command: ""
+`
+	os.WriteFile(filepath.Join(dir, ".apexion", "hooks.yaml"), []byte(hooks), 0644)
+
+	n, errs := CheckHooks(dir)
+	if n != 3 {
+		t.Errorf("expected 3 hooks, got %d", n)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 problems, got %v", errs)
+	}
+	if !strings.Contains(errs[0].Error(), "invalid matcher") || !strings.Contains(errs[1].Error(), "empty command") {
+		t.Errorf("unexpected problems: %v", errs)
+	}
+}
+
+func TestCheckCommandTemplates(t *testing.T) {
+	errs := CheckCommandTemplates(map[string]string{
+		".go": "go vet {{.file}}",
+		"py":  "go vet {{.file}}",
+		".rs": "go vet {{ .file }}",
+		".js": "apexion-no-such-linter {{.file}}",
+	})
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	joined := strings.Join(got, "\n")
+	for _, want := range []string{`"py": key must be a file extension`, "unknown placeholder {{ .file }}", "apexion-no-such-linter not found"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected %q in:\n%s", want, joined)
+		}
+	}
+	if len(errs) != 3 {
+		t.Errorf("expected 3 problems, got %d:\n%s", len(errs), joined)
+	}
+}
@@ -53,6 +53,19 @@ func (ft *FileTracker) Changes() []FileChange {
 	return cp
 }
 
+// Since returns the changes recorded at or after t.
+func (ft *FileTracker) Since(t time.Time) []FileChange {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	var out []FileChange
+	for _, c := range ft.changes {
+		if !c.At.Before(t) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
 // Summary returns a formatted summary of unique file changes.
 // Returns empty string if no changes were recorded.
 func (ft *FileTracker) Summary() string {
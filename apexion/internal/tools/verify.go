@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/apexion-ai/apexion/internal/config"
+)
+
+// Verifier runs the project-level verify commands (build, vet, tests)
+// before a turn that changed files ends.
+type Verifier struct {
+	config config.VerifyConfig
+}
+
+// VerifyResult is the outcome of one verify command.
+type VerifyResult struct {
+	Name     string
+	Command  string
+	Passed   bool
+	Output   string // combined output, only kept on failure
+	Duration time.Duration
+}
+
+// NewVerifier creates a Verifier from configuration.
+// Returns nil if verification is disabled or no commands are configured.
+func NewVerifier(cfg config.VerifyConfig) *Verifier {
+	if !cfg.Enabled || len(cfg.Commands) == 0 {
+		return nil
+	}
+	if cfg.MaxRounds <= 0 {
+		cfg.MaxRounds = 3
+	}
+	if cfg.TimeoutSec <= 0 {
+		cfg.TimeoutSec = 300
+	}
+	return &Verifier{config: cfg}
+}
+
+// Run executes the verify commands in order and stops at the first failure.
+// The returned results cover the commands that ran.
+func (v *Verifier) Run(ctx context.Context) []VerifyResult {
+	var results []VerifyResult
+	for _, c := range v.config.Commands {
+		r := v.run(ctx, c)
+		results = append(results, r)
+		if !r.Passed || ctx.Err() != nil {
+			break
+		}
+	}
+	return results
+}
+
+func (v *Verifier) run(ctx context.Context, c config.VerifyCommand) VerifyResult {
+	name := c.Name
+	if name == "" {
+		name = c.Run
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(v.config.TimeoutSec)*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.Run)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	started := time.Now()
+	err := cmd.Run()
+	r := VerifyResult{Name: name, Command: c.Run, Passed: err == nil, Duration: time.Since(started)}
+	if err != nil {
+		combined := strings.TrimSpace(out.String())
+		if ctx.Err() == context.DeadlineExceeded {
+			combined += "\n[timed out after " + (time.Duration(v.config.TimeoutSec) * time.Second).String() + "]"
+		} else if combined == "" {
+			combined = err.Error()
+		}
+		// Build errors come first and test failures last; keep both ends.
+		r.Output = truncateHeadTail(strings.TrimSpace(combined), 8192)
+	}
+	return r
+}
+
+// MaxRounds returns the configured maximum fix-and-verify rounds per turn.
+func (v *Verifier) MaxRounds() int {
+	return v.config.MaxRounds
+}
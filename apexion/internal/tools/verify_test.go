@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/apexion-ai/apexion/internal/config"
+)
+
+func TestVerifier_StopsAtFirstFailure(t *testing.T) {
+	if NewVerifier(config.VerifyConfig{Commands: []config.VerifyCommand{{Run: "true"}}}) != nil {
+		t.Error("disabled verify config should give a nil Verifier")
+	}
+	v := NewVerifier(config.VerifyConfig{Enabled: true, Commands: []config.VerifyCommand{
+		{Name: "build", Run: "true"},
+		{Name: "test", Run: "echo 'FAIL: TestX' >&2; exit 1"},
+		{Name: "never", Run: "true"},
+	}})
+	results := v.Run(context.Background())
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	if !results[0].Passed || results[0].Output != "" {
+		t.Errorf("build: %+v", results[0])
+	}
+	if results[1].Passed || !strings.Contains(results[1].Output, "FAIL: TestX") {
+		t.Errorf("test should fail with its output, got %+v", results[1])
+	}
+	if v.MaxRounds() != 3 {
+		t.Errorf("MaxRounds = %d, want default 3", v.MaxRounds())
+	}
+}
@@ -78,6 +78,28 @@ func fetchCacheSet(key, content string) {
 	fetchCache[key] = fetchCacheEntry{content: content, fetchedAt: time.Now()}
 }
 
+// ---------- transport ----------
+
+var (
+	webTransportMu sync.RWMutex
+	webTransport   http.RoundTripper
+)
+
+// SetWebTransport sets the transport used by web_fetch, web_search and
+// doc_context, e.g. to go through a proxy. Nil restores the default.
+func SetWebTransport(rt http.RoundTripper) {
+	webTransportMu.Lock()
+	defer webTransportMu.Unlock()
+	webTransport = rt
+}
+
+// webClient returns an HTTP client using the configured web transport.
+func webClient() *http.Client {
+	webTransportMu.RLock()
+	defer webTransportMu.RUnlock()
+	return &http.Client{Transport: webTransport}
+}
+
 // ---------- tool ----------
 
 // WebFetchTool fetches a web page and converts it to markdown.
@@ -150,17 +172,16 @@ func (t *WebFetchTool) Execute(ctx context.Context, params json.RawMessage) (Too
 	originalHost := u.Host
 
 	// Build HTTP client that detects cross-domain redirects.
-	client := &http.Client{
-		Timeout: fetchTimeout,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 10 {
-				return fmt.Errorf("too many redirects")
-			}
-			if req.URL.Host == originalHost {
-				return nil
-			}
-			return &crossDomainRedirect{URL: req.URL.String()}
-		},
+	client := webClient()
+	client.Timeout = fetchTimeout
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("too many redirects")
+		}
+		if req.URL.Host == originalHost {
+			return nil
+		}
+		return &crossDomainRedirect{URL: req.URL.String()}
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", fetchURL, nil)
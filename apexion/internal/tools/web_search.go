@@ -130,7 +130,7 @@ func (t *WebSearchTool) searchTavily(ctx context.Context, query string, maxResul
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+t.APIKey)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := webClient().Do(req)
 	if err != nil {
 		if ctx.Err() != nil {
 			return ToolResult{}, fmt.Errorf("cancelled")
@@ -186,7 +186,7 @@ func (t *WebSearchTool) searchJina(ctx context.Context, query string, maxResults
 		req.Header.Set("Authorization", "Bearer "+t.APIKey)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := webClient().Do(req)
 	if err != nil {
 		if ctx.Err() != nil {
 			return ToolResult{}, fmt.Errorf("cancelled")
@@ -268,7 +268,7 @@ func (t *WebSearchTool) searchExa(ctx context.Context, query string, maxResults
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-api-key", t.APIKey)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := webClient().Do(req)
 	if err != nil {
 		if ctx.Err() != nil {
 			return ToolResult{}, fmt.Errorf("cancelled")
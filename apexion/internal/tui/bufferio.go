@@ -21,6 +21,7 @@ type SubAgentProgress struct {
 type BufferIO struct {
 	mu         sync.Mutex
 	buf        strings.Builder
+	done       int // length of buf at the last TextDone
 	taskID     string
 	toolCount  int
 	onProgress func(SubAgentProgress)
@@ -59,7 +60,20 @@ func (b *BufferIO) TextDelta(delta string) {
 	b.buf.WriteString(delta)
 }
 
-func (b *BufferIO) TextDone(_ string) {}
+func (b *BufferIO) TextDone(_ string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done = b.buf.Len()
+}
+
+// TextDiscard drops the text captured since the last TextDone.
+func (b *BufferIO) TextDiscard() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	kept := b.buf.String()[:b.done]
+	b.buf.Reset()
+	b.buf.WriteString(kept)
+}
 
 func (b *BufferIO) ToolStart(_, name, _ string) {
 	if b.onProgress != nil {
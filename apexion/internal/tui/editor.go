@@ -0,0 +1,21 @@
+package tui
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// editorCommand builds the command that opens path in the user's editor.
+// $VISUAL and $EDITOR may carry arguments, e.g. "code --wait".
+func editorCommand(path string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if strings.TrimSpace(editor) == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+	}
+	return exec.Command(args[0], append(args[1:], path)...)
+}
@@ -2,7 +2,11 @@
 // user interface layer, plus PlainIO (terminal fallback) and TuiIO (bubbletea).
 package tui
 
-import "github.com/apexion-ai/apexion/internal/tools"
+import (
+	"time"
+
+	"github.com/apexion-ai/apexion/internal/tools"
+)
 
 // IO is the contract between the agent loop and the UI layer.
 // Every method maps to a distinct visual event — this separation ensures
@@ -69,3 +73,51 @@ type IO interface {
 type ImageInput interface {
 	PendingImages() []ImageAttachment
 }
+
+// ThinkingOutput is an optional interface for IO implementations that can
+// display model reasoning separately from the answer. ThinkingDelta streams
+// reasoning text; ThinkingDone closes the block before the answer (or a tool
+// call) begins. IO implementations without it simply don't show reasoning.
+type ThinkingOutput interface {
+	ThinkingDelta(delta string)
+	ThinkingDone(fullText string)
+}
+
+// TextDiscarder is an optional interface for IO implementations that can
+// drop the text streamed since the last TextDone, used when a response is
+// thrown away and asked for again. Implementations that already printed
+// the deltas just end the line.
+type TextDiscarder interface {
+	TextDiscard()
+}
+
+// ThrottleStatus is an optional interface for IO implementations that can
+// show when provider calls are held back by the rate limiter. waiting is the
+// number of calls currently queued (0 clears the indicator); wait is the
+// expected delay of the newest one.
+type ThrottleStatus interface {
+	SetThrottled(provider string, waiting int, wait time.Duration)
+}
+
+// EscalationStatus is an optional interface for IO implementations that can
+// show that the agent escalated to a stronger model for the current turn.
+// An empty model clears the indicator.
+type EscalationStatus interface {
+	SetEscalation(model string)
+}
+
+// SteeringInput is an optional interface for IO implementations that accept
+// messages while a turn is running. The agent loop drains them at the next
+// iteration boundary and hands them to the model after the current tool
+// results. Messages still queued when the turn ends are returned by the
+// following ReadInput calls, one per call.
+type SteeringInput interface {
+	DrainSteering() []string
+}
+
+// FileEditor is an optional interface for IO implementations that can hand
+// the terminal to the user's editor ($VISUAL, $EDITOR, or vi). EditFile
+// returns once the editor exits.
+type FileEditor interface {
+	EditFile(path string) error
+}
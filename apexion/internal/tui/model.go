@@ -3,6 +3,7 @@ package tui
 import (
 	"encoding/json"
 	"fmt"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -30,6 +31,9 @@ type userMsg struct{ text string }
 type thinkingStartMsg struct{}
 type textDeltaMsg struct{ delta string }
 type textDoneMsg struct{ fullText string }
+type textDiscardMsg struct{}
+type thinkingDeltaMsg struct{ delta string }
+type thinkingDoneMsg struct{ fullText string }
 type toolStartMsg struct{ id, name, params string }
 type toolDoneMsg struct {
 	id, name, result string
@@ -47,7 +51,14 @@ type tokensMsg struct{ n int }
 type contextInfoMsg struct{ used, total int }
 type planModeMsg struct{ active bool }
 type costMsg struct{ cost float64 }
+type throttleMsg struct {
+	provider string
+	waiting  int
+	wait     time.Duration
+}
 type agentDoneMsg struct{ err error }
+type steeringTakenMsg struct{ n int }
+type escalationMsg struct{ model string }
 type toolTickMsg struct{}
 type subAgentProgressMsg struct{ progress SubAgentProgress }
 type questionMsg struct {
@@ -55,6 +66,10 @@ type questionMsg struct {
 	options  []string
 	replyCh  chan string
 }
+type editFileMsg struct {
+	cmd     *exec.Cmd
+	replyCh chan error
+}
 
 // ---------- spinner activity kinds ----------
 
@@ -220,6 +235,13 @@ type Model struct {
 	inputMode   bool
 	spinnerKind spinnerKind
 
+	// Model reasoning: streamed into liveThinking, then printed collapsed
+	// (or in full when expandThinking is on). ctrl+o toggles.
+	liveThinking   *strings.Builder
+	thinkingStart  time.Time
+	lastThinking   string
+	expandThinking bool
+
 	currentTool          *toolCallState
 	currentToolConfirmed bool
 
@@ -248,9 +270,20 @@ type Model struct {
 	toolName      string
 	toolStartTime time.Time
 
+	throttleProvider string
+	throttleWaiting  int
+	throttleUntil    time.Time
+
+	escalatedModel string // model the current turn escalated to, if any
+
 	cancelToolFn func() bool
 	cancelLoopFn func() bool
 
+	// Mid-turn steering: messages typed while a turn runs are handed to
+	// steerFn and listed in steerQueue until the agent takes them.
+	steerFn    func(string)
+	steerQueue []string
+
 	subAgentTool  string
 	subAgentCount int
 
@@ -280,12 +313,13 @@ func NewModel(inputCh chan inputResult, cfg TUIConfig) Model {
 	allCmds := append(BuiltinSlashCommands(), cfg.CustomCommands...)
 
 	return Model{
-		textinput:   ti,
-		spinner:     sp,
-		liveContent: &strings.Builder{},
-		inputCh:     inputCh,
-		cfg:         cfg,
-		slashAll:    allCmds,
+		textinput:    ti,
+		spinner:      sp,
+		liveContent:  &strings.Builder{},
+		liveThinking: &strings.Builder{},
+		inputCh:      inputCh,
+		cfg:          cfg,
+		slashAll:     allCmds,
 	}
 }
 
@@ -385,6 +419,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		switch s {
+		case "ctrl+o":
+			if m.confirming || m.questioning {
+				return m, nil
+			}
+			m.expandThinking = !m.expandThinking
+			cmds = append(cmds, tea.Println(systemStyle.Render(thinkingToggleNotice(m.expandThinking))))
+			if m.expandThinking && m.lastThinking != "" {
+				cmds = append(cmds, tea.Println(renderThinkingBlock(m.lastThinking, 0, true, m.width)))
+			}
+			return m, tea.Batch(cmds...)
 		case "ctrl+c":
 			if m.questioning && m.questionCh != nil {
 				close(m.questionCh)
@@ -423,6 +467,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.currentToolConfirmed = true
 				return m, nil
 			}
+			if m.canSteer() {
+				if text := strings.TrimSpace(m.textinput.Value()); text != "" {
+					m.textinput.SetValue("")
+					m.steerQueue = append(m.steerQueue, text)
+					m.steerFn(text)
+				}
+				return m, nil
+			}
 			if m.inputMode {
 				text := strings.TrimSpace(m.textinput.Value())
 				m.textinput.SetValue("")
@@ -515,10 +567,20 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.spinnerKind = spinnerNone
 				m.streaming = false
 				m.liveContent.Reset()
+				m.liveThinking.Reset()
 				return m, nil
 			}
 		}
 
+		if m.canSteer() && !isControlKeyMsg(s) {
+			if !m.textinput.Focused() {
+				cmds = append(cmds, m.textinput.Focus())
+			}
+			var cmd tea.Cmd
+			m.textinput, cmd = m.textinput.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+
 		if m.inputMode && !m.confirming {
 			if isControlKeyMsg(msg.String()) {
 				return m, nil
@@ -563,6 +625,28 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.streaming = true
 		m.liveContent.WriteString(msg.delta)
 
+	case thinkingDeltaMsg:
+		if m.liveThinking.Len() == 0 {
+			m.thinkingStart = time.Now()
+		}
+		if m.spinnerKind == spinnerNone {
+			cmds = append(cmds, m.spinner.Tick)
+		}
+		m.spinnerKind = spinnerThinking
+		m.liveThinking.WriteString(msg.delta)
+
+	case thinkingDoneMsg:
+		var elapsed time.Duration
+		if !m.thinkingStart.IsZero() {
+			elapsed = time.Since(m.thinkingStart)
+		}
+		m.liveThinking.Reset()
+		m.thinkingStart = time.Time{}
+		if strings.TrimSpace(msg.fullText) != "" {
+			m.lastThinking = msg.fullText
+			cmds = append(cmds, tea.Println(renderThinkingBlock(msg.fullText, elapsed, m.expandThinking, m.width)))
+		}
+
 	case textDoneMsg:
 		m.spinnerKind = spinnerNone
 		m.streaming = false
@@ -570,6 +654,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.liveContent.Reset()
 		cmds = append(cmds, tea.Println(rendered))
 
+	case textDiscardMsg:
+		m.spinnerKind = spinnerNone
+		m.streaming = false
+		m.liveContent.Reset()
+
 	case toolTickMsg:
 		if m.toolName != "" {
 			cmds = append(cmds, toolTickCmd())
@@ -627,6 +716,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.spinnerKind = spinnerNone
 		cmds = append(cmds, tea.Println(m.renderQuestionBlock(msg.question, msg.options)))
 
+	case editFileMsg:
+		// Suspend the TUI while the editor owns the terminal.
+		cmds = append(cmds, tea.ExecProcess(msg.cmd, func(err error) tea.Msg {
+			msg.replyCh <- err
+			return nil
+		}))
+
 	case systemMsg:
 		cmds = append(cmds, tea.Println(systemStyle.Render(msg.text)))
 
@@ -646,6 +742,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case costMsg:
 		m.sessionCost = msg.cost
 
+	case throttleMsg:
+		m.throttleProvider = msg.provider
+		m.throttleWaiting = msg.waiting
+		if msg.wait > 0 {
+			m.throttleUntil = time.Now().Add(msg.wait)
+		}
+
+	case escalationMsg:
+		m.escalatedModel = msg.model
+
+	case steeringTakenMsg:
+		m.steerQueue = m.steerQueue[min(msg.n, len(m.steerQueue)):]
+
 	case agentDoneMsg:
 		m.quitting = true
 		return m, tea.Quit
@@ -664,6 +773,9 @@ func (m Model) View() string {
 	case spinnerThinking:
 		// ✶ Thinking… — matches Claude Code's thinking indicator
 		live = dotRunningStyle.Render(m.spinner.View()) + hintStyle.Render(" Thinking…")
+		if tail := renderThinkingLive(m.liveThinking.String(), m.width); tail != "" {
+			live += "\n" + tail
+		}
 	case spinnerStreaming:
 		live = m.liveContent.String() + dotRunningStyle.Render(m.spinner.View())
 	case spinnerTool:
@@ -699,9 +811,14 @@ func (m Model) View() string {
 		if m.slashMenu && len(m.slashFiltered) > 0 {
 			input += "\n" + renderSlashMenu(m.slashFiltered, m.slashSel, m.width)
 		}
+	} else if m.canSteer() && m.textinput.Value() != "" {
+		input = m.textinput.View()
 	} else {
 		input = systemStyle.Render("❯")
 	}
+	if len(m.steerQueue) > 0 && !m.questioning && !m.confirming {
+		input = m.renderSteerQueue() + "\n" + input
+	}
 
 	bar := m.renderStatusBar()
 
@@ -837,6 +954,22 @@ func (m *Model) renderQuestionBlock(question string, options []string) string {
 	return confirmBorderStyle.Render(strings.Join(lines, "\n"))
 }
 
+// canSteer reports whether typed input should be queued as a steering
+// message: a turn is running and no prompt is waiting for an answer.
+func (m *Model) canSteer() bool {
+	return m.steerFn != nil && !m.inputMode && !m.confirming && !m.questioning && !m.quitting
+}
+
+// renderSteerQueue lists messages waiting to be picked up by the agent.
+func (m *Model) renderSteerQueue() string {
+	lines := make([]string, len(m.steerQueue))
+	for i, text := range m.steerQueue {
+		text = strings.Join(strings.Fields(text), " ")
+		lines[i] = hintStyle.Render("  ↳ queued: " + runewidth.Truncate(text, max(m.width-14, 10), "…"))
+	}
+	return strings.Join(lines, "\n")
+}
+
 // renderStatusBar renders the bottom separator + model/tokens/tool bar.
 func (m *Model) renderStatusBar() string {
 	modelName := m.cfg.Model
@@ -849,6 +982,10 @@ func (m *Model) renderStatusBar() string {
 		status += statusBarStyle.Render(" │ ") + statusPlanStyle.Render("PLAN")
 	}
 
+	if m.escalatedModel != "" {
+		status += statusBarStyle.Render(" │ ") + statusPlanStyle.Render("escalated: "+m.escalatedModel)
+	}
+
 	if m.sessionCost > 0 {
 		if m.sessionCost < 0.01 {
 			status += statusBarStyle.Render(fmt.Sprintf(" │ $%.4f", m.sessionCost))
@@ -857,6 +994,17 @@ func (m *Model) renderStatusBar() string {
 		}
 	}
 
+	if m.throttleWaiting > 0 {
+		label := "rate limited: " + m.throttleProvider
+		if left := time.Until(m.throttleUntil); left >= time.Second {
+			label += fmt.Sprintf(" ~%ds", int(left.Seconds()))
+		}
+		if m.throttleWaiting > 1 {
+			label += fmt.Sprintf(" (%d waiting)", m.throttleWaiting)
+		}
+		status += statusBarStyle.Render(" │ ") + statusPlanStyle.Render(label)
+	}
+
 	status += statusBarStyle.Render(fmt.Sprintf(" │ tokens: %d", m.tokens))
 
 	if m.contextTotal > 0 {
@@ -20,6 +20,8 @@ type PipeIO struct {
 	writer    io.Writer // stdout
 	errW      io.Writer // stderr
 	lastText  string    // last complete LLM text (for printLast mode)
+
+	steering steeringQueue // messages read from stream-json stdin
 }
 
 // NewPipeIO creates a PipeIO instance.
@@ -62,6 +64,26 @@ func (p *PipeIO) TextDone(fullText string) {
 	}
 }
 
+// TextDiscard drops the pending text. jsonl emits nothing until TextDone;
+// text mode has already printed the deltas, so it only ends the line.
+func (p *PipeIO) TextDiscard() {
+	if p.printLast || p.format == "jsonl" {
+		return
+	}
+	fmt.Fprintln(p.writer)
+}
+
+// ThinkingDelta is ignored: reasoning is emitted whole on ThinkingDone.
+func (p *PipeIO) ThinkingDelta(_ string) {}
+
+func (p *PipeIO) ThinkingDone(fullText string) {
+	if p.format == "jsonl" && !p.printLast {
+		p.emitJSONL("thinking", map[string]string{"content": fullText})
+	} else if p.verbose {
+		fmt.Fprintf(p.errW, "[thinking] %s\n", truncatePipe(fullText, 4096))
+	}
+}
+
 func (p *PipeIO) ToolStart(id, name, params string) {
 	if p.verbose {
 		fmt.Fprintf(p.errW, "[tool] %s started\n", name)
@@ -104,6 +126,23 @@ func (p *PipeIO) SetContextInfo(_, _ int)  {}
 func (p *PipeIO) SetPlanMode(_ bool)       {}
 func (p *PipeIO) SetCost(_ float64)        {}
 
+// Steer queues a message for the running turn. Pipe mode feeds it the
+// stream-json lines read from stdin after the prompt.
+func (p *PipeIO) Steer(text string) {
+	p.steering.push(text)
+}
+
+// DrainSteering returns and clears the queued messages.
+func (p *PipeIO) DrainSteering() []string {
+	msgs := p.steering.drain()
+	if p.format == "jsonl" && !p.printLast {
+		for _, text := range msgs {
+			p.emitJSONL("user", map[string]string{"content": text})
+		}
+	}
+	return msgs
+}
+
 // Flush outputs the last LLM text when in printLast mode.
 // Should be called after the agent finishes.
 func (p *PipeIO) Flush() {
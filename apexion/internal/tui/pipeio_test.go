@@ -0,0 +1,95 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPipeIO_JSONLThinkingEvent(t *testing.T) {
+	var out bytes.Buffer
+	p := NewPipeIO("jsonl", false, false)
+	p.writer = &out
+
+	p.ThinkingDelta("partial")
+	p.ThinkingDone("full reasoning")
+	p.TextDone("answer")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 jsonl lines, got %d: %q", len(lines), out.String())
+	}
+	var ev struct {
+		Type string            `json:"type"`
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &ev); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if ev.Type != "thinking" || ev.Data["content"] != "full reasoning" {
+		t.Errorf("unexpected thinking event %+v", ev)
+	}
+}
+
+func TestPipeIO_TextModeHidesThinking(t *testing.T) {
+	var out bytes.Buffer
+	p := NewPipeIO("text", false, false)
+	p.writer = &out
+	p.ThinkingDone("secret reasoning")
+	if out.Len() != 0 {
+		t.Errorf("text mode should not print reasoning to stdout, got %q", out.String())
+	}
+}
+
+func TestParseStreamJSONInput(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    string
+		wantErr bool
+	}{
+		{`{"type":"user","content":"  also update the README "}`, "also update the README", false},
+		{`{"type":"control","content":"stop"}`, "", true},
+		{`{"type":"user","content":""}`, "", true},
+		{`not json`, "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStreamJSONInput([]byte(tt.line))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseStreamJSONInput(%s) = %q, %v", tt.line, got, err)
+		}
+	}
+}
+
+func TestPipeIO_DrainSteering(t *testing.T) {
+	var out bytes.Buffer
+	p := NewPipeIO("jsonl", false, false)
+	p.writer = &out
+
+	p.Steer("first")
+	p.Steer("second")
+	if got := p.DrainSteering(); len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Errorf("DrainSteering() = %q", got)
+	}
+	if got := p.DrainSteering(); len(got) != 0 {
+		t.Errorf("queue not cleared: %q", got)
+	}
+	if n := strings.Count(out.String(), `"type":"user"`); n != 2 {
+		t.Errorf("expected 2 user events, got %d: %q", n, out.String())
+	}
+}
+
+func TestPipeIO_JSONLDiscardedTextNotEmitted(t *testing.T) {
+	var out bytes.Buffer
+	p := NewPipeIO("jsonl", false, false)
+	p.writer = &out
+
+	p.TextDelta("The build")
+	p.TextDiscard()
+	p.TextDelta("The build has three stages.")
+	p.TextDone("The build has three stages.")
+
+	if n := strings.Count(out.String(), `"type":"text"`); n != 1 {
+		t.Errorf("expected 1 text event, got %d: %q", n, out.String())
+	}
+}
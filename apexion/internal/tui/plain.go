@@ -15,9 +15,10 @@ import (
 // It preserves the exact behaviour of the original agent loop and is used
 // when TUI mode is disabled or the terminal does not support raw mode.
 type PlainIO struct {
-	scanner *bufio.Scanner
-	tokens  int
-	mu      sync.Mutex // protects concurrent output during parallel tool execution
+	scanner  *bufio.Scanner
+	tokens   int
+	thinking bool       // a [thinking] block is open
+	mu       sync.Mutex // protects concurrent output during parallel tool execution
 }
 
 // NewPlainIO creates a PlainIO that reads from stdin.
@@ -54,6 +55,25 @@ func (p *PlainIO) TextDone(_ string) {
 	// Plain terminal: text is already rendered incrementally.
 }
 
+func (p *PlainIO) TextDiscard() {
+	fmt.Println() // the discarded text is already on screen
+}
+
+func (p *PlainIO) ThinkingDelta(delta string) {
+	if !p.thinking {
+		p.thinking = true
+		fmt.Print("[thinking] ")
+	}
+	fmt.Print(delta)
+}
+
+func (p *PlainIO) ThinkingDone(_ string) {
+	if p.thinking {
+		p.thinking = false
+		fmt.Print("\n[/thinking]\n\n")
+	}
+}
+
 func (p *PlainIO) ToolStart(_, name, _ string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -123,6 +143,15 @@ func (p *PlainIO) AskQuestion(question string, options []string) (string, error)
 	return answer, nil
 }
 
+// EditFile opens path in the user's editor on the current terminal.
+func (p *PlainIO) EditFile(path string) error {
+	cmd := editorCommand(path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
 // truncate shortens s to maxLen characters, appending "..." if cut.
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -43,6 +43,7 @@ func RunTUI(cfg TUIConfig, agentFn func(io IO, ctx context.Context) error) error
 	}
 	model.cancelToolFn = tuiIO.CancelRunningTool
 	model.cancelLoopFn = tuiIO.CancelLoop
+	model.steerFn = tuiIO.Steer
 
 	p := tea.NewProgram(model)
 	tuiIO.program = p
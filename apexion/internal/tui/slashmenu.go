@@ -19,7 +19,7 @@ func BuiltinSlashCommands() []SlashMenuItem {
 		{Name: "/model", Desc: "Switch model"},
 		{Name: "/provider", Desc: "Switch provider"},
 		{Name: "/config", Desc: "Show configuration"},
-		{Name: "/plan", Desc: "Toggle plan mode"},
+		{Name: "/plan", Desc: "Toggle plan mode, or show/edit/execute the plan"},
 		{Name: "/compact", Desc: "Compact context"},
 		{Name: "/changes", Desc: "Show file changes"},
 		{Name: "/trust", Desc: "Show/reset approvals"},
@@ -31,7 +31,7 @@ func BuiltinSlashCommands() []SlashMenuItem {
 		{Name: "/test", Desc: "Run test for a file"},
 		{Name: "/map", Desc: "Show repository map"},
 		{Name: "/architect", Desc: "Architect mode (dual-model)"},
-		{Name: "/bg", Desc: "Background agents status"},
+		{Name: "/bg", Desc: "Background agents status, merge or discard"},
 		{Name: "/audit", Desc: "Show command audit log"},
 		{Name: "/save", Desc: "Save session"},
 		{Name: "/sessions", Desc: "List saved sessions"},
@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// steeringQueue holds messages the user submitted while a turn was running,
+// in the order they were sent.
+type steeringQueue struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (q *steeringQueue) push(text string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.msgs = append(q.msgs, text)
+}
+
+// drain returns and clears all queued messages.
+func (q *steeringQueue) drain() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msgs := q.msgs
+	q.msgs = nil
+	return msgs
+}
+
+// pop removes and returns the oldest queued message.
+func (q *steeringQueue) pop() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.msgs) == 0 {
+		return "", false
+	}
+	text := q.msgs[0]
+	q.msgs = q.msgs[1:]
+	return text, true
+}
+
+// ParseStreamJSONInput decodes one stream-json input line of the form
+// {"type":"user","content":"..."} and returns the message text.
+func ParseStreamJSONInput(line []byte) (string, error) {
+	var in struct {
+		Type    string `json:"type"`
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(line, &in); err != nil {
+		return "", fmt.Errorf("invalid stream-json input: %w", err)
+	}
+	if in.Type != "user" {
+		return "", fmt.Errorf("unsupported stream-json input type %q (want \"user\")", in.Type)
+	}
+	text := strings.TrimSpace(in.Content)
+	if text == "" {
+		return "", fmt.Errorf("stream-json input has no content")
+	}
+	return text, nil
+}
@@ -0,0 +1,45 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestModel_QueuesSteeringWhileBusy(t *testing.T) {
+	var sent []string
+	m := NewModel(make(chan inputResult, 1), TUIConfig{})
+	m.width = 80
+	m.steerFn = func(text string) { sent = append(sent, text) }
+
+	update := func(msg tea.Msg) {
+		next, _ := m.Update(msg)
+		m = next.(Model)
+	}
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("use tabs")})
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if len(sent) != 1 || sent[0] != "use tabs" {
+		t.Fatalf("steerFn got %q", sent)
+	}
+	if m.textinput.Value() != "" {
+		t.Errorf("input not cleared: %q", m.textinput.Value())
+	}
+	if !strings.Contains(m.View(), "queued: use tabs") {
+		t.Errorf("pending message not shown:\n%s", m.View())
+	}
+
+	update(steeringTakenMsg{n: 1})
+	if len(m.steerQueue) != 0 || strings.Contains(m.View(), "queued:") {
+		t.Errorf("queue not cleared after the agent took it: %q", m.steerQueue)
+	}
+
+	// While waiting for normal input, Enter submits instead of queueing.
+	update(readInputMsg{})
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("next")})
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(sent) != 1 {
+		t.Errorf("input-mode submit was queued as steering: %q", sent)
+	}
+}
@@ -0,0 +1,75 @@
+package tui
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// thinkingStyle renders model reasoning dimmed so it reads as secondary to
+// the answer.
+var thinkingStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("240")).
+	Italic(true)
+
+// thinkingLiveLines is how many trailing lines of in-flight reasoning are
+// shown under the spinner.
+const thinkingLiveLines = 3
+
+// renderThinkingBlock renders a completed reasoning block for scrollback.
+// Collapsed, it is a one-line summary; expanded, the full text follows.
+//
+//	✻ Thought for 4s · ctrl+o to expand
+//	✻ Thought for 4s
+//	  │ First I need to look at...
+func renderThinkingBlock(text string, elapsed time.Duration, expanded bool, width int) string {
+	header := "✻ Thought"
+	if elapsed >= time.Second {
+		header += " for " + formatElapsed(elapsed)
+	}
+	if !expanded {
+		return thinkingStyle.Render(header + " · ctrl+o to expand")
+	}
+
+	text = strings.TrimSpace(text)
+	if width <= 0 {
+		width = 80
+	}
+	lines := wrapByDisplayWidth(text, width-6)
+	var sb strings.Builder
+	sb.WriteString(thinkingStyle.Render(header))
+	for _, line := range lines {
+		sb.WriteString("\n")
+		sb.WriteString(thinkingStyle.Render("  │ " + line))
+	}
+	return sb.String()
+}
+
+// renderThinkingLive renders the tail of reasoning still being streamed.
+func renderThinkingLive(text string, width int) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	if width <= 0 {
+		width = 80
+	}
+	lines := wrapByDisplayWidth(text, width-6)
+	if len(lines) > thinkingLiveLines {
+		lines = lines[len(lines)-thinkingLiveLines:]
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = thinkingStyle.Render("  │ " + line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// thinkingToggleNotice describes the new ctrl+o state.
+func thinkingToggleNotice(expanded bool) string {
+	if expanded {
+		return "  Thinking blocks expanded (ctrl+o to collapse)"
+	}
+	return "  Thinking blocks collapsed (ctrl+o to expand)"
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/apexion-ai/apexion/internal/tools"
 	tea "github.com/charmbracelet/bubbletea"
@@ -21,6 +22,8 @@ type TuiIO struct {
 	cancelLoop context.CancelFunc
 
 	lastImages []ImageAttachment
+
+	steering steeringQueue
 }
 
 var _ IO = (*TuiIO)(nil)
@@ -37,6 +40,12 @@ func (t *TuiIO) ReadInput() (string, error) {
 	if t.program == nil {
 		return "", io.EOF
 	}
+	// Messages queued during the last turn that the loop never picked up
+	// are submitted as if typed now.
+	if text, ok := t.steering.pop(); ok {
+		t.program.Send(steeringTakenMsg{n: 1})
+		return text, nil
+	}
 	// Tell the TUI to activate the text input
 	t.program.Send(readInputMsg{})
 
@@ -79,6 +88,18 @@ func (t *TuiIO) TextDone(fullText string) {
 	t.send(textDoneMsg{fullText: fullText})
 }
 
+func (t *TuiIO) TextDiscard() {
+	t.send(textDiscardMsg{})
+}
+
+func (t *TuiIO) ThinkingDelta(delta string) {
+	t.send(thinkingDeltaMsg{delta: delta})
+}
+
+func (t *TuiIO) ThinkingDone(fullText string) {
+	t.send(thinkingDoneMsg{fullText: fullText})
+}
+
 func (t *TuiIO) ToolStart(id, name, params string) {
 	t.send(toolStartMsg{id: id, name: name, params: params})
 }
@@ -125,6 +146,37 @@ func (t *TuiIO) SetCost(cost float64) {
 	t.send(costMsg{cost: cost})
 }
 
+// --- ThrottleStatus implementation ---
+
+// SetThrottled shows or clears the rate-limit indicator in the status bar.
+func (t *TuiIO) SetThrottled(provider string, waiting int, wait time.Duration) {
+	t.send(throttleMsg{provider: provider, waiting: waiting, wait: wait})
+}
+
+// --- EscalationStatus implementation ---
+
+// SetEscalation shows or clears the escalated model in the status bar.
+func (t *TuiIO) SetEscalation(model string) {
+	t.send(escalationMsg{model: model})
+}
+
+// --- SteeringInput implementation ---
+
+// Steer queues a message typed while a turn is running.
+func (t *TuiIO) Steer(text string) {
+	t.steering.push(text)
+}
+
+// DrainSteering returns and clears the queued messages and removes them
+// from the pending list shown above the input.
+func (t *TuiIO) DrainSteering() []string {
+	msgs := t.steering.drain()
+	if len(msgs) > 0 {
+		t.send(steeringTakenMsg{n: len(msgs)})
+	}
+	return msgs
+}
+
 // --- Questioner implementation ---
 
 func (t *TuiIO) AskQuestion(question string, options []string) (string, error) {
@@ -144,6 +196,18 @@ func (t *TuiIO) AskQuestion(question string, options []string) (string, error) {
 	return answer, nil
 }
 
+// --- FileEditor implementation ---
+
+// EditFile suspends the TUI and opens path in the user's editor.
+func (t *TuiIO) EditFile(path string) error {
+	if t.program == nil {
+		return fmt.Errorf("no TUI program available")
+	}
+	replyCh := make(chan error, 1)
+	t.program.Send(editFileMsg{cmd: editorCommand(path), replyCh: replyCh})
+	return <-replyCh
+}
+
 // --- SubAgentReporter ---
 
 // ReportSubAgentProgress sends sub-agent progress to the TUI for rendering.